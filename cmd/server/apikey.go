@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"digital-wallet/internal/repository"
+	"digital-wallet/internal/service"
+	"digital-wallet/pkg/postgres"
+
+	"github.com/google/uuid"
+)
+
+const apiKeyUsage = "usage: server apikey issue -name NAME -scopes SCOPE[,SCOPE] [-owner USER_ID] [-admin-role ROLE] [-rate-limit N] [-expires-in DURATION] | list | revoke ID"
+
+// runAPIKey implements the apikey subcommand, mainly to issue the first admin
+// key, which then manages the others through /admin/api-keys:
+//
+//	server apikey issue -name ops -scopes admin  issue a key and print it and its signing secret
+//	server apikey list                           list keys
+//	server apikey revoke ID                      revoke a key
+func runAPIKey(args []string, dsn string) {
+	if len(args) == 0 {
+		log.Fatal(apiKeyUsage)
+	}
+
+	db, err := postgres.NewConnection(dsn)
+	if err != nil {
+		log.Fatalf("Postgres init failed: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("Postgres init failed: %v", err)
+	}
+	defer sqlDB.Close()
+
+	// Only the wallet repository's transactions are used by key management.
+	svc := service.NewWalletService(repository.NewWalletRepository(db), nil, nil, nil, nil,
+		service.WithAPIKeys(repository.NewAPIKeyRepository(db)))
+	ctx := context.Background()
+
+	switch args[0] {
+	case "issue":
+		flags := flag.NewFlagSet("apikey issue", flag.ExitOnError)
+		name := flags.String("name", "", "name of the key's holder")
+		scopes := flags.String("scopes", "", "comma-separated scopes: wallets:read, transfers:write, wallets:all, admin")
+		owner := flags.String("owner", "", "ID of the user whose wallets the key may use")
+		adminRole := flags.String("admin-role", "", "staff role of an admin key on the /admin wallet routes: viewer, operator, finance")
+		rateLimit := flags.Int("rate-limit", 0, "requests per minute, 0 for no limit")
+		expiresIn := flags.Duration("expires-in", 0, "lifetime of the key, 0 for no expiry")
+		flags.Parse(args[1:])
+		if *name == "" || *scopes == "" {
+			log.Fatal(apiKeyUsage)
+		}
+
+		key := &domain.APIKey{Name: *name, AdminRole: domain.AdminRole(*adminRole), RateLimit: *rateLimit}
+		if *owner != "" {
+			ownerID, err := uuid.Parse(*owner)
+			if err != nil {
+				log.Fatalf("Invalid owner ID %q", *owner)
+			}
+			key.OwnerID = &ownerID
+		}
+		for _, s := range strings.Split(*scopes, ",") {
+			key.Scopes = append(key.Scopes, domain.APIScope(strings.TrimSpace(s)))
+		}
+		if *expiresIn > 0 {
+			expiresAt := time.Now().Add(*expiresIn)
+			key.ExpiresAt = &expiresAt
+		}
+		plain, err := svc.IssueAPIKey(ctx, key)
+		if err != nil {
+			log.Fatalf("Issuing API key failed: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "issued API key %s (%s) and its signing secret; they cannot be shown again\n", key.ID, key.Prefix)
+		fmt.Println(plain)
+		fmt.Println(key.SigningSecret)
+	case "list":
+		keys, err := svc.ListAPIKeys(ctx)
+		if err != nil {
+			log.Fatalf("Listing API keys failed: %v", err)
+		}
+		now := time.Now()
+		for _, k := range keys {
+			state := "active"
+			if !k.Active(now) {
+				state = "inactive"
+			}
+			fmt.Printf("%s  %s  %-8s  %-20s %v %s\n", k.ID, k.Prefix, state, k.Name, k.Scopes, k.AdminRole)
+		}
+	case "revoke":
+		if len(args) < 2 {
+			log.Fatal(apiKeyUsage)
+		}
+		id, err := uuid.Parse(args[1])
+		if err != nil {
+			log.Fatalf("Invalid API key ID %q", args[1])
+		}
+		if _, err := svc.RevokeAPIKey(ctx, id); err != nil {
+			log.Fatalf("Revoking API key failed: %v", err)
+		}
+		fmt.Printf("revoked %s\n", id)
+	default:
+		log.Fatal(apiKeyUsage)
+	}
+}
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AdminRole is a staff role on the /admin API. Every role may look wallets
+// up; operator and finance each add their own actions.
+type AdminRole string
+
+// Admin roles.
+const (
+	AdminRoleViewer   AdminRole = "viewer"   // Look up wallets, ledger entries and the audit log
+	AdminRoleOperator AdminRole = "operator" // Also freeze and unfreeze wallets
+	AdminRoleFinance  AdminRole = "finance"  // Also post manual adjustments
+)
+
+// ParseAdminRole returns ErrInvalidAdminRole for unknown roles.
+func ParseAdminRole(s string) (AdminRole, error) {
+	switch role := AdminRole(s); role {
+	case AdminRoleViewer, AdminRoleOperator, AdminRoleFinance:
+		return role, nil
+	}
+	return "", fmt.Errorf("%w %q", ErrInvalidAdminRole, s)
+}
+
+// AdminActor is who performs an admin action, as recorded in the audit log.
+type AdminActor struct {
+	ID   string    // "user:<id>" for staff tokens, "api_key:<prefix>" for API keys
+	Role AdminRole // The role the action was allowed by
+}
+
+// Admin actions recorded in the audit log.
+const (
+	AdminActionListWallets  = "wallets.list"
+	AdminActionViewLedger   = "ledger.view"
+	AdminActionFreeze       = "wallet.freeze"
+	AdminActionUnfreeze     = "wallet.unfreeze"
+	AdminActionAdjust       = "wallet.adjust"
+	AdminActionClose        = "wallet.close"
+	AdminActionViewAuditLog = "audit_log.view"
+)
+
+// AdjustmentReason says why finance staff adjusted a balance by hand.
+type AdjustmentReason string
+
+// Adjustment reason codes.
+const (
+	AdjustmentReasonGoodwill      AdjustmentReason = "goodwill"       // Compensation credited to a customer
+	AdjustmentReasonCorrection    AdjustmentReason = "correction"     // Reverses a posting made in error
+	AdjustmentReasonChargeback    AdjustmentReason = "chargeback"     // A card payment funding the wallet was charged back
+	AdjustmentReasonFeeRefund     AdjustmentReason = "fee_refund"     // Returns a fee
+	AdjustmentReasonFraudRecovery AdjustmentReason = "fraud_recovery" // Recovers funds obtained by fraud
+)
+
+// ParseAdjustmentReason returns ErrInvalidReasonCode for unknown reasons.
+func ParseAdjustmentReason(s string) (AdjustmentReason, error) {
+	switch reason := AdjustmentReason(s); reason {
+	case AdjustmentReasonGoodwill, AdjustmentReasonCorrection, AdjustmentReasonChargeback,
+		AdjustmentReasonFeeRefund, AdjustmentReasonFraudRecovery:
+		return reason, nil
+	}
+	return "", ErrInvalidReasonCode
+}
+
+// AdminAuditEntry records one action taken through the /admin API. Entries
+// are never changed or deleted.
+type AdminAuditEntry struct {
+	ID            int64            `gorm:"primaryKey;autoIncrement" json:"id"`
+	Actor         string           `gorm:"not null" json:"actor"` // AdminActor.ID
+	Role          AdminRole        `gorm:"not null" json:"role"`
+	Action        string           `gorm:"not null" json:"action"`                           // See the AdminAction constants
+	WalletID      *uuid.UUID       `gorm:"type:uuid;index" json:"wallet_id,omitempty"`       // The wallet acted on
+	UserID        *uuid.UUID       `gorm:"type:uuid" json:"user_id,omitempty"`               // The user looked up
+	TransactionID *uuid.UUID       `gorm:"type:uuid" json:"transaction_id,omitempty"`        // Set for adjustments
+	ReasonCode    AdjustmentReason `gorm:"not null;default:''" json:"reason_code,omitempty"` // Set for adjustments
+	Note          string           `gorm:"not null;default:''" json:"note,omitempty"`        // Free text from the actor
+	CreatedAt     time.Time        `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// AdminAuditFilter selects entries of the audit log. A nil WalletID selects
+// every entry.
+type AdminAuditFilter struct {
+	WalletID *uuid.UUID
+	Limit    int
+}
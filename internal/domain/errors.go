@@ -3,9 +3,42 @@ package domain
 import "errors"
 
 var (
-	ErrWalletNotFound      = errors.New("wallet not found")
-	ErrInsufficientFunds   = errors.New("insufficient funds")
-	ErrInvalidAmount       = errors.New("amount must be positive")
-	ErrSelfTransfer        = errors.New("cannot transfer money to yourself")
-	ErrInternalServerError = errors.New("internal server error")
+	ErrWalletNotFound       = errors.New("wallet not found")
+	ErrInsufficientFunds    = errors.New("insufficient funds")
+	ErrInvalidAmount        = errors.New("amount must be positive")
+	ErrSelfTransfer         = errors.New("cannot transfer money to yourself")
+	ErrInternalServerError  = errors.New("internal server error")
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+	ErrIdempotencyKeyInUse  = errors.New("idempotency key is already in use")
+	ErrInvalidCursor        = errors.New("invalid cursor")
+	ErrInvalidFilter        = errors.New("invalid transaction filter")
+	ErrUnbalancedPosting    = errors.New("ledger entries do not sum to zero")
+	ErrUnsupportedCurrency  = errors.New("unsupported currency")
+	ErrCurrencyMismatch     = errors.New("currency does not match the wallet currency")
+	ErrRateUnavailable      = errors.New("exchange rate unavailable")
+	ErrQuoteNotFound        = errors.New("quote not found")
+	ErrQuoteExpired         = errors.New("quote has expired")
+	ErrQuoteUsed            = errors.New("quote has already been used")
+	ErrPendingNotFound      = errors.New("pending transfer not found")
+	ErrPendingNotActive     = errors.New("pending transfer is no longer pending")
+	ErrCaptureExceedsHold   = errors.New("capture amount exceeds the authorized amount")
+	ErrTransactionNotFound  = errors.New("transaction not found")
+	ErrRefundNotAllowed     = errors.New("only transfers can be refunded")
+	ErrRefundExceedsAmount  = errors.New("refund exceeds the amount left to refund")
+	ErrInvalidBatch         = errors.New("invalid batch transfer")
+	ErrInvalidStripeCount   = errors.New("stripe count can only be raised, up to the maximum")
+	ErrVersionMismatch      = errors.New("wallet version does not match")
+	ErrFeatureDisabled      = errors.New("feature is not enabled")
+	ErrForbidden            = errors.New("wallet belongs to another user")
+	ErrAPIKeyNotFound       = errors.New("API key not found")
+	ErrInvalidAPIKey        = errors.New("API key is invalid, expired or revoked")
+	ErrAPIKeyNotActive      = errors.New("API key is already expired or revoked")
+	ErrInvalidScope         = errors.New("unknown API key scope")
+	ErrInvalidAdminRole     = errors.New("unknown admin role")
+	ErrWalletFrozen         = errors.New("wallet is frozen")
+	ErrWalletClosed         = errors.New("wallet is closed")
+	ErrStatusUnchanged      = errors.New("wallet already has the requested status")
+	ErrInvalidTransition    = errors.New("wallet status cannot change to the requested status")
+	ErrWalletNotEmpty       = errors.New("wallet still holds money")
+	ErrInvalidReasonCode    = errors.New("unknown adjustment reason code")
 )
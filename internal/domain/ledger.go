@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const walletAccountPrefix = "wallet:"
+
+// House accounts are the platform's side of postings that move money in or
+// out of the wallet system.
+const (
+	HouseAccountCash        = "house:cash"        // Counterparty of deposits and withdrawals
+	HouseAccountFees        = "house:fees"        // Fee revenue
+	HouseAccountAdjustments = "house:adjustments" // Counterparty of manual adjustments
+)
+
+// HouseFXAccount returns the house account that holds currency during
+// cross-currency transfers.
+func HouseFXAccount(currency Currency) string {
+	return "house:fx:" + string(currency)
+}
+
+// WalletAccount returns the ledger account of a wallet.
+func WalletAccount(walletID uuid.UUID) string {
+	return walletAccountPrefix + walletID.String()
+}
+
+// WalletID returns the wallet an entry posts to, or false for house accounts.
+func (e LedgerEntry) WalletID() (uuid.UUID, bool) {
+	rest, ok := strings.CutPrefix(e.Account, walletAccountPrefix)
+	if !ok {
+		return uuid.Nil, false
+	}
+	id, err := uuid.Parse(rest)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// Credit returns an entry increasing account by amount.
+func Credit(account string, amount Money) LedgerEntry {
+	return LedgerEntry{Account: account, Amount: amount.Amount, Currency: amount.Currency}
+}
+
+// Debit returns an entry decreasing account by amount.
+func Debit(account string, amount Money) LedgerEntry {
+	return LedgerEntry{Account: account, Amount: -amount.Amount, Currency: amount.Currency}
+}
+
+// ValidatePosting checks that entries form a balanced double-entry posting:
+// at least two non-zero legs whose amounts sum to zero in every currency.
+func ValidatePosting(entries []LedgerEntry) error {
+	if len(entries) < 2 {
+		return fmt.Errorf("%w: a posting needs at least two entries", ErrUnbalancedPosting)
+	}
+	sums := make(map[Currency]int64)
+	for _, e := range entries {
+		if e.Amount == 0 {
+			return fmt.Errorf("%w: zero amount on %s", ErrUnbalancedPosting, e.Account)
+		}
+		if e.Currency == "" {
+			return fmt.Errorf("%w: missing currency on %s", ErrUnbalancedPosting, e.Account)
+		}
+		sums[e.Currency] += e.Amount
+	}
+	for currency, sum := range sums {
+		if sum != 0 {
+			return fmt.Errorf("%w: off by %s", ErrUnbalancedPosting, NewMoney(sum, currency))
+		}
+	}
+	return nil
+}
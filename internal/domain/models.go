@@ -2,59 +2,565 @@ package domain
 
 import (
 	"context"
+	"math/big"
 	"time"
 
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
 // Wallet represents a user's digital wallet.
 type Wallet struct {
-	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	UserID    uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
-	Balance   int64     `gorm:"not null;default:0;check:balance >= 0" json:"balance"` // Stored in cents, must be >= 0
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID        uuid.UUID    `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID    `gorm:"type:uuid;not null;index" json:"user_id"`
+	Balance   int64        `gorm:"not null;default:0;check:balance >= 0" json:"balance"`                                                                                    // Ledger balance in minor units of Currency, must be >= 0
+	Held      int64        `gorm:"column:held_balance;not null;default:0;check:chk_wallets_held_balance,held_balance >= 0 AND held_balance <= balance" json:"held_balance"` // Reserved by pending transfers
+	Currency  Currency     `gorm:"type:char(3);not null;default:'USD'" json:"currency"`
+	Stripes   int          `gorm:"not null;default:0" json:"stripes,omitempty"`                                                                         // Number of WalletStripe rows, 0 if the wallet is not striped
+	Status    WalletStatus `gorm:"type:text;not null;default:'active';check:chk_wallets_status,status IN ('active', 'frozen', 'closed')" json:"status"` // See the WalletStatus constants
+	Version   int64        `gorm:"not null;default:0" json:"version"`                                                                                   // Incremented on every balance or status change, served as the ETag
+	CreatedAt time.Time    `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time    `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// WalletStatus controls which operations a wallet accepts.
+type WalletStatus string
+
+// Wallet statuses.
+const (
+	WalletStatusActive WalletStatus = "active"
+	WalletStatusFrozen WalletStatus = "frozen" // Money cannot leave the wallet, see the /admin API
+	WalletStatusClosed WalletStatus = "closed" // Terminal: money can neither leave nor reach the wallet
+)
+
+// walletStatusTransitions lists the statuses each status may change to.
+var walletStatusTransitions = map[WalletStatus][]WalletStatus{
+	WalletStatusActive: {WalletStatusFrozen, WalletStatusClosed},
+	WalletStatusFrozen: {WalletStatusActive, WalletStatusClosed},
+}
+
+// CanTransitionTo reports whether a wallet with status s may change to next.
+func (s WalletStatus) CanTransitionTo(next WalletStatus) bool {
+	for _, allowed := range walletStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// WalletStripe is one sub-balance of a striped wallet. Incoming transfers
+// credit a random stripe without locking the wallet row, so a hot wallet
+// does not serialize its receivers. A striped wallet's balance is the wallet
+// row's Balance plus all of its stripes; debits borrow from the stripes into
+// the wallet row when the row alone cannot cover them.
+type WalletStripe struct {
+	WalletID uuid.UUID `gorm:"type:uuid;primaryKey" json:"wallet_id"`
+	Stripe   int       `gorm:"primaryKey;autoIncrement:false" json:"stripe"`
+	Balance  int64     `gorm:"not null;default:0;check:chk_wallet_stripes_balance,balance >= 0" json:"balance"`
+	Version  int64     `gorm:"not null;default:0" json:"version"` // Added to the wallet's Version
+}
+
+// AvailableBalance is the part of the balance not reserved by pending transfers.
+func (w *Wallet) AvailableBalance() int64 {
+	return w.Balance - w.Held
 }
 
 // Transaction represents a money transfer history.
 type Transaction struct {
-	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	SenderID   *uuid.UUID `gorm:"type:uuid" json:"sender_id,omitempty"`   // Nullable for deposits
-	ReceiverID *uuid.UUID `gorm:"type:uuid" json:"receiver_id,omitempty"` // Nullable for withdrawals
-	Amount     int64     `gorm:"not null" json:"amount"`
-	Type       string    `gorm:"not null" json:"type"` // "TRANSFER", "DEPOSIT", "WITHDRAWAL"
-	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	SenderID   *uuid.UUID `gorm:"type:uuid;index:idx_transactions_sender_created,priority:1" json:"sender_id,omitempty"`     // Nullable for deposits
+	ReceiverID *uuid.UUID `gorm:"type:uuid;index:idx_transactions_receiver_created,priority:1" json:"receiver_id,omitempty"` // Nullable for withdrawals
+	Amount     int64      `gorm:"not null" json:"amount"`                                                                    // Minor units of Currency
+	Currency   Currency   `gorm:"type:char(3);not null;default:'USD'" json:"currency"`
+	Type       string     `gorm:"not null" json:"type"`                // "TRANSFER", "FX_TRANSFER", "DEPOSIT", "WITHDRAWAL", "REFUND", "ADJUSTMENT", "SWEEP"
+	QuoteID    *uuid.UUID `gorm:"type:uuid" json:"quote_id,omitempty"` // Set for FX transfers
+
+	RefundedAmount        int64      `gorm:"not null;default:0" json:"refunded_amount"`                // Total refunded so far, never above Amount
+	OriginalTransactionID *uuid.UUID `gorm:"type:uuid;index" json:"original_transaction_id,omitempty"` // Set for refunds
+	CreatedAt             time.Time  `gorm:"autoCreateTime;index:idx_transactions_sender_created,priority:2;index:idx_transactions_receiver_created,priority:2" json:"created_at"`
+}
+
+// Transaction types.
+const (
+	TransactionTypeTransfer   = "TRANSFER"
+	TransactionTypeFXTransfer = "FX_TRANSFER"
+	TransactionTypeDeposit    = "DEPOSIT"
+	TransactionTypeWithdrawal = "WITHDRAWAL"
+	TransactionTypeRefund     = "REFUND"
+	TransactionTypeAdjustment = "ADJUSTMENT" // Manual correction posted by finance staff
+	TransactionTypeSweep      = "SWEEP"      // Balance moved out of a wallet being closed
+)
+
+// LedgerEntry is one leg of a double-entry posting. Each Transaction is the
+// journal header for its entries, whose amounts always sum to zero. Amounts
+// are signed from the account holder's side: credits are positive and
+// increase the account, debits are negative.
+type LedgerEntry struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	TransactionID uuid.UUID `gorm:"type:uuid;not null;index" json:"transaction_id"`
+	Account       string    `gorm:"not null;index" json:"account"` // See WalletAccount and the HouseAccount constants
+	Amount        int64     `gorm:"not null;check:amount <> 0" json:"amount"`
+	Currency      Currency  `gorm:"type:char(3);not null" json:"currency"`
+	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// PendingTransfer reserves funds on the sender's wallet until it is captured,
+// voided, or expires. Capturing moves CapturedAmount (at most Amount) and
+// releases the rest of the hold.
+type PendingTransfer struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	SenderID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"sender_id"`
+	ReceiverID     uuid.UUID  `gorm:"type:uuid;not null" json:"receiver_id"`
+	Amount         int64      `gorm:"not null;check:amount > 0" json:"amount"` // Authorized amount
+	Currency       Currency   `gorm:"type:char(3);not null" json:"currency"`
+	CapturedAmount int64      `gorm:"not null;default:0" json:"captured_amount"`
+	Status         string     `gorm:"not null;index:idx_pending_transfers_status_expires,priority:1" json:"status"`
+	TransactionID  *uuid.UUID `gorm:"type:uuid" json:"transaction_id,omitempty"` // Set on capture
+	ExpiresAt      time.Time  `gorm:"not null;index:idx_pending_transfers_status_expires,priority:2" json:"expires_at"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// Pending transfer statuses.
+const (
+	PendingStatusPending  = "PENDING"
+	PendingStatusCaptured = "CAPTURED"
+	PendingStatusVoided   = "VOIDED"
+	PendingStatusExpired  = "EXPIRED"
+)
+
+// FXQuote is a locked exchange offer. The sender pays SourceAmount, Fee of
+// which goes to the house, and the receiver gets TargetAmount. A quote can be
+// used for one transfer out of SenderID before ExpiresAt.
+type FXQuote struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	SenderID       uuid.UUID  `gorm:"type:uuid;not null" json:"sender_id"`
+	SourceCurrency Currency   `gorm:"type:char(3);not null" json:"source_currency"`
+	TargetCurrency Currency   `gorm:"type:char(3);not null" json:"target_currency"`
+	SourceAmount   int64      `gorm:"not null" json:"source_amount"`
+	Fee            int64      `gorm:"not null" json:"fee"` // In SourceCurrency
+	Rate           string     `gorm:"type:numeric(30,12);not null" json:"rate"`
+	TargetAmount   int64      `gorm:"not null" json:"target_amount"`
+	ExpiresAt      time.Time  `gorm:"not null" json:"expires_at"`
+	TransactionID  *uuid.UUID `gorm:"type:uuid" json:"transaction_id,omitempty"` // Set once the quote is used
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// BalanceCheck compares a wallet's stored balance with its ledger.
+type BalanceCheck struct {
+	WalletID      uuid.UUID `json:"wallet_id"`
+	Currency      Currency  `json:"currency"`
+	Balance       int64     `json:"balance"`        // Stored projection on the wallet row
+	LedgerBalance int64     `json:"ledger_balance"` // Sum of the wallet's ledger entries
+	Consistent    bool      `json:"consistent"`
+}
+
+// Transaction directions relative to a wallet.
+const (
+	DirectionIncoming = "incoming"
+	DirectionOutgoing = "outgoing"
+)
+
+// TransactionFilter selects a page of a wallet's transaction history,
+// ordered newest first. Nil or empty fields are not filtered on.
+type TransactionFilter struct {
+	WalletID  uuid.UUID
+	Type      string
+	Direction string
+	MinAmount *int64
+	MaxAmount *int64
+	From      *time.Time // Inclusive
+	To        *time.Time // Exclusive
+	After     *TransactionCursor
+	Limit     int
+}
+
+// TransactionCursor is the position of the last transaction on a page.
+type TransactionCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// TransactionPage is one page of transaction history. NextCursor is nil on the last page.
+type TransactionPage struct {
+	Transactions []Transaction
+	NextCursor   *TransactionCursor
+}
+
+// TransferLeg is one transfer of a batch.
+type TransferLeg struct {
+	SenderID   uuid.UUID
+	ReceiverID uuid.UUID
+	Amount     Money
+}
+
+// BatchMode selects how a batch transfer treats failing legs.
+type BatchMode string
+
+const (
+	// BatchModeAtomic commits every leg or none of them.
+	BatchModeAtomic BatchMode = "atomic"
+	// BatchModePerLeg commits the legs that succeed and reports the rest.
+	BatchModePerLeg BatchMode = "per_leg"
+)
+
+// BatchLegResult is the outcome of one leg of a batch transfer, in request
+// order. Exactly one of Transaction and Err is set.
+type BatchLegResult struct {
+	Index       int
+	Transaction *Transaction
+	Err         error
+}
+
+// IdempotencyKey stores the response of a request made with an Idempotency-Key
+// header so that retries are answered without executing the request again.
+type IdempotencyKey struct {
+	Scope          string    `gorm:"primary_key" json:"-"` // Caller the key belongs to; keys only need to be unique per caller
+	Key            string    `gorm:"primary_key" json:"key"`
+	RequestHash    string    `gorm:"not null" json:"request_hash"` // Fingerprint of the original request
+	TransactionID  uuid.UUID `gorm:"type:uuid;not null" json:"transaction_id"`
+	ResponseStatus int       `gorm:"not null" json:"response_status"`
+	ResponseBody   []byte    `gorm:"type:bytea;not null" json:"-"`
+	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ExpiresAt      time.Time `gorm:"not null;index" json:"expires_at"`
+}
+
+// OutboxEvent is an event waiting to be published to RabbitMQ. It is written
+// in the same database transaction as the change it describes and relayed
+// after commit, in ID order per wallet.
+type OutboxEvent struct {
+	ID            int64      `gorm:"primaryKey;autoIncrement;index:idx_outbox_events_pending,where:dispatched_at IS NULL" json:"id"`
+	EventType     string     `gorm:"not null" json:"event_type"`
+	SenderID      *uuid.UUID `gorm:"type:uuid" json:"sender_id,omitempty"`
+	ReceiverID    *uuid.UUID `gorm:"type:uuid" json:"receiver_id,omitempty"`
+	Payload       []byte     `gorm:"type:jsonb;not null" json:"payload"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `gorm:"not null" json:"next_attempt_at"`
+	DispatchedAt  *time.Time `json:"dispatched_at,omitempty"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// APIKey lets a backend service call the API without an end-user token. Only
+// the SHA-256 hash of the key is stored; Prefix, its first characters, tells
+// keys apart in listings and logs. SigningSecret is stored as is, since
+// verifying a request signature needs it. A key acts for OwnerID, or for
+// every user with ScopeAllWallets, see MayActFor.
+type APIKey struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name          string     `gorm:"not null" json:"name"`
+	Prefix        string     `gorm:"not null" json:"prefix"`
+	KeyHash       string     `gorm:"not null;uniqueIndex" json:"-"`
+	SigningSecret string     `gorm:"not null;default:''" json:"-"` // Signs the key's requests, empty for keys issued before request signing
+	Scopes        []APIScope `gorm:"type:jsonb;serializer:json;not null" json:"scopes"`
+	OwnerID       *uuid.UUID `gorm:"type:uuid" json:"owner_id,omitempty"`             // The user whose wallets the key may use
+	RateLimit     int        `gorm:"not null;default:0" json:"rate_limit"`            // Requests per minute, 0 for no limit
+	AdminRole     AdminRole  `gorm:"not null;default:''" json:"admin_role,omitempty"` // Staff role of an admin key on the /admin wallet routes
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RotatedFrom   *uuid.UUID `gorm:"type:uuid" json:"rotated_from,omitempty"` // The key this one replaced
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// Active reports whether the key may be used at now.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// HasScope reports whether the key grants scope. ScopeAdmin grants every scope.
+func (k *APIKey) HasScope(scope APIScope) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// MayActFor reports whether the key may use the wallets of userID.
+func (k *APIKey) MayActFor(userID uuid.UUID) bool {
+	return k.HasScope(ScopeAllWallets) || (k.OwnerID != nil && *k.OwnerID == userID)
+}
+
+// APIScope is a permission granted to an API key.
+type APIScope string
+
+// API key scopes.
+const (
+	ScopeWalletsRead    APIScope = "wallets:read"    // Read wallets, history, ledger and pending transfers
+	ScopeTransfersWrite APIScope = "transfers:write" // Create wallets and move money
+	ScopeAllWallets     APIScope = "wallets:all"     // Use every user's wallets, not just the owner's
+	ScopeAdmin          APIScope = "admin"           // Everything, including managing API keys
+)
+
+// ParseAPIScope returns ErrInvalidScope for unknown scopes.
+func ParseAPIScope(s string) (APIScope, error) {
+	switch scope := APIScope(s); scope {
+	case ScopeWalletsRead, ScopeTransfersWrite, ScopeAllWallets, ScopeAdmin:
+		return scope, nil
+	}
+	return "", ErrInvalidScope
+}
+
+// Event types, used as the outbox EventType and the RabbitMQ message type.
+const (
+	EventTypeTransfer     = "transfer"      // Payload is a TransferEvent
+	EventTypeRefund       = "refund"        // Payload is a RefundEvent
+	EventTypeWalletStatus = "wallet_status" // Payload is a WalletStatusEvent; SenderID is the wallet
+)
+
+// WalletIDs returns the wallets whose event ordering this event takes part in.
+func (e *OutboxEvent) WalletIDs() []uuid.UUID {
+	var ids []uuid.UUID
+	if e.SenderID != nil {
+		ids = append(ids, *e.SenderID)
+	}
+	if e.ReceiverID != nil {
+		ids = append(ids, *e.ReceiverID)
+	}
+	return ids
 }
 
 // TransferEvent is the payload sent to RabbitMQ.
+// SenderID is uuid.Nil for deposits and ReceiverID is uuid.Nil for withdrawals.
 type TransferEvent struct {
 	TransactionID uuid.UUID `json:"transaction_id"`
+	Type          string    `json:"type"`
 	SenderID      uuid.UUID `json:"sender_id"`
 	ReceiverID    uuid.UUID `json:"receiver_id"`
 	Amount        int64     `json:"amount"`
+	Currency      Currency  `json:"currency"`
+}
+
+// RefundEvent is the payload sent to RabbitMQ when a transfer is refunded.
+// Money flows from the original receiver (SenderID) back to the original
+// sender (ReceiverID).
+type RefundEvent struct {
+	RefundID              uuid.UUID `json:"refund_id"`
+	OriginalTransactionID uuid.UUID `json:"original_transaction_id"`
+	SenderID              uuid.UUID `json:"sender_id"`
+	ReceiverID            uuid.UUID `json:"receiver_id"`
+	Amount                int64     `json:"amount"`
+	Currency              Currency  `json:"currency"`
+}
+
+// WalletStatusEvent is the payload sent to RabbitMQ when a wallet is
+// frozen, unfrozen or closed. Actor is set when staff made the change, and
+// SweepTransactionID when closing the wallet swept its balance elsewhere.
+type WalletStatusEvent struct {
+	WalletID           uuid.UUID    `json:"wallet_id"`
+	UserID             uuid.UUID    `json:"user_id"`
+	From               WalletStatus `json:"from"`
+	To                 WalletStatus `json:"to"`
+	Actor              string       `json:"actor,omitempty"`
+	SweepTransactionID *uuid.UUID   `json:"sweep_transaction_id,omitempty"`
+	OccurredAt         time.Time    `json:"occurred_at"`
 }
 
 // Repository Interfaces
 
+// Transactor runs units of work. Repository calls made with the context
+// passed to fn take part in the transaction, which commits if fn returns nil
+// and rolls back otherwise. Methods named WithLock hold their row locks until
+// it ends. A WithTx call whose ctx already carries a transaction joins it.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type primaryReadsKey struct{}
+
+// WithPrimaryReads marks ctx so that repositories serve its reads from the
+// primary database. Outside a transaction, the wallet and transaction GetByID,
+// ListByWallet, ListByUser, ListByTransaction and ListByAccount reads may
+// otherwise be served by a lagging replica; use it when a read must see
+// writes that just committed.
+func WithPrimaryReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryReadsKey{}, true)
+}
+
+// PrimaryReads reports whether ctx was marked by WithPrimaryReads.
+func PrimaryReads(ctx context.Context) bool {
+	v, _ := ctx.Value(primaryReadsKey{}).(bool)
+	return v
+}
+
+type callerKey struct{}
+
+// WithCaller records the authenticated end user a request is made for. The
+// service then only lets that user read their wallets and move money out of
+// them. A ctx without a caller is trusted, as for internal jobs.
+func WithCaller(ctx context.Context, userID uuid.UUID) context.Context {
+	return context.WithValue(ctx, callerKey{}, userID)
+}
+
+// CallerFromContext returns the user recorded by WithCaller.
+func CallerFromContext(ctx context.Context) (uuid.UUID, bool) {
+	userID, ok := ctx.Value(callerKey{}).(uuid.UUID)
+	return userID, ok
+}
+
+type apiKeyCtxKey struct{}
+
+// WithAPIKey records the API key a request is authenticated with. The
+// service then only lets it use the wallets it may act for, see
+// APIKey.MayActFor.
+func WithAPIKey(ctx context.Context, key *APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyCtxKey{}, key)
+}
+
+// APIKeyFromContext returns the key recorded by WithAPIKey, or nil.
+func APIKeyFromContext(ctx context.Context) *APIKey {
+	key, _ := ctx.Value(apiKeyCtxKey{}).(*APIKey)
+	return key
+}
+
 type WalletRepository interface {
 	Create(ctx context.Context, wallet *Wallet) error
+	// GetByID and GetByIDWithLock return ErrWalletNotFound if no wallet has the ID.
 	GetByID(ctx context.Context, id uuid.UUID) (*Wallet, error)
-	GetByIDWithLock(ctx context.Context, tx *gorm.DB, id uuid.UUID) (*Wallet, error)
-	UpdateBalance(ctx context.Context, tx *gorm.DB, id uuid.UUID, newBalance int64) error
-	WithTx(ctx context.Context, fn func(tx *gorm.DB) error) error
+	GetByIDWithLock(ctx context.Context, id uuid.UUID) (*Wallet, error)
+	// AdjustBalance adds delta to the balance projection. It returns
+	// ErrWalletNotFound if no wallet has the ID. Like every method that
+	// changes a balance, it increments the wallet's Version.
+	AdjustBalance(ctx context.Context, id uuid.UUID, delta int64) error
+	// AdjustHeld adds delta to the amount reserved by pending transfers.
+	AdjustHeld(ctx context.Context, id uuid.UUID, delta int64) error
+	// TransferBalance moves amount from sender to receiver in one statement,
+	// without a prior locking read. The debit only applies while the sender's
+	// available balance covers it: ErrInsufficientFunds is returned when it
+	// does not, ErrWalletNotFound when either wallet is missing and
+	// ErrCurrencyMismatch when either wallet holds another currency. The
+	// wallets are returned as they were before the update.
+	TransferBalance(ctx context.Context, senderID, receiverID uuid.UUID, amount Money) (sender, receiver *Wallet, err error)
+	// SetStripes creates the missing stripes 0..stripes-1 of a wallet and
+	// records the stripe count on the wallet row.
+	SetStripes(ctx context.Context, id uuid.UUID, stripes int) error
+	// CreditStripe adds amount to one stripe of a striped wallet.
+	CreditStripe(ctx context.Context, id uuid.UUID, stripe int, amount int64) error
+	// BorrowFromStripes moves up to amount from a wallet's stripes into its
+	// wallet row and returns how much was moved.
+	BorrowFromStripes(ctx context.Context, id uuid.UUID, amount int64) (int64, error)
+	// CollectStripes moves the whole balance of a wallet's stripes into its
+	// wallet row and returns how much was moved. Unlike BorrowFromStripes it
+	// locks every stripe, so no credit lands on them until the transaction
+	// ends.
+	CollectStripes(ctx context.Context, id uuid.UUID) (int64, error)
+	// SumStripes returns the total balance and version of a wallet's stripes.
+	SumStripes(ctx context.Context, id uuid.UUID) (balance, version int64, err error)
+	// ListByUser returns the wallets of a user, oldest first.
+	ListByUser(ctx context.Context, userID uuid.UUID) ([]Wallet, error)
+	// SetStatus changes a wallet's status and increments its Version. It
+	// returns ErrWalletNotFound if no wallet has the ID.
+	SetStatus(ctx context.Context, id uuid.UUID, status WalletStatus) error
+	Transactor
 }
 
 type TransactionRepository interface {
-	Create(ctx context.Context, tx *gorm.DB, transaction *Transaction) error
+	Create(ctx context.Context, transaction *Transaction) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Transaction, error)
+	GetByIDWithLock(ctx context.Context, id uuid.UUID) (*Transaction, error)
+	AddRefundedAmount(ctx context.Context, id uuid.UUID, amount int64) error
+	// ListByWallet returns up to filter.Limit transactions touching filter.WalletID.
+	ListByWallet(ctx context.Context, filter TransactionFilter) ([]Transaction, error)
+}
+
+type LedgerRepository interface {
+	CreateEntries(ctx context.Context, entries []LedgerEntry) error
+	ListByTransaction(ctx context.Context, transactionID uuid.UUID) ([]LedgerEntry, error)
+	// SumByAccount returns the balance of an account as recorded by the ledger.
+	SumByAccount(ctx context.Context, account string) (int64, error)
+	// ListByAccount returns the latest limit entries of an account, newest first.
+	ListByAccount(ctx context.Context, account string, limit int) ([]LedgerEntry, error)
+}
+
+type PendingTransferRepository interface {
+	Create(ctx context.Context, pending *PendingTransfer) error
+	GetByID(ctx context.Context, id uuid.UUID) (*PendingTransfer, error)
+	GetByIDWithLock(ctx context.Context, id uuid.UUID) (*PendingTransfer, error)
+	Update(ctx context.Context, pending *PendingTransfer) error
+	// ListExpired returns up to limit pending transfers still PENDING after their expiry.
+	ListExpired(ctx context.Context, now time.Time, limit int) ([]PendingTransfer, error)
+}
+
+type QuoteRepository interface {
+	Create(ctx context.Context, quote *FXQuote) error
+	GetByIDWithLock(ctx context.Context, id uuid.UUID) (*FXQuote, error)
+	MarkUsed(ctx context.Context, id, transactionID uuid.UUID) error
+}
+
+// RateProvider supplies mid-market exchange rates: one unit of from is worth
+// the returned number of units of to (major units, e.g. USD->JPY ~ 150).
+type RateProvider interface {
+	Rate(ctx context.Context, from, to Currency) (*big.Rat, error)
+}
+
+type IdempotencyRepository interface {
+	// Get returns nil, nil when the key does not exist in scope or has expired.
+	Get(ctx context.Context, scope, key string) (*IdempotencyKey, error)
+	// Create returns ErrIdempotencyKeyInUse if an unexpired record already holds the key in its scope.
+	Create(ctx context.Context, record *IdempotencyKey) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *APIKey) error
+	// GetByHash and GetByIDWithLock return ErrAPIKeyNotFound if no key matches.
+	GetByHash(ctx context.Context, keyHash string) (*APIKey, error)
+	GetByIDWithLock(ctx context.Context, id uuid.UUID) (*APIKey, error)
+	// List returns every key, newest first.
+	List(ctx context.Context) ([]APIKey, error)
+	// Update saves the key's ExpiresAt and RevokedAt.
+	Update(ctx context.Context, key *APIKey) error
+}
+
+// AdminAuditRepository stores the admin audit log. Entries can only be
+// appended; the table rejects updates and deletes.
+type AdminAuditRepository interface {
+	Append(ctx context.Context, entry *AdminAuditEntry) error
+	// List returns up to filter.Limit entries, newest first.
+	List(ctx context.Context, filter AdminAuditFilter) ([]AdminAuditEntry, error)
+}
+
+// RateLimiter counts requests in fixed windows.
+type RateLimiter interface {
+	// Allow counts a request against key and reports whether it is within
+	// limit requests per window. If not, retryAfter is the time left until
+	// the window ends.
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// NonceStore remembers the nonces of signed requests to reject replays.
+type NonceStore interface {
+	// Claim records nonce for ttl and reports whether it was unused.
+	Claim(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+type OutboxRepository interface {
+	Enqueue(ctx context.Context, event *OutboxEvent) error
+	// WithRelayLock runs fn in a transaction holding the relay lock so only one
+	// relay drains the outbox at a time. It returns false if another relay holds it.
+	WithRelayLock(ctx context.Context, fn func(ctx context.Context) error) (bool, error)
+	// ListPending returns up to limit undispatched events in ID order.
+	ListPending(ctx context.Context, limit int) ([]OutboxEvent, error)
+	MarkDispatched(ctx context.Context, id int64, at time.Time) error
+	MarkFailed(ctx context.Context, id int64, lastErr string, nextAttemptAt time.Time) error
 }
 
+// CacheRepository caches wallets by version. Once a wallet is invalidated
+// with a minimum version, older copies are neither stored nor served, so a
+// read that raced with a write cannot put the old balance back in the cache.
 type CacheRepository interface {
+	// GetWallet returns nil on a miss or when the cached copy is stale.
 	GetWallet(ctx context.Context, walletID uuid.UUID) (*Wallet, error)
+	// SetWallet stores wallet unless its Version is below the minimum.
 	SetWallet(ctx context.Context, wallet *Wallet) error
-	InvalidateWallet(ctx context.Context, walletID uuid.UUID) error
+	// InvalidateWallet drops the cached copy and raises the minimum version
+	// of the wallet to minVersion.
+	InvalidateWallet(ctx context.Context, walletID uuid.UUID, minVersion int64) error
 }
 
 type EventProducer interface {
 	PublishTransferEvent(ctx context.Context, event TransferEvent) error
+	PublishRefundEvent(ctx context.Context, event RefundEvent) error
+	PublishWalletStatusEvent(ctx context.Context, event WalletStatusEvent) error
 }
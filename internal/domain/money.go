@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Currency is an ISO 4217 alphabetic currency code.
+type Currency string
+
+// DefaultCurrency is used when a request does not name a currency.
+const DefaultCurrency Currency = "USD"
+
+// currencyExponents holds the ISO 4217 minor-unit exponent of each supported
+// currency: 100 minor units are 1.00 USD, 100 JPY or 0.100 KWD.
+var currencyExponents = map[Currency]int{
+	"AUD": 2, "BHD": 3, "CAD": 2, "CHF": 2, "CLP": 0, "CNY": 2, "EUR": 2,
+	"GBP": 2, "HKD": 2, "IDR": 2, "INR": 2, "ISK": 0, "JOD": 3, "JPY": 0,
+	"KRW": 0, "KWD": 3, "MYR": 2, "OMR": 3, "SGD": 2, "TND": 3, "USD": 2,
+	"VND": 0,
+}
+
+// ParseCurrency returns the supported currency for a case-insensitive code.
+func ParseCurrency(code string) (Currency, error) {
+	c := Currency(strings.ToUpper(strings.TrimSpace(code)))
+	if _, ok := currencyExponents[c]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedCurrency, code)
+	}
+	return c, nil
+}
+
+// Exponent returns the number of minor-unit digits of the currency.
+func (c Currency) Exponent() int {
+	return currencyExponents[c]
+}
+
+// Money is an amount in the minor units of its currency.
+type Money struct {
+	Amount   int64    `json:"amount"`
+	Currency Currency `json:"currency"`
+}
+
+// NewMoney returns amount minor units of currency.
+func NewMoney(amount int64, currency Currency) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Decimal formats the amount in major units using the currency's exponent,
+// e.g. 1050 USD is "10.50", 1050 JPY is "1050" and 1050 KWD is "1.050".
+func (m Money) Decimal() string {
+	exp := m.Currency.Exponent()
+	digits := strconv.FormatInt(m.Amount, 10)
+	sign := ""
+	if m.Amount < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	if exp == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	cut := len(digits) - exp
+	return sign + digits[:cut] + "." + digits[cut:]
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + string(m.Currency)
+}
+
+// Convert returns m in currency to at rate (units of to per unit of m's
+// currency, in major units). The result is rounded down to a whole minor unit.
+func (m Money) Convert(to Currency, rate *big.Rat) (Money, error) {
+	r := new(big.Rat).SetInt64(m.Amount)
+	r.Mul(r, rate)
+	r.Mul(r, new(big.Rat).SetInt(pow10(to.Exponent())))
+	r.Quo(r, new(big.Rat).SetInt(pow10(m.Currency.Exponent())))
+
+	minor := new(big.Int).Quo(r.Num(), r.Denom())
+	if !minor.IsInt64() {
+		return Money{}, fmt.Errorf("%w: conversion overflows", ErrInvalidAmount)
+	}
+	return NewMoney(minor.Int64(), to), nil
+}
+
+// BasisPoints returns bps hundredths of a percent of m, rounded up.
+func (m Money) BasisPoints(bps int64) (Money, error) {
+	fee := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(bps))
+	fee.Add(fee, big.NewInt(9_999))
+	fee.Div(fee, big.NewInt(10_000))
+	if !fee.IsInt64() {
+		return Money{}, fmt.Errorf("%w: fee overflows", ErrInvalidAmount)
+	}
+	return NewMoney(fee.Int64(), m.Currency), nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
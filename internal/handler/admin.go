@@ -0,0 +1,304 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+type staffRolesKey struct{}
+
+// staffRoles returns the /admin roles of the request's bearer token.
+func staffRoles(ctx context.Context) []domain.AdminRole {
+	roles, _ := ctx.Value(staffRolesKey{}).([]domain.AdminRole)
+	return roles
+}
+
+type adminActorKey struct{}
+
+// adminActorFrom returns the actor set by requireRole.
+func adminActorFrom(ctx context.Context) domain.AdminActor {
+	actor, _ := ctx.Value(adminActorKey{}).(domain.AdminActor)
+	return actor
+}
+
+// Role sets of the /admin routes.
+var (
+	anyStaffRole = []domain.AdminRole{domain.AdminRoleViewer, domain.AdminRoleOperator, domain.AdminRoleFinance}
+	operatorRole = []domain.AdminRole{domain.AdminRoleOperator}
+	financeRole  = []domain.AdminRole{domain.AdminRoleFinance}
+)
+
+// requireRole lets through staff whose token grants one of roles, and API
+// keys with the admin scope whose admin role is one of roles. The actor is
+// passed on for the audit log with the role it was let through by.
+func requireRole(roles []domain.AdminRole, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		if key := apiKeyFrom(ctx); key != nil {
+			if !key.HasScope(domain.ScopeAdmin) {
+				respondProblem(w, http.StatusForbidden, CodeInsufficientScope, fmt.Sprintf("API key lacks the %s scope", domain.ScopeAdmin))
+				return
+			}
+			for _, role := range roles {
+				if key.AdminRole == role {
+					actor := domain.AdminActor{ID: "api_key:" + key.Prefix, Role: role}
+					next(w, r.WithContext(context.WithValue(ctx, adminActorKey{}, actor)))
+					return
+				}
+			}
+			respondProblem(w, http.StatusForbidden, CodeInsufficientRole, fmt.Sprintf("API key needs one of the admin roles %v", roles))
+			return
+		}
+
+		caller, ok := domain.CallerFromContext(ctx)
+		if !ok {
+			respondError(w, http.StatusUnauthorized, "a staff token or an API key with the admin scope is required")
+			return
+		}
+		held := staffRoles(ctx)
+		for _, role := range roles {
+			for _, h := range held {
+				if h == role {
+					actor := domain.AdminActor{ID: "user:" + caller.String(), Role: role}
+					next(w, r.WithContext(context.WithValue(ctx, adminActorKey{}, actor)))
+					return
+				}
+			}
+		}
+		respondProblem(w, http.StatusForbidden, CodeInsufficientRole, fmt.Sprintf("one of the roles %v is required", roles))
+	})
+}
+
+type StatusChangeReq struct {
+	Note string `json:"note" validate:"max=1000"`
+}
+
+type AdminCloseWalletReq struct {
+	SweepTo string `json:"sweep_to" validate:"omitempty,uuid"` // Required while the wallet holds money
+	Note    string `json:"note" validate:"max=1000"`
+}
+
+type AdjustmentReq struct {
+	Amount     int64  `json:"amount" validate:"required"` // Signed: positive credits the wallet, negative debits it
+	Currency   string `json:"currency" validate:"omitempty,len=3"`
+	ReasonCode string `json:"reason_code" validate:"required"`
+	Note       string `json:"note" validate:"max=1000"`
+}
+
+// parseAdminLimit reads the optional limit query parameter.
+func parseAdminLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return 0, true
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit <= 0 {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit %q", v))
+		return 0, false
+	}
+	return limit, true
+}
+
+// AdminListWallets lists the wallets of the user in the path.
+func (h *Handler) AdminListWallets(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid user ID format")
+		return
+	}
+
+	wallets, err := h.svc.AdminListWallets(r.Context(), adminActorFrom(r.Context()), userID)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	resp := make([]WalletResponse, 0, len(wallets))
+	for i := range wallets {
+		resp = append(resp, newWalletResponse(&wallets[i]))
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"wallets": resp})
+}
+
+// AdminWalletLedger lists a wallet's latest ledger entries. Supported query
+// parameters: limit.
+func (h *Handler) AdminWalletLedger(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := walletIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	limit, ok := parseAdminLimit(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.svc.AdminLedger(r.Context(), adminActorFrom(r.Context()), walletID, limit)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	if entries == nil {
+		entries = []domain.LedgerEntry{}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"entries": entries})
+}
+
+func (h *Handler) FreezeWallet(w http.ResponseWriter, r *http.Request) {
+	h.changeWalletStatus(w, r, domain.WalletStatusFrozen)
+}
+
+func (h *Handler) UnfreezeWallet(w http.ResponseWriter, r *http.Request) {
+	h.changeWalletStatus(w, r, domain.WalletStatusActive)
+}
+
+func (h *Handler) changeWalletStatus(w http.ResponseWriter, r *http.Request, status domain.WalletStatus) {
+	walletID, ok := walletIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	r, ok = withIfMatch(w, r, walletID)
+	if !ok {
+		return
+	}
+
+	var req StatusChangeReq
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	change := h.svc.UnfreezeWallet
+	if status == domain.WalletStatusFrozen {
+		change = h.svc.FreezeWallet
+	}
+	wallet, err := change(r.Context(), adminActorFrom(r.Context()), walletID, req.Note)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondWallet(w, http.StatusOK, wallet)
+}
+
+func (h *Handler) AdminCloseWallet(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := walletIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	r, ok = withIfMatch(w, r, walletID)
+	if !ok {
+		return
+	}
+
+	var req AdminCloseWalletReq
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	wallet, sweep, err := h.svc.AdminCloseWallet(r.Context(), adminActorFrom(r.Context()), walletID,
+		parseSweepTo(req.SweepTo), req.Note)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondClosedWallet(w, wallet, sweep)
+}
+
+func (h *Handler) PostAdjustment(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := walletIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	r, ok = withIfMatch(w, r, walletID)
+	if !ok {
+		return
+	}
+
+	var req AdjustmentReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	currency, err := parseCurrency(req.Currency)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+	reason, err := domain.ParseAdjustmentReason(req.ReasonCode)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	tx, err := h.svc.PostAdjustment(r.Context(), adminActorFrom(r.Context()), walletID,
+		domain.NewMoney(req.Amount, currency), reason, req.Note)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, newTransactionResponse(tx))
+}
+
+// AdminAuditLog lists the audit log, newest first. Supported query
+// parameters: wallet_id and limit.
+func (h *Handler) AdminAuditLog(w http.ResponseWriter, r *http.Request) {
+	var filter domain.AdminAuditFilter
+	if v := r.URL.Query().Get("wallet_id"); v != "" {
+		walletID, err := uuid.Parse(v)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid wallet ID format")
+			return
+		}
+		filter.WalletID = &walletID
+	}
+	limit, ok := parseAdminLimit(w, r)
+	if !ok {
+		return
+	}
+	filter.Limit = limit
+
+	entries, err := h.svc.AdminAuditLog(r.Context(), adminActorFrom(r.Context()), filter)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	if entries == nil {
+		entries = []domain.AdminAuditEntry{}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{"entries": entries})
+}
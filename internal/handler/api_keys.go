@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"digital-wallet/internal/service"
+	"github.com/google/uuid"
+)
+
+// APIKeyHeader carries the API key of server-to-server requests.
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyRateWindow is the window APIKey.RateLimit counts requests in.
+const apiKeyRateWindow = time.Minute
+
+// apiKeyFrom returns the API key the request was authenticated with, or nil.
+func apiKeyFrom(ctx context.Context) *domain.APIKey {
+	return domain.APIKeyFromContext(ctx)
+}
+
+// APIKeyAuthenticator authenticates requests carrying an API key and applies
+// the key's rate limit. Requests authenticated by a key act for the backend
+// service that holds it: the route's scope is checked by requireScope, and
+// the service only lets the key use its owner's wallets unless it has
+// ScopeAllWallets.
+type APIKeyAuthenticator struct {
+	svc      *service.WalletService
+	limiter  domain.RateLimiter
+	fallback domain.RateLimiter
+}
+
+// NewAPIKeyAuthenticator counts rate-limited requests with limiter. While
+// limiter fails, requests are counted by fallback instead, typically an
+// in-process limiter that only sees this server's share of the traffic. With
+// no fallback, rate-limited keys are refused until limiter recovers.
+func NewAPIKeyAuthenticator(svc *service.WalletService, limiter, fallback domain.RateLimiter) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{svc: svc, limiter: limiter, fallback: fallback}
+}
+
+// Middleware rejects requests with an invalid key with 401, requests over the
+// key's rate limit with 429 and, without a fallback limiter, rate-limited
+// requests that cannot be counted with 503. Requests without a key are passed on as they
+// are, for Authenticator.Middleware to authenticate if end-user tokens are
+// required.
+func (a *APIKeyAuthenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		plain := r.Header.Get(APIKeyHeader)
+		if plain == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, err := a.svc.AuthenticateAPIKey(r.Context(), plain)
+		if err != nil {
+			respondServiceError(w, err)
+			return
+		}
+
+		if key.RateLimit > 0 {
+			allowed, retryAfter, err := a.allow(r.Context(), key)
+			if err != nil {
+				log.Printf("Rate limiter unavailable for API key %s: %v", key.Prefix, err)
+				w.Header().Set("Retry-After", "1")
+				respondProblem(w, http.StatusServiceUnavailable, CodeUnavailable, "the rate limiter is unavailable, retry later")
+				return
+			}
+			if !allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				respondProblem(w, http.StatusTooManyRequests, CodeRateLimited,
+					fmt.Sprintf("API key is limited to %d requests per minute", key.RateLimit))
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(domain.WithAPIKey(r.Context(), key)))
+	})
+}
+
+// allow counts a request of key against its rate limit, with the fallback
+// limiter if the main one fails.
+func (a *APIKeyAuthenticator) allow(ctx context.Context, key *domain.APIKey) (bool, time.Duration, error) {
+	id := "api_key:" + key.ID.String()
+	allowed, retryAfter, err := a.limiter.Allow(ctx, id, key.RateLimit, apiKeyRateWindow)
+	if err == nil || a.fallback == nil {
+		return allowed, retryAfter, err
+	}
+	log.Printf("Rate limiter unavailable for API key %s, counting in process: %v", key.Prefix, err)
+	return a.fallback.Allow(ctx, id, key.RateLimit, apiKeyRateWindow)
+}
+
+// requireScope rejects requests authenticated by an API key without scope
+// with 403. Other requests are left to the end-user checks of the service.
+func requireScope(scope domain.APIScope, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := apiKeyFrom(r.Context()); key != nil && !key.HasScope(scope) {
+			respondProblem(w, http.StatusForbidden, CodeInsufficientScope, fmt.Sprintf("API key lacks the %s scope", scope))
+			return
+		}
+		next(w, r)
+	})
+}
+
+// requireAPIKey is requireScope for routes that only API keys may call.
+func requireAPIKey(scope domain.APIScope, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKeyFrom(r.Context()) == nil {
+			respondError(w, http.StatusUnauthorized, fmt.Sprintf("an API key with the %s scope is required", scope))
+			return
+		}
+		requireScope(scope, next).ServeHTTP(w, r)
+	})
+}
+
+type APIKeyReq struct {
+	Name             string   `json:"name" validate:"required"`
+	Scopes           []string `json:"scopes" validate:"required,min=1"`
+	OwnerID          string   `json:"owner_id" validate:"omitempty,uuid"`           // Omit for a key acting for nobody, or everybody with wallets:all
+	AdminRole        string   `json:"admin_role"`                                   // Staff role of an admin key, see requireRole
+	RateLimit        int      `json:"rate_limit" validate:"gte=0"`                  // Requests per minute, 0 for no limit
+	ExpiresInSeconds int64    `json:"expires_in_seconds" validate:"omitempty,gt=0"` // Omit for a key that does not expire
+}
+
+type RotateAPIKeyReq struct {
+	OverlapSeconds *int64 `json:"overlap_seconds" validate:"omitempty,gte=0"` // Defaults to service.DefaultAPIKeyRotationOverlap
+}
+
+// APIKeyResponse is an issued key. Key and SigningSecret are only ever
+// returned here.
+type APIKeyResponse struct {
+	*domain.APIKey
+	Key           string `json:"key"`
+	SigningSecret string `json:"signing_secret"` // Signs the key's requests, see SignRequest
+}
+
+func (h *Handler) IssueAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req APIKeyReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	key := &domain.APIKey{Name: req.Name, RateLimit: req.RateLimit}
+	if req.OwnerID != "" {
+		ownerID, _ := uuid.Parse(req.OwnerID)
+		key.OwnerID = &ownerID
+	}
+	for _, s := range req.Scopes {
+		scope, err := domain.ParseAPIScope(s)
+		if err != nil {
+			respondServiceError(w, err)
+			return
+		}
+		key.Scopes = append(key.Scopes, scope)
+	}
+	if req.AdminRole != "" {
+		role, err := domain.ParseAdminRole(req.AdminRole)
+		if err != nil {
+			respondServiceError(w, err)
+			return
+		}
+		key.AdminRole = role
+	}
+	if req.ExpiresInSeconds > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresInSeconds) * time.Second)
+		key.ExpiresAt = &expiresAt
+	}
+
+	plain, err := h.svc.IssueAPIKey(r.Context(), key)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, APIKeyResponse{APIKey: key, Key: plain, SigningSecret: key.SigningSecret})
+}
+
+func (h *Handler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.svc.ListAPIKeys(r.Context())
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"api_keys": keys})
+}
+
+func (h *Handler) RotateAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiKeyIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req RotateAPIKeyReq
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	overlap := service.DefaultAPIKeyRotationOverlap
+	if req.OverlapSeconds != nil {
+		overlap = time.Duration(*req.OverlapSeconds) * time.Second
+	}
+
+	key, plain, err := h.svc.RotateAPIKey(r.Context(), id, overlap)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, APIKeyResponse{APIKey: key, Key: plain, SigningSecret: key.SigningSecret})
+}
+
+func (h *Handler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiKeyIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	key, err := h.svc.RevokeAPIKey(r.Context(), id)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, key)
+}
+
+func apiKeyIDFromPath(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid API key ID format")
+		return uuid.Nil, false
+	}
+	return id, true
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"digital-wallet/internal/domain"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// tokenLeeway is the clock skew tolerated on exp, nbf and iat.
+const tokenLeeway = 30 * time.Second
+
+// AuthConfig configures the bearer tokens accepted by Authenticator. HS256
+// tokens are accepted if HMACSecret is set, RS256 tokens if JWKSFile names a
+// JSON Web Key Set with the RSA public keys. Issuer and Audience are checked
+// when set.
+type AuthConfig struct {
+	HMACSecret []byte
+	JWKSFile   string
+	Issuer     string
+	Audience   string
+}
+
+// Enabled reports whether any token can be verified with cfg.
+func (cfg AuthConfig) Enabled() bool {
+	return len(cfg.HMACSecret) > 0 || cfg.JWKSFile != ""
+}
+
+// Authenticator verifies the JWT bearer token of each request. The token's
+// subject must be a user ID; it becomes the caller the service authorizes
+// against, see domain.WithCaller. Staff tokens also carry their /admin roles
+// in a "roles" claim.
+type Authenticator struct {
+	secret  []byte
+	rsaKeys map[string]*rsa.PublicKey // By kid
+	parser  *jwt.Parser
+}
+
+// NewAuthenticator loads the keys named by cfg.
+func NewAuthenticator(cfg AuthConfig) (*Authenticator, error) {
+	if !cfg.Enabled() {
+		return nil, errors.New("no JWT secret or key set configured")
+	}
+	a := &Authenticator{secret: cfg.HMACSecret}
+
+	var methods []string
+	if len(cfg.HMACSecret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.rsaKeys = keys
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(tokenLeeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	a.parser = jwt.NewParser(opts...)
+	return a, nil
+}
+
+// tokenClaims are the claims read from a bearer token.
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Roles []string `json:"roles,omitempty"` // Staff roles, see domain.AdminRole
+}
+
+// Middleware rejects requests without a valid bearer token with 401 and
+// passes the others on with the token's subject as the caller and its staff
+// roles. Requests already authenticated by APIKeyAuthenticator need no token.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKeyFrom(r.Context()) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userID, roles, err := a.authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="digital-wallet"`)
+			respondError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		ctx := domain.WithCaller(r.Context(), userID)
+		if len(roles) > 0 {
+			ctx = context.WithValue(ctx, staffRolesKey{}, roles)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authenticate returns the user and staff roles of the request's token.
+// Unknown roles are ignored.
+func (a *Authenticator) authenticate(r *http.Request) (uuid.UUID, []domain.AdminRole, error) {
+	scheme, raw, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || raw == "" {
+		return uuid.Nil, nil, errors.New("missing bearer token")
+	}
+
+	var claims tokenClaims
+	if _, err := a.parser.ParseWithClaims(strings.TrimSpace(raw), &claims, a.key); err != nil {
+		return uuid.Nil, nil, fmt.Errorf("invalid token: %w", err)
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, nil, errors.New("invalid token: subject is not a user ID")
+	}
+
+	var roles []domain.AdminRole
+	for _, name := range claims.Roles {
+		if role, err := domain.ParseAdminRole(name); err == nil {
+			roles = append(roles, role)
+		}
+	}
+	return userID, roles, nil
+}
+
+// key returns the key to verify token with. The parser has already checked
+// that its algorithm is one of those configured.
+func (a *Authenticator) key(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		return a.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if key, ok := a.rsaKeys[kid]; ok {
+		return key, nil
+	}
+	// A set with a single key may be used by tokens without a kid.
+	if kid == "" && len(a.rsaKeys) == 1 {
+		for _, key := range a.rsaKeys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// loadJWKS reads the RSA signing keys from a JSON Web Key Set file. Keys of
+// other types or uses are skipped.
+func loadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS %s: %w", path, err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS %s: key %q: %w", path, k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWKS %s: key %q: %w", path, k.Kid, err)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS %s has no RSA signing keys", path)
+	}
+	return keys, nil
+}
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"digital-wallet/internal/domain"
+)
+
+// ProblemContentType is the media type of error responses (RFC 7807).
+const ProblemContentType = "application/problem+json"
+
+// problemTypePrefix turns an error code into the problem type URI.
+const problemTypePrefix = "urn:digital-wallet:problem:"
+
+// Problem is an RFC 7807 problem details object. Code is a stable,
+// machine-readable identifier of the error; Type is derived from it.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   string `json:"code"`
+}
+
+// Error codes that are not tied to a domain error.
+const (
+	CodeInvalidRequest     = "invalid_request"
+	CodeUnauthorized       = "unauthorized"
+	CodeInsufficientScope  = "insufficient_scope"
+	CodeInsufficientRole   = "insufficient_role"
+	CodeRateLimited        = "rate_limited"
+	CodeInvalidSignature   = "invalid_signature"
+	CodeNotFound           = "not_found"
+	CodePreconditionFailed = "precondition_failed"
+	CodeInternal           = "internal_error"
+	CodeUnavailable        = "service_unavailable"
+)
+
+type problemKind struct {
+	status int
+	code   string
+}
+
+// problemKinds maps the domain errors to their status and code. The first
+// match in errors.Is order wins, so wrapped errors map like their sentinel.
+var problemKinds = []struct {
+	err error
+	problemKind
+}{
+	{domain.ErrWalletNotFound, problemKind{http.StatusNotFound, "wallet_not_found"}},
+	{domain.ErrTransactionNotFound, problemKind{http.StatusNotFound, "transaction_not_found"}},
+	{domain.ErrPendingNotFound, problemKind{http.StatusNotFound, "pending_transfer_not_found"}},
+	{domain.ErrQuoteNotFound, problemKind{http.StatusNotFound, "quote_not_found"}},
+	{domain.ErrAPIKeyNotFound, problemKind{http.StatusNotFound, "api_key_not_found"}},
+
+	{domain.ErrInvalidAPIKey, problemKind{http.StatusUnauthorized, CodeUnauthorized}},
+	{domain.ErrForbidden, problemKind{http.StatusForbidden, "forbidden"}},
+
+	{domain.ErrPendingNotActive, problemKind{http.StatusConflict, "pending_transfer_not_active"}},
+	{domain.ErrIdempotencyKeyInUse, problemKind{http.StatusConflict, "idempotency_key_in_use"}},
+	{domain.ErrQuoteUsed, problemKind{http.StatusConflict, "quote_used"}},
+	{domain.ErrAPIKeyNotActive, problemKind{http.StatusConflict, "api_key_not_active"}},
+	{domain.ErrWalletFrozen, problemKind{http.StatusConflict, "wallet_frozen"}},
+	{domain.ErrWalletClosed, problemKind{http.StatusConflict, "wallet_closed"}},
+	{domain.ErrStatusUnchanged, problemKind{http.StatusConflict, "status_unchanged"}},
+	{domain.ErrInvalidTransition, problemKind{http.StatusConflict, "invalid_status_transition"}},
+	{domain.ErrWalletNotEmpty, problemKind{http.StatusConflict, "wallet_not_empty"}},
+
+	{domain.ErrVersionMismatch, problemKind{http.StatusPreconditionFailed, "version_mismatch"}},
+
+	{domain.ErrInsufficientFunds, problemKind{http.StatusUnprocessableEntity, "insufficient_funds"}},
+	{domain.ErrInvalidAmount, problemKind{http.StatusUnprocessableEntity, "invalid_amount"}},
+	{domain.ErrSelfTransfer, problemKind{http.StatusUnprocessableEntity, "self_transfer"}},
+	{domain.ErrCurrencyMismatch, problemKind{http.StatusUnprocessableEntity, "currency_mismatch"}},
+	{domain.ErrRateUnavailable, problemKind{http.StatusUnprocessableEntity, "rate_unavailable"}},
+	{domain.ErrQuoteExpired, problemKind{http.StatusUnprocessableEntity, "quote_expired"}},
+	{domain.ErrCaptureExceedsHold, problemKind{http.StatusUnprocessableEntity, "capture_exceeds_hold"}},
+	{domain.ErrRefundNotAllowed, problemKind{http.StatusUnprocessableEntity, "refund_not_allowed"}},
+	{domain.ErrRefundExceedsAmount, problemKind{http.StatusUnprocessableEntity, "refund_exceeds_amount"}},
+	{domain.ErrInvalidStripeCount, problemKind{http.StatusUnprocessableEntity, "invalid_stripe_count"}},
+	{domain.ErrIdempotencyKeyReused, problemKind{http.StatusUnprocessableEntity, "idempotency_key_reused"}},
+
+	{domain.ErrInvalidCursor, problemKind{http.StatusBadRequest, "invalid_cursor"}},
+	{domain.ErrInvalidFilter, problemKind{http.StatusBadRequest, "invalid_filter"}},
+	{domain.ErrInvalidBatch, problemKind{http.StatusBadRequest, "invalid_batch"}},
+	{domain.ErrUnsupportedCurrency, problemKind{http.StatusBadRequest, "unsupported_currency"}},
+	{domain.ErrInvalidScope, problemKind{http.StatusBadRequest, "invalid_scope"}},
+	{domain.ErrInvalidAdminRole, problemKind{http.StatusBadRequest, "invalid_admin_role"}},
+	{domain.ErrInvalidReasonCode, problemKind{http.StatusBadRequest, "invalid_reason_code"}},
+
+	{domain.ErrFeatureDisabled, problemKind{http.StatusNotImplemented, "feature_disabled"}},
+}
+
+// classifyError returns the status and code for an error returned by the
+// service. Errors that are not domain errors are internal, unless they show
+// that the database or another dependency is unreachable or overloaded.
+func classifyError(err error) problemKind {
+	for _, k := range problemKinds {
+		if errors.Is(err, k.err) {
+			return k.problemKind
+		}
+	}
+	if isUnavailable(err) {
+		return problemKind{http.StatusServiceUnavailable, CodeUnavailable}
+	}
+	return problemKind{http.StatusInternalServerError, CodeInternal}
+}
+
+// isUnavailable reports whether err is a transient infrastructure failure:
+// a connection problem, a timeout, or a Postgres error of a class the client
+// may retry (connection exceptions, transaction rollbacks such as deadlocks,
+// insufficient resources and operator intervention).
+func isUnavailable(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		state := pgErr.SQLState()
+		for _, class := range []string{"08", "40", "53", "57"} {
+			if strings.HasPrefix(state, class) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// respondServiceError maps an error returned by the service to a problem
+// response. Details of internal errors are logged, not returned.
+func respondServiceError(w http.ResponseWriter, err error) {
+	kind := classifyError(err)
+	detail := err.Error()
+	switch kind.status {
+	case http.StatusInternalServerError:
+		log.Printf("internal error: %v", err)
+		detail = domain.ErrInternalServerError.Error()
+	case http.StatusServiceUnavailable:
+		log.Printf("dependency unavailable: %v", err)
+		detail = "the service is temporarily unavailable, retry later"
+		w.Header().Set("Retry-After", "1")
+	}
+	respondProblem(w, kind.status, kind.code, detail)
+}
+
+// respondError writes a problem for a request the handler rejected itself,
+// such as a malformed body, with the generic code for the status.
+func respondError(w http.ResponseWriter, status int, msg string) {
+	code := CodeInvalidRequest
+	switch status {
+	case http.StatusUnauthorized:
+		code = CodeUnauthorized
+	case http.StatusNotFound:
+		code = CodeNotFound
+	case http.StatusPreconditionFailed:
+		code = CodePreconditionFailed
+	case http.StatusInternalServerError:
+		code = CodeInternal
+	}
+	respondProblem(w, status, code, msg)
+}
+
+func respondProblem(w http.ResponseWriter, status int, code, detail string) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Problem{
+		Type:   problemTypePrefix + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	})
+}
@@ -1,37 +1,38 @@
 package handler
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"digital-wallet/internal/domain"
 	"digital-wallet/internal/service"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 )
 
 type Handler struct {
-	svc *service.WalletService
+	svc       *service.WalletService
 	validator *validator.Validate
 }
 
 func NewHandler(svc *service.WalletService) *Handler {
 	return &Handler{
-		svc: svc,
+		svc:       svc,
 		validator: validator.New(),
 	}
 }
 
 // Responses
-type ErrorResponse struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-}
-
-func respondError(w http.ResponseWriter, code int, msg string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	json.NewEncoder(w).Encode(ErrorResponse{Code: code, Message: msg})
-}
+// Errors are written as problem details, see errors.go.
 
 func respondJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -39,20 +40,163 @@ func respondJSON(w http.ResponseWriter, code int, payload interface{}) {
 	json.NewEncoder(w).Encode(payload)
 }
 
+// WalletResponse adds the available balance and the balances formatted in
+// major units of the wallet's currency.
+type WalletResponse struct {
+	*domain.Wallet
+	AvailableBalance        int64  `json:"available_balance"`
+	BalanceDecimal          string `json:"balance_decimal"`
+	AvailableBalanceDecimal string `json:"available_balance_decimal"`
+}
+
+func newWalletResponse(wallet *domain.Wallet) WalletResponse {
+	return WalletResponse{
+		Wallet:                  wallet,
+		AvailableBalance:        wallet.AvailableBalance(),
+		BalanceDecimal:          domain.NewMoney(wallet.Balance, wallet.Currency).Decimal(),
+		AvailableBalanceDecimal: domain.NewMoney(wallet.AvailableBalance(), wallet.Currency).Decimal(),
+	}
+}
+
+// TransactionResponse adds the amount formatted in major units of its currency.
+type TransactionResponse struct {
+	*domain.Transaction
+	AmountDecimal string `json:"amount_decimal"`
+}
+
+func newTransactionResponse(tx *domain.Transaction) TransactionResponse {
+	return TransactionResponse{
+		Transaction:   tx,
+		AmountDecimal: domain.NewMoney(tx.Amount, tx.Currency).Decimal(),
+	}
+}
+
+// BatchTransferResponse lists the outcome of every leg in request order.
+type BatchTransferResponse struct {
+	Mode    domain.BatchMode   `json:"mode"`
+	Results []BatchLegResponse `json:"results"`
+}
+
+type BatchLegResponse struct {
+	Index       int                  `json:"index"`
+	Status      string               `json:"status"` // "committed" or "failed"
+	Transaction *TransactionResponse `json:"transaction,omitempty"`
+	Code        string               `json:"code,omitempty"` // Error code, as in problem responses
+	Error       string               `json:"error,omitempty"`
+}
+
+// QuoteResponse adds the quote amounts formatted in major units.
+type QuoteResponse struct {
+	*domain.FXQuote
+	SourceAmountDecimal string `json:"source_amount_decimal"`
+	FeeDecimal          string `json:"fee_decimal"`
+	TargetAmountDecimal string `json:"target_amount_decimal"`
+}
+
+func newQuoteResponse(q *domain.FXQuote) QuoteResponse {
+	return QuoteResponse{
+		FXQuote:             q,
+		SourceAmountDecimal: domain.NewMoney(q.SourceAmount, q.SourceCurrency).Decimal(),
+		FeeDecimal:          domain.NewMoney(q.Fee, q.SourceCurrency).Decimal(),
+		TargetAmountDecimal: domain.NewMoney(q.TargetAmount, q.TargetCurrency).Decimal(),
+	}
+}
+
+type CaptureResponse struct {
+	PendingTransfer *domain.PendingTransfer `json:"pending_transfer"`
+	Transaction     TransactionResponse     `json:"transaction"`
+}
+
+// CloseWalletResponse is the closed wallet and the transaction that swept
+// its balance, if there was one.
+type CloseWalletResponse struct {
+	Wallet WalletResponse       `json:"wallet"`
+	Sweep  *TransactionResponse `json:"sweep,omitempty"`
+}
+
+type TransactionPageResponse struct {
+	Transactions []TransactionResponse `json:"transactions"`
+	NextCursor   string                `json:"next_cursor,omitempty"`
+}
+
 // Request Models
+// Currency fields are ISO 4217 codes and default to USD. Amounts are in the
+// currency's minor units.
+
 type CreateWalletReq struct {
-	UserID string `json:"user_id" validate:"required,uuid"`
+	UserID   string `json:"user_id" validate:"required,uuid"`
+	Currency string `json:"currency" validate:"omitempty,len=3"`
+}
+
+type StripesReq struct {
+	Stripes int `json:"stripes" validate:"required,gt=0"`
+}
+
+type CloseWalletReq struct {
+	SweepTo string `json:"sweep_to" validate:"omitempty,uuid"` // Required while the wallet holds money
 }
 
 type TransferReq struct {
 	SenderID   string `json:"sender_id" validate:"required,uuid"`
 	ReceiverID string `json:"receiver_id" validate:"required,uuid"`
 	Amount     int64  `json:"amount" validate:"required,gt=0"`
+	Currency   string `json:"currency" validate:"omitempty,len=3"`
+}
+
+type BatchTransferReq struct {
+	Mode string        `json:"mode" validate:"omitempty,oneof=atomic per_leg"` // Defaults to atomic
+	Legs []TransferReq `json:"legs" validate:"required,min=1,dive"`
+}
+
+type QuoteReq struct {
+	SenderID       string `json:"sender_id" validate:"required,uuid"` // The wallet the quote can be used from
+	SourceCurrency string `json:"source_currency" validate:"required,len=3"`
+	TargetCurrency string `json:"target_currency" validate:"required,len=3"`
+	Amount         int64  `json:"amount" validate:"required,gt=0"` // Minor units of SourceCurrency
+}
+
+type FXTransferReq struct {
+	SenderID   string `json:"sender_id" validate:"required,uuid"`
+	ReceiverID string `json:"receiver_id" validate:"required,uuid"`
+	QuoteID    string `json:"quote_id" validate:"required,uuid"`
+}
+
+type PendingTransferReq struct {
+	SenderID         string `json:"sender_id" validate:"required,uuid"`
+	ReceiverID       string `json:"receiver_id" validate:"required,uuid"`
+	Amount           int64  `json:"amount" validate:"required,gt=0"`
+	Currency         string `json:"currency" validate:"omitempty,len=3"`
+	ExpiresInSeconds int64  `json:"expires_in_seconds" validate:"omitempty,gt=0"`
+}
+
+type CaptureReq struct {
+	Amount int64 `json:"amount" validate:"omitempty,gt=0"` // Omit to capture the full hold
+}
+
+type RefundReq struct {
+	Amount int64 `json:"amount" validate:"omitempty,gt=0"` // Omit to refund the remaining amount
+}
+
+type AmountReq struct {
+	Amount   int64  `json:"amount" validate:"required,gt=0"`
+	Currency string `json:"currency" validate:"omitempty,len=3"`
+}
+
+// parseCurrency returns the requested currency, or the default when empty.
+func parseCurrency(code string) (domain.Currency, error) {
+	if code == "" {
+		return domain.DefaultCurrency, nil
+	}
+	return domain.ParseCurrency(code)
 }
 
 // Handlers
 
 func (h *Handler) CreateWallet(w http.ResponseWriter, r *http.Request) {
+	if !rejectIfMatch(w, r) {
+		return
+	}
+
 	var req CreateWalletReq
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondError(w, http.StatusBadRequest, "Invalid request body")
@@ -64,42 +208,315 @@ func (h *Handler) CreateWallet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	currency, err := parseCurrency(req.Currency)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
 	uid, _ := uuid.Parse(req.UserID)
-	wallet, err := h.svc.CreateWallet(r.Context(), uid)
+	wallet, err := h.svc.CreateWallet(r.Context(), uid, currency)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondServiceError(w, err)
 		return
 	}
 
-	respondJSON(w, http.StatusCreated, wallet)
+	respondWallet(w, http.StatusCreated, wallet)
 }
 
-func (h *Handler) GetBalance(w http.ResponseWriter, r *http.Request) {
+// respondWallet writes a wallet with its version as the ETag.
+func respondWallet(w http.ResponseWriter, code int, wallet *domain.Wallet) {
+	w.Header().Set("ETag", walletETag(wallet.Version))
+	respondJSON(w, code, newWalletResponse(wallet))
+}
+
+// walletETag formats a wallet version as a strong entity tag.
+func walletETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// withIfMatch carries an If-Match header into r's context as a version
+// precondition on walletID. "*" and a missing header match any version. It
+// writes a 412 response and returns false when the header is not a wallet
+// ETag, since it can then match no version.
+func withIfMatch(w http.ResponseWriter, r *http.Request, walletID uuid.UUID) (*http.Request, bool) {
+	return ifMatch(w, r, func(ctx context.Context, version int64) context.Context {
+		return service.ExpectVersion(ctx, walletID, version)
+	})
+}
+
+// withIfMatchPayer is withIfMatch for requests that name the wallet they
+// debit only through a pending transfer or transaction, see
+// service.ExpectPayerVersion.
+func withIfMatchPayer(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	return ifMatch(w, r, service.ExpectPayerVersion)
+}
+
+func ifMatch(w http.ResponseWriter, r *http.Request, expect func(context.Context, int64) context.Context) (*http.Request, bool) {
+	tag := strings.TrimSpace(r.Header.Get("If-Match"))
+	if tag == "" || tag == "*" {
+		return r, true
+	}
+
+	version, err := strconv.ParseInt(strings.Trim(tag, `"`), 10, 64)
+	if err != nil || walletETag(version) != tag {
+		respondServiceError(w, domain.ErrVersionMismatch)
+		return r, false
+	}
+	return r.WithContext(expect(r.Context(), version)), true
+}
+
+// rejectIfMatch writes a 400 response and returns false when a request that
+// changes no existing wallet carries an If-Match header, rather than
+// silently ignoring the precondition.
+func rejectIfMatch(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("If-Match") != "" {
+		respondError(w, http.StatusBadRequest, "If-Match is not supported on this endpoint")
+		return false
+	}
+	return true
+}
+
+// walletIDFromPath parses the {id} path value, writing a 400 response and
+// returning false when it is missing or malformed.
+func walletIDFromPath(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
 	idStr := r.PathValue("id")
 	if idStr == "" {
 		respondError(w, http.StatusBadRequest, "Missing wallet ID")
-		return
+		return uuid.Nil, false
 	}
 
 	walletID, err := uuid.Parse(idStr)
 	if err != nil {
 		respondError(w, http.StatusBadRequest, "Invalid wallet ID format")
+		return uuid.Nil, false
+	}
+	return walletID, true
+}
+
+func (h *Handler) GetBalance(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := walletIDFromPath(w, r)
+	if !ok {
 		return
 	}
 
 	wallet, err := h.svc.GetBalance(r.Context(), walletID)
 	if err != nil {
-		if err.Error() == "record not found" { // Simplified error check
-			respondError(w, http.StatusNotFound, "Wallet not found")
+		respondServiceError(w, err)
+		return
+	}
+
+	respondWallet(w, http.StatusOK, wallet)
+}
+
+// GetTransactions lists a wallet's transactions. Supported query parameters:
+// limit, cursor, type, direction (incoming|outgoing), min_amount, max_amount,
+// from and to (RFC 3339).
+func (h *Handler) GetTransactions(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := walletIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	filter, err := parseTransactionFilter(r.URL.Query())
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCursor) {
+			respondServiceError(w, err)
 			return
 		}
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	filter.WalletID = walletID
 
-	respondJSON(w, http.StatusOK, wallet)
+	page, err := h.svc.GetTransactionHistory(r.Context(), filter)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	resp := TransactionPageResponse{Transactions: make([]TransactionResponse, 0, len(page.Transactions))}
+	for i := range page.Transactions {
+		resp.Transactions = append(resp.Transactions, newTransactionResponse(&page.Transactions[i]))
+	}
+	if page.NextCursor != nil {
+		resp.NextCursor = page.NextCursor.Encode()
+	}
+	respondJSON(w, http.StatusOK, resp)
 }
 
+func parseTransactionFilter(q url.Values) (domain.TransactionFilter, error) {
+	var filter domain.TransactionFilter
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			return filter, fmt.Errorf("invalid limit %q", v)
+		}
+		filter.Limit = limit
+	}
+	if v := q.Get("cursor"); v != "" {
+		cursor, err := domain.DecodeTransactionCursor(v)
+		if err != nil {
+			return filter, err
+		}
+		filter.After = cursor
+	}
+
+	filter.Type = strings.ToUpper(q.Get("type"))
+	filter.Direction = strings.ToLower(q.Get("direction"))
+
+	for name, dst := range map[string]**int64{"min_amount": &filter.MinAmount, "max_amount": &filter.MaxAmount} {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s %q", name, v)
+			}
+			*dst = &n
+		}
+	}
+	for name, dst := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s %q, expected RFC 3339", name, v)
+			}
+			*dst = &t
+		}
+	}
+
+	return filter, nil
+}
+
+// SetWalletStripes turns a hot wallet into a striped wallet, or raises its
+// stripe count.
+func (h *Handler) SetWalletStripes(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := walletIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	r, ok = withIfMatch(w, r, walletID)
+	if !ok {
+		return
+	}
+
+	var req StripesReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	wallet, err := h.svc.SetWalletStripes(r.Context(), walletID, req.Stripes)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondWallet(w, http.StatusOK, wallet)
+}
+
+// CloseWallet closes a wallet, sweeping its balance to the wallet named in
+// the optional body.
+func (h *Handler) CloseWallet(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := walletIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	r, ok = withIfMatch(w, r, walletID)
+	if !ok {
+		return
+	}
+
+	var req CloseWalletReq
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	wallet, sweep, err := h.svc.CloseWallet(r.Context(), walletID, parseSweepTo(req.SweepTo))
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondClosedWallet(w, wallet, sweep)
+}
+
+// parseSweepTo returns the ID of a validated sweep_to field, or nil if empty.
+func parseSweepTo(v string) *uuid.UUID {
+	if v == "" {
+		return nil
+	}
+	id := uuid.MustParse(v)
+	return &id
+}
+
+func respondClosedWallet(w http.ResponseWriter, wallet *domain.Wallet, sweep *domain.Transaction) {
+	resp := CloseWalletResponse{Wallet: newWalletResponse(wallet)}
+	if sweep != nil {
+		tx := newTransactionResponse(sweep)
+		resp.Sweep = &tx
+	}
+	w.Header().Set("ETag", walletETag(wallet.Version))
+	respondJSON(w, http.StatusOK, resp)
+}
+
+// GetReconciliation compares a wallet's balance with the sum of its ledger entries.
+func (h *Handler) GetReconciliation(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := walletIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	check, err := h.svc.VerifyWalletBalance(r.Context(), walletID)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, check)
+}
+
+// GetTransactionEntries returns the ledger legs of a transaction.
+func (h *Handler) GetTransactionEntries(w http.ResponseWriter, r *http.Request) {
+	transactionID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid transaction ID format")
+		return
+	}
+
+	entries, err := h.svc.GetTransactionEntries(r.Context(), transactionID)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+	if len(entries) == 0 {
+		respondServiceError(w, domain.ErrTransactionNotFound)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, entries)
+}
+
+// IdempotencyKeyHeader lets clients retry POST /transfers safely.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const maxIdempotencyKeyLen = 255
+
 func (h *Handler) Transfer(w http.ResponseWriter, r *http.Request) {
 	var req TransferReq
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -112,14 +529,426 @@ func (h *Handler) Transfer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	currency, err := parseCurrency(req.Currency)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
 	senderID, _ := uuid.Parse(req.SenderID)
 	receiverID, _ := uuid.Parse(req.ReceiverID)
+	amount := domain.NewMoney(req.Amount, currency)
+
+	// If-Match applies to the sender's wallet
+	r, ok := withIfMatch(w, r, senderID)
+	if !ok {
+		return
+	}
 
-	tx, err := h.svc.TransferMoney(r.Context(), senderID, receiverID, req.Amount)
+	if key := r.Header.Get(IdempotencyKeyHeader); key != "" {
+		h.idempotentTransfer(w, r, key, requestHash(r, req), senderID, receiverID, amount)
+		return
+	}
+
+	tx, err := h.svc.TransferMoney(r.Context(), senderID, receiverID, amount)
 	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, newTransactionResponse(tx))
+}
+
+func (h *Handler) BatchTransfer(w http.ResponseWriter, r *http.Request) {
+	var req BatchTransferReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
 		respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	respondJSON(w, http.StatusOK, tx)
+	mode := domain.BatchModeAtomic
+	if req.Mode != "" {
+		mode = domain.BatchMode(req.Mode)
+	}
+
+	legs := make([]domain.TransferLeg, len(req.Legs))
+	for i, leg := range req.Legs {
+		currency, err := parseCurrency(leg.Currency)
+		if err != nil {
+			respondServiceError(w, fmt.Errorf("leg %d: %w", i, err))
+			return
+		}
+		legs[i].SenderID, _ = uuid.Parse(leg.SenderID)
+		legs[i].ReceiverID, _ = uuid.Parse(leg.ReceiverID)
+		legs[i].Amount = domain.NewMoney(leg.Amount, currency)
+	}
+
+	// If-Match applies to the sender's wallet, so every leg needs the same one
+	if r.Header.Get("If-Match") != "" {
+		for _, leg := range legs {
+			if leg.SenderID != legs[0].SenderID {
+				respondError(w, http.StatusBadRequest, "If-Match requires every leg to have the same sender")
+				return
+			}
+		}
+	}
+	r, ok := withIfMatch(w, r, legs[0].SenderID)
+	if !ok {
+		return
+	}
+
+	results, err := h.svc.TransferBatch(r.Context(), legs, mode)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	resp := BatchTransferResponse{Mode: mode, Results: make([]BatchLegResponse, len(results))}
+	for i, result := range results {
+		item := BatchLegResponse{Index: result.Index, Status: "committed"}
+		if result.Err != nil {
+			item.Status = "failed"
+			item.Code = classifyError(result.Err).code
+			item.Error = result.Err.Error()
+		} else {
+			tx := newTransactionResponse(result.Transaction)
+			item.Transaction = &tx
+		}
+		resp.Results[i] = item
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) idempotentTransfer(w http.ResponseWriter, r *http.Request, key, hash string, senderID, receiverID uuid.UUID, amount domain.Money) {
+	if len(key) > maxIdempotencyKeyLen {
+		respondError(w, http.StatusBadRequest, "Idempotency-Key is too long")
+		return
+	}
+
+	render := func(tx *domain.Transaction) (int, []byte, error) {
+		data, err := json.Marshal(newTransactionResponse(tx))
+		return http.StatusOK, data, err
+	}
+
+	record, replayed, err := h.svc.TransferMoneyIdempotent(r.Context(), key, hash, senderID, receiverID, amount, render)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	if replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(record.ResponseStatus)
+	w.Write(record.ResponseBody)
+}
+
+// requestHash fingerprints the parts of a request that must match for a
+// retry to reuse an idempotency key. The decoded request is hashed rather
+// than the raw body so formatting differences between retries don't matter.
+func requestHash(r *http.Request, req interface{}) string {
+	payload, _ := json.Marshal(req)
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (h *Handler) Deposit(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := walletIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	r, ok = withIfMatch(w, r, walletID)
+	if !ok {
+		return
+	}
+
+	var req AmountReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	currency, err := parseCurrency(req.Currency)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	tx, err := h.svc.Deposit(r.Context(), walletID, domain.NewMoney(req.Amount, currency))
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, newTransactionResponse(tx))
+}
+
+func (h *Handler) Withdraw(w http.ResponseWriter, r *http.Request) {
+	walletID, ok := walletIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	r, ok = withIfMatch(w, r, walletID)
+	if !ok {
+		return
+	}
+
+	var req AmountReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	currency, err := parseCurrency(req.Currency)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	tx, err := h.svc.Withdraw(r.Context(), walletID, domain.NewMoney(req.Amount, currency))
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, newTransactionResponse(tx))
+}
+
+func (h *Handler) CreateQuote(w http.ResponseWriter, r *http.Request) {
+	if !rejectIfMatch(w, r) {
+		return
+	}
+
+	var req QuoteReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	source, err := domain.ParseCurrency(req.SourceCurrency)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+	target, err := domain.ParseCurrency(req.TargetCurrency)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	senderID, _ := uuid.Parse(req.SenderID)
+	quote, err := h.svc.CreateQuote(r.Context(), senderID, domain.NewMoney(req.Amount, source), target)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, newQuoteResponse(quote))
+}
+
+func (h *Handler) FXTransfer(w http.ResponseWriter, r *http.Request) {
+	var req FXTransferReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	senderID, _ := uuid.Parse(req.SenderID)
+	receiverID, _ := uuid.Parse(req.ReceiverID)
+	quoteID, _ := uuid.Parse(req.QuoteID)
+
+	// If-Match applies to the sender's wallet
+	r, ok := withIfMatch(w, r, senderID)
+	if !ok {
+		return
+	}
+
+	tx, err := h.svc.TransferWithQuote(r.Context(), senderID, receiverID, quoteID)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, newTransactionResponse(tx))
+}
+
+func (h *Handler) CreatePendingTransfer(w http.ResponseWriter, r *http.Request) {
+	var req PendingTransferReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	currency, err := parseCurrency(req.Currency)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	senderID, _ := uuid.Parse(req.SenderID)
+	receiverID, _ := uuid.Parse(req.ReceiverID)
+	expiresIn := time.Duration(req.ExpiresInSeconds) * time.Second
+
+	// If-Match applies to the sender's wallet, where the funds are held
+	r, ok := withIfMatch(w, r, senderID)
+	if !ok {
+		return
+	}
+
+	pending, err := h.svc.AuthorizeTransfer(r.Context(), senderID, receiverID, domain.NewMoney(req.Amount, currency), expiresIn)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, pending)
+}
+
+func (h *Handler) GetPendingTransfer(w http.ResponseWriter, r *http.Request) {
+	id, ok := pendingIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	pending, err := h.svc.GetPendingTransfer(r.Context(), id)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, pending)
+}
+
+func (h *Handler) CapturePendingTransfer(w http.ResponseWriter, r *http.Request) {
+	id, ok := pendingIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req CaptureReq
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// If-Match applies to the sender's wallet, where the funds are held
+	r, ok = withIfMatchPayer(w, r)
+	if !ok {
+		return
+	}
+
+	pending, tx, err := h.svc.CapturePendingTransfer(r.Context(), id, req.Amount)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, CaptureResponse{PendingTransfer: pending, Transaction: newTransactionResponse(tx)})
+}
+
+func (h *Handler) VoidPendingTransfer(w http.ResponseWriter, r *http.Request) {
+	id, ok := pendingIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// If-Match applies to the sender's wallet, where the funds are held
+	r, ok = withIfMatchPayer(w, r)
+	if !ok {
+		return
+	}
+
+	pending, err := h.svc.VoidPendingTransfer(r.Context(), id)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, pending)
+}
+
+func pendingIDFromPath(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid pending transfer ID format")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (h *Handler) RefundTransfer(w http.ResponseWriter, r *http.Request) {
+	transactionID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid transaction ID format")
+		return
+	}
+
+	var req RefundReq
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// If-Match applies to the original receiver's wallet, which pays the refund
+	r, ok := withIfMatchPayer(w, r)
+	if !ok {
+		return
+	}
+
+	refund, err := h.svc.RefundTransfer(r.Context(), transactionID, req.Amount)
+	if err != nil {
+		respondServiceError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, newTransactionResponse(refund))
 }
@@ -2,14 +2,64 @@ package handler
 
 import (
 	"net/http"
+
+	"digital-wallet/internal/domain"
 )
 
-func NewRouter(h *Handler) http.Handler {
+// NewRouter returns the API routes wrapped in middleware, the first of which
+// runs first. Requests authenticated by an API key need the scope of the
+// route. The /admin routes take API keys with the admin scope; those for
+// staff also take bearer tokens with one of the route's roles.
+func NewRouter(h *Handler, middleware ...func(http.Handler) http.Handler) http.Handler {
 	mux := http.NewServeMux()
+	read := func(pattern string, fn http.HandlerFunc) {
+		mux.Handle(pattern, requireScope(domain.ScopeWalletsRead, fn))
+	}
+	write := func(pattern string, fn http.HandlerFunc) {
+		mux.Handle(pattern, requireScope(domain.ScopeTransfersWrite, fn))
+	}
+	admin := func(pattern string, fn http.HandlerFunc) {
+		mux.Handle(pattern, requireAPIKey(domain.ScopeAdmin, fn))
+	}
+	staff := func(pattern string, roles []domain.AdminRole, fn http.HandlerFunc) {
+		mux.Handle(pattern, requireRole(roles, fn))
+	}
+
+	write("POST /wallets", h.CreateWallet)
+	read("GET /wallets/{id}", h.GetBalance)
+	read("GET /wallets/{id}/transactions", h.GetTransactions)
+	read("GET /wallets/{id}/reconciliation", h.GetReconciliation)
+	write("POST /wallets/{id}/deposits", h.Deposit)
+	write("POST /wallets/{id}/withdrawals", h.Withdraw)
+	write("PUT /wallets/{id}/stripes", h.SetWalletStripes)
+	write("POST /wallets/{id}/close", h.CloseWallet)
+	write("POST /transfers", h.Transfer)
+	write("POST /transfers/fx", h.FXTransfer)
+	write("POST /transfers/batch", h.BatchTransfer)
+	write("POST /transfers/{id}/refunds", h.RefundTransfer)
+	write("POST /quotes", h.CreateQuote)
+	write("POST /pending-transfers", h.CreatePendingTransfer)
+	read("GET /pending-transfers/{id}", h.GetPendingTransfer)
+	write("POST /pending-transfers/{id}/capture", h.CapturePendingTransfer)
+	write("POST /pending-transfers/{id}/void", h.VoidPendingTransfer)
+	read("GET /transactions/{id}/entries", h.GetTransactionEntries)
+
+	admin("POST /admin/api-keys", h.IssueAPIKey)
+	admin("GET /admin/api-keys", h.ListAPIKeys)
+	admin("POST /admin/api-keys/{id}/rotate", h.RotateAPIKey)
+	admin("DELETE /admin/api-keys/{id}", h.RevokeAPIKey)
 
-	mux.HandleFunc("POST /wallets", h.CreateWallet)
-	mux.HandleFunc("GET /wallets/{id}", h.GetBalance)
-	mux.HandleFunc("POST /transfers", h.Transfer)
+	staff("GET /admin/users/{id}/wallets", anyStaffRole, h.AdminListWallets)
+	staff("GET /admin/wallets/{id}/ledger", anyStaffRole, h.AdminWalletLedger)
+	staff("POST /admin/wallets/{id}/freeze", operatorRole, h.FreezeWallet)
+	staff("POST /admin/wallets/{id}/unfreeze", operatorRole, h.UnfreezeWallet)
+	staff("POST /admin/wallets/{id}/close", operatorRole, h.AdminCloseWallet)
+	staff("POST /admin/wallets/{id}/adjustments", financeRole, h.PostAdjustment)
+	staff("GET /admin/audit-log", anyStaffRole, h.AdminAuditLog)
 
-	return mux
+	var handler http.Handler = mux
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
 }
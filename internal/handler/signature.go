@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"digital-wallet/internal/domain"
+)
+
+// Headers of a signed request.
+const (
+	SignatureHeader          = "X-Signature"           // "v1=" and the hex HMAC-SHA256, see SignRequest
+	SignatureTimestampHeader = "X-Signature-Timestamp" // Unix seconds
+	SignatureNonceHeader     = "X-Signature-Nonce"     // Unique per request
+)
+
+// DefaultSignatureMaxSkew is the clock skew tolerated when SignatureConfig
+// sets none.
+const DefaultSignatureMaxSkew = 5 * time.Minute
+
+const (
+	signatureVersion = "v1="
+	minNonceLen      = 16
+	maxNonceLen      = 128
+	maxSignedBody    = 1 << 20 // Bytes of body read to verify a signature
+)
+
+// SignatureConfig configures request signing.
+type SignatureConfig struct {
+	MaxSkew time.Duration // How far the timestamp may be from the server's clock
+}
+
+// SignatureVerifier checks the HMAC signature of mutating requests, so that
+// a request cannot be altered or replayed on its way to the server. Requests
+// are signed with the signing secret of the API key they carry, see
+// domain.APIKey.
+type SignatureVerifier struct {
+	cfg    SignatureConfig
+	nonces domain.NonceStore
+}
+
+// NewSignatureVerifier returns a SignatureVerifier that records nonces in
+// nonces, filling in defaults for unset fields of cfg. Its middleware must
+// run after APIKeyAuthenticator's.
+func NewSignatureVerifier(cfg SignatureConfig, nonces domain.NonceStore) *SignatureVerifier {
+	if cfg.MaxSkew <= 0 {
+		cfg.MaxSkew = DefaultSignatureMaxSkew
+	}
+	return &SignatureVerifier{cfg: cfg, nonces: nonces}
+}
+
+// SignRequest returns the X-Signature value of a request: the HMAC-SHA256,
+// keyed with secret, of the method, the path with its query string, the
+// timestamp, the nonce and the hex SHA-256 of the body, joined by newlines.
+func SignRequest(secret []byte, method, path string, timestamp int64, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s\n%s\n%d\n%s\n%s", method, path, timestamp, nonce, hex.EncodeToString(bodyHash[:]))
+	return signatureVersion + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Middleware rejects requests that change state with 401 unless they carry
+// an API key and are signed with its secret, fresh and not replayed. Reads,
+// and requests made for an end user authenticated by a JWT, are not signed:
+// clients holding user tokens cannot keep a signing secret.
+func (v *SignatureVerifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !mutating(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key := apiKeyFrom(r.Context())
+		if key == nil {
+			if _, ok := domain.CallerFromContext(r.Context()); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			respondProblem(w, http.StatusUnauthorized, CodeInvalidSignature,
+				fmt.Sprintf("requests that change state need an %s and its signature", APIKeyHeader))
+			return
+		}
+		if key.SigningSecret == "" {
+			respondProblem(w, http.StatusUnauthorized, CodeInvalidSignature, "API key has no signing secret, rotate it to get one")
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSignedBody))
+		if err != nil {
+			respondError(w, http.StatusRequestEntityTooLarge, "request body too large to verify its signature")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		nonce, err := v.verify(r, []byte(key.SigningSecret), body, time.Now())
+		if err != nil {
+			respondProblem(w, http.StatusUnauthorized, CodeInvalidSignature, err.Error())
+			return
+		}
+		// Claimed only once the signature holds, so forged requests cannot
+		// use up nonces. A nonce outlives the timestamps it is valid with.
+		fresh, err := v.nonces.Claim(r.Context(), nonce, 2*v.cfg.MaxSkew)
+		if err != nil {
+			respondServiceError(w, err)
+			return
+		}
+		if !fresh {
+			respondProblem(w, http.StatusUnauthorized, CodeInvalidSignature, "nonce has already been used")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// verify checks the signature headers of r against secret and body and
+// returns the request's nonce.
+func (v *SignatureVerifier) verify(r *http.Request, secret, body []byte, now time.Time) (string, error) {
+	signature := r.Header.Get(SignatureHeader)
+	nonce := r.Header.Get(SignatureNonceHeader)
+	if signature == "" || nonce == "" || r.Header.Get(SignatureTimestampHeader) == "" {
+		return "", fmt.Errorf("missing %s, %s or %s header", SignatureHeader, SignatureTimestampHeader, SignatureNonceHeader)
+	}
+	if len(nonce) < minNonceLen || len(nonce) > maxNonceLen {
+		return "", fmt.Errorf("nonce must be %d to %d characters", minNonceLen, maxNonceLen)
+	}
+	timestamp, err := strconv.ParseInt(r.Header.Get(SignatureTimestampHeader), 10, 64)
+	if err != nil {
+		return "", errors.New("timestamp must be Unix seconds")
+	}
+	if skew := now.Sub(time.Unix(timestamp, 0)); skew > v.cfg.MaxSkew || skew < -v.cfg.MaxSkew {
+		return "", errors.New("timestamp is outside the allowed clock skew")
+	}
+	if !strings.HasPrefix(signature, signatureVersion) {
+		return "", errors.New("unsupported signature version")
+	}
+
+	want := SignRequest(secret, r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+	if !hmac.Equal([]byte(signature), []byte(want)) {
+		return "", errors.New("signature does not match")
+	}
+	return nonce, nil
+}
+
+func mutating(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
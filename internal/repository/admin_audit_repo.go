@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+
+	"digital-wallet/internal/domain"
+	"gorm.io/gorm"
+)
+
+type adminAuditRepository struct {
+	db *gorm.DB
+}
+
+func NewAdminAuditRepository(db *gorm.DB) domain.AdminAuditRepository {
+	return &adminAuditRepository{db: db}
+}
+
+func (r *adminAuditRepository) Append(ctx context.Context, entry *domain.AdminAuditEntry) error {
+	return dbFor(ctx, r.db).Create(entry).Error
+}
+
+func (r *adminAuditRepository) List(ctx context.Context, filter domain.AdminAuditFilter) ([]domain.AdminAuditEntry, error) {
+	query := dbFor(ctx, r.db).Order("id DESC").Limit(filter.Limit)
+	if filter.WalletID != nil {
+		query = query.Where("wallet_id = ?", *filter.WalletID)
+	}
+	var entries []domain.AdminAuditEntry
+	err := query.Find(&entries).Error
+	return entries, err
+}
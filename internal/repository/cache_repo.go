@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"digital-wallet/internal/domain"
@@ -11,6 +12,30 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+const walletCacheTTL = 10 * time.Minute
+
+// setWalletScript stores a wallet (ARGV[1]) unless its version (ARGV[2]) is
+// below the minimum version key.
+var setWalletScript = redis.NewScript(`
+local min = tonumber(redis.call('GET', KEYS[2]) or '0')
+if tonumber(ARGV[2]) < min then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'EX', ARGV[3])
+return 1
+`)
+
+// invalidateWalletScript raises the minimum version key to ARGV[1] and drops
+// the cached wallet.
+var invalidateWalletScript = redis.NewScript(`
+local min = tonumber(redis.call('GET', KEYS[2]) or '0')
+if tonumber(ARGV[1]) > min then
+	redis.call('SET', KEYS[2], ARGV[1], 'EX', ARGV[2])
+end
+redis.call('DEL', KEYS[1])
+return 1
+`)
+
 type cacheRepository struct {
 	client *redis.Client
 }
@@ -19,33 +44,49 @@ func NewCacheRepository(client *redis.Client) domain.CacheRepository {
 	return &cacheRepository{client: client}
 }
 
-func (r *cacheRepository) GetWallet(ctx context.Context, walletID uuid.UUID) (*domain.Wallet, error) {
+func walletCacheKeys(walletID uuid.UUID) []string {
 	key := fmt.Sprintf("wallet:%s", walletID)
-	val, err := r.client.Get(ctx, key).Result()
-	if err == redis.Nil {
-		return nil, nil // Cache miss
-	}
+	return []string{key, key + ":min_version"}
+}
+
+func (r *cacheRepository) GetWallet(ctx context.Context, walletID uuid.UUID) (*domain.Wallet, error) {
+	vals, err := r.client.MGet(ctx, walletCacheKeys(walletID)...).Result()
 	if err != nil {
 		return nil, err
 	}
+	val, ok := vals[0].(string)
+	if !ok {
+		return nil, nil // Cache miss
+	}
 
 	var wallet domain.Wallet
 	if err := json.Unmarshal([]byte(val), &wallet); err != nil {
 		return nil, err
 	}
+
+	// Reject copies older than the last invalidation
+	if floor, ok := vals[1].(string); ok {
+		minVersion, err := strconv.ParseInt(floor, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if wallet.Version < minVersion {
+			return nil, nil
+		}
+	}
 	return &wallet, nil
 }
 
 func (r *cacheRepository) SetWallet(ctx context.Context, wallet *domain.Wallet) error {
-	key := fmt.Sprintf("wallet:%s", wallet.ID)
 	data, err := json.Marshal(wallet)
 	if err != nil {
 		return err
 	}
-	return r.client.Set(ctx, key, data, 10*time.Minute).Err()
+	ttl := int(walletCacheTTL / time.Second)
+	return setWalletScript.Run(ctx, r.client, walletCacheKeys(wallet.ID), data, wallet.Version, ttl).Err()
 }
 
-func (r *cacheRepository) InvalidateWallet(ctx context.Context, walletID uuid.UUID) error {
-	key := fmt.Sprintf("wallet:%s", walletID)
-	return r.client.Del(ctx, key).Err()
+func (r *cacheRepository) InvalidateWallet(ctx context.Context, walletID uuid.UUID, minVersion int64) error {
+	ttl := int(walletCacheTTL / time.Second)
+	return invalidateWalletScript.Run(ctx, r.client, walletCacheKeys(walletID), minVersion, ttl).Err()
 }
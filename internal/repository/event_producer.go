@@ -21,5 +21,21 @@ func (p *eventProducer) PublishTransferEvent(ctx context.Context, event domain.T
 	if err != nil {
 		return err
 	}
-	return p.mq.Publish(ctx, body)
+	return p.mq.Publish(ctx, domain.EventTypeTransfer, body)
+}
+
+func (p *eventProducer) PublishRefundEvent(ctx context.Context, event domain.RefundEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.mq.Publish(ctx, domain.EventTypeRefund, body)
+}
+
+func (p *eventProducer) PublishWalletStatusEvent(ctx context.Context, event domain.WalletStatusEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.mq.Publish(ctx, domain.EventTypeWalletStatus, body)
 }
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) domain.IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+func (r *idempotencyRepository) Get(ctx context.Context, scope, key string) (*domain.IdempotencyKey, error) {
+	var records []domain.IdempotencyKey
+	err := dbFor(ctx, r.db).
+		Where("scope = ? AND key = ? AND expires_at > ?", scope, key, time.Now()).
+		Limit(1).
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return &records[0], nil
+}
+
+func (r *idempotencyRepository) Create(ctx context.Context, record *domain.IdempotencyKey) error {
+	conn := dbFor(ctx, r.db)
+	// An expired record may still occupy the key until it is purged, so it is
+	// overwritten in place. A live record leaves the row untouched and the
+	// insert affects nothing. A concurrent insert of the same key blocks on
+	// the primary key until the other transaction finishes.
+	result := conn.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "scope"}, {Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"request_hash", "transaction_id", "response_status", "response_body", "created_at", "expires_at",
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "idempotency_keys.expires_at <= ?", Vars: []interface{}{time.Now()}},
+		}},
+	}).Create(record)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrIdempotencyKeyInUse
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := dbFor(ctx, r.db).Where("expires_at <= ?", now).Delete(&domain.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ledgerRepository struct {
+	db *gorm.DB
+}
+
+func NewLedgerRepository(db *gorm.DB) domain.LedgerRepository {
+	return &ledgerRepository{db: db}
+}
+
+func (r *ledgerRepository) CreateEntries(ctx context.Context, entries []domain.LedgerEntry) error {
+	conn := dbFor(ctx, r.db)
+	return conn.Create(&entries).Error
+}
+
+func (r *ledgerRepository) ListByTransaction(ctx context.Context, transactionID uuid.UUID) ([]domain.LedgerEntry, error) {
+	var entries []domain.LedgerEntry
+	err := readerFor(ctx, r.db).
+		Where("transaction_id = ?", transactionID).
+		Order("amount").
+		Find(&entries).Error
+	return entries, err
+}
+
+func (r *ledgerRepository) SumByAccount(ctx context.Context, account string) (int64, error) {
+	var sum int64
+	err := dbFor(ctx, r.db).
+		Model(&domain.LedgerEntry{}).
+		Where("account = ?", account).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&sum).Error
+	return sum, err
+}
+
+func (r *ledgerRepository) ListByAccount(ctx context.Context, account string, limit int) ([]domain.LedgerEntry, error) {
+	var entries []domain.LedgerEntry
+	err := readerFor(ctx, r.db).
+		Where("account = ?", account).
+		Order("created_at DESC, id").
+		Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,47 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"digital-wallet/internal/domain"
+)
+
+type adminAuditRepository struct {
+	store *Store
+}
+
+func NewAdminAuditRepository(store *Store) domain.AdminAuditRepository {
+	return &adminAuditRepository{store: store}
+}
+
+func (r *adminAuditRepository) Append(ctx context.Context, entry *domain.AdminAuditEntry) error {
+	entry.CreatedAt = time.Now()
+	return r.store.within(ctx, func(t *storeTx) error {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		// Like a sequence, the counter is not rolled back.
+		r.store.auditSeq++
+		entry.ID = r.store.auditSeq
+		put(t, r.store.auditLog, entry.ID, *entry)
+		return nil
+	})
+}
+
+func (r *adminAuditRepository) List(ctx context.Context, filter domain.AdminAuditFilter) ([]domain.AdminAuditEntry, error) {
+	r.store.mu.Lock()
+	var entries []domain.AdminAuditEntry
+	for _, entry := range r.store.auditLog {
+		if filter.WalletID == nil || (entry.WalletID != nil && *entry.WalletID == *filter.WalletID) {
+			entries = append(entries, entry)
+		}
+	}
+	r.store.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID > entries[j].ID })
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[:filter.Limit]
+	}
+	return entries, nil
+}
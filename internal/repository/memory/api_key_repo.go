@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+type apiKeyRepository struct {
+	store *Store
+}
+
+func NewAPIKeyRepository(store *Store) domain.APIKeyRepository {
+	return &apiKeyRepository{store: store}
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	if key.ID == uuid.Nil {
+		key.ID = uuid.New()
+	}
+	key.CreatedAt = time.Now()
+
+	return r.store.within(ctx, func(t *storeTx) error {
+		if err := r.store.lock(ctx, t, rowKey("api_keys", key.ID)); err != nil {
+			return err
+		}
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		for _, k := range r.store.apiKeys {
+			if k.KeyHash == key.KeyHash {
+				return fmt.Errorf("memory: API key hash already exists")
+			}
+		}
+		stored := *key
+		stored.Scopes = append([]domain.APIScope(nil), key.Scopes...)
+		put(t, r.store.apiKeys, key.ID, stored)
+		return nil
+	})
+}
+
+func (r *apiKeyRepository) GetByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, k := range r.store.apiKeys {
+		if k.KeyHash == keyHash {
+			return &k, nil
+		}
+	}
+	return nil, domain.ErrAPIKeyNotFound
+}
+
+func (r *apiKeyRepository) GetByIDWithLock(ctx context.Context, id uuid.UUID) (*domain.APIKey, error) {
+	var key domain.APIKey
+	err := r.store.within(ctx, func(t *storeTx) error {
+		if err := r.store.lock(ctx, t, rowKey("api_keys", id)); err != nil {
+			return err
+		}
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		var ok bool
+		if key, ok = r.store.apiKeys[id]; !ok {
+			return domain.ErrAPIKeyNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) List(ctx context.Context) ([]domain.APIKey, error) {
+	r.store.mu.Lock()
+	keys := make([]domain.APIKey, 0, len(r.store.apiKeys))
+	for _, k := range r.store.apiKeys {
+		keys = append(keys, k)
+	}
+	r.store.mu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+func (r *apiKeyRepository) Update(ctx context.Context, key *domain.APIKey) error {
+	return r.store.within(ctx, func(t *storeTx) error {
+		if err := r.store.lock(ctx, t, rowKey("api_keys", key.ID)); err != nil {
+			return err
+		}
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		stored, ok := r.store.apiKeys[key.ID]
+		if !ok {
+			return nil
+		}
+		stored.ExpiresAt = key.ExpiresAt
+		stored.RevokedAt = key.RevokedAt
+		put(t, r.store.apiKeys, key.ID, stored)
+		return nil
+	})
+}
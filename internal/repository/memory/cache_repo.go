@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+// cacheRepository caches wallets in a map with the same version rules as the
+// Redis cache. Entries do not expire.
+type cacheRepository struct {
+	mu          sync.Mutex
+	wallets     map[uuid.UUID]domain.Wallet
+	minVersions map[uuid.UUID]int64
+}
+
+func NewCacheRepository() domain.CacheRepository {
+	return &cacheRepository{
+		wallets:     make(map[uuid.UUID]domain.Wallet),
+		minVersions: make(map[uuid.UUID]int64),
+	}
+}
+
+func (r *cacheRepository) GetWallet(ctx context.Context, walletID uuid.UUID) (*domain.Wallet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wallet, ok := r.wallets[walletID]
+	if !ok || wallet.Version < r.minVersions[walletID] {
+		return nil, nil
+	}
+	return &wallet, nil
+}
+
+func (r *cacheRepository) SetWallet(ctx context.Context, wallet *domain.Wallet) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if wallet.Version < r.minVersions[wallet.ID] {
+		return nil
+	}
+	r.wallets[wallet.ID] = *wallet
+	return nil
+}
+
+func (r *cacheRepository) InvalidateWallet(ctx context.Context, walletID uuid.UUID, minVersion int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if minVersion > r.minVersions[walletID] {
+		r.minVersions[walletID] = minVersion
+	}
+	delete(r.wallets, walletID)
+	return nil
+}
@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"digital-wallet/internal/domain"
+)
+
+// EventProducer records published events instead of sending them to RabbitMQ.
+type EventProducer struct {
+	mu       sync.Mutex
+	transfer []domain.TransferEvent
+	refund   []domain.RefundEvent
+	status   []domain.WalletStatusEvent
+}
+
+func NewEventProducer() *EventProducer {
+	return &EventProducer{}
+}
+
+func (p *EventProducer) PublishTransferEvent(ctx context.Context, event domain.TransferEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.transfer = append(p.transfer, event)
+	return nil
+}
+
+func (p *EventProducer) PublishRefundEvent(ctx context.Context, event domain.RefundEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refund = append(p.refund, event)
+	return nil
+}
+
+func (p *EventProducer) PublishWalletStatusEvent(ctx context.Context, event domain.WalletStatusEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status = append(p.status, event)
+	return nil
+}
+
+// TransferEvents returns the transfer events published so far, oldest first.
+func (p *EventProducer) TransferEvents() []domain.TransferEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]domain.TransferEvent(nil), p.transfer...)
+}
+
+// RefundEvents returns the refund events published so far, oldest first.
+func (p *EventProducer) RefundEvents() []domain.RefundEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]domain.RefundEvent(nil), p.refund...)
+}
+
+// WalletStatusEvents returns the wallet status events published so far,
+// oldest first.
+func (p *EventProducer) WalletStatusEvents() []domain.WalletStatusEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]domain.WalletStatusEvent(nil), p.status...)
+}
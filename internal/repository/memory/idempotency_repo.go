@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"digital-wallet/internal/domain"
+)
+
+type idempotencyRepository struct {
+	store *Store
+}
+
+func NewIdempotencyRepository(store *Store) domain.IdempotencyRepository {
+	return &idempotencyRepository{store: store}
+}
+
+func (r *idempotencyRepository) Get(ctx context.Context, scope, key string) (*domain.IdempotencyKey, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	record, ok := r.store.idempotency[idempotencyKey{scope, key}]
+	if !ok || !record.ExpiresAt.After(time.Now()) {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+func (r *idempotencyRepository) Create(ctx context.Context, record *domain.IdempotencyKey) error {
+	record.CreatedAt = time.Now()
+	id := idempotencyKey{record.Scope, record.Key}
+	return r.store.within(ctx, func(t *storeTx) error {
+		// A concurrent insert of the same key waits until the other
+		// transaction finishes, like it does on the primary key in Postgres.
+		if err := r.store.lock(ctx, t, rowKey("idempotency_keys", id)); err != nil {
+			return err
+		}
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		// An expired record is overwritten in place.
+		if existing, ok := r.store.idempotency[id]; ok && existing.ExpiresAt.After(time.Now()) {
+			return domain.ErrIdempotencyKeyInUse
+		}
+		put(t, r.store.idempotency, id, *record)
+		return nil
+	})
+}
+
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var deleted int64
+	err := r.store.within(ctx, func(t *storeTx) error {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		for key, record := range r.store.idempotency {
+			if !record.ExpiresAt.After(now) {
+				remove(t, r.store.idempotency, key)
+				deleted++
+			}
+		}
+		return nil
+	})
+	return deleted, err
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+type ledgerRepository struct {
+	store *Store
+}
+
+func NewLedgerRepository(store *Store) domain.LedgerRepository {
+	return &ledgerRepository{store: store}
+}
+
+func (r *ledgerRepository) CreateEntries(ctx context.Context, entries []domain.LedgerEntry) error {
+	now := time.Now()
+	for i := range entries {
+		if entries[i].ID == uuid.Nil {
+			entries[i].ID = uuid.New()
+		}
+		entries[i].CreatedAt = now
+		if entries[i].Amount == 0 {
+			return fmt.Errorf("%w: zero ledger entry on %s", errCheckViolation, entries[i].Account)
+		}
+	}
+
+	return r.store.within(ctx, func(t *storeTx) error {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		for _, entry := range entries {
+			put(t, r.store.entries, entry.ID, entry)
+		}
+		return nil
+	})
+}
+
+func (r *ledgerRepository) ListByTransaction(ctx context.Context, transactionID uuid.UUID) ([]domain.LedgerEntry, error) {
+	r.store.mu.Lock()
+	var entries []domain.LedgerEntry
+	for _, entry := range r.store.entries {
+		if entry.TransactionID == transactionID {
+			entries = append(entries, entry)
+		}
+	}
+	r.store.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Amount < entries[j].Amount })
+	return entries, nil
+}
+
+func (r *ledgerRepository) SumByAccount(ctx context.Context, account string) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var sum int64
+	for _, entry := range r.store.entries {
+		if entry.Account == account {
+			sum += entry.Amount
+		}
+	}
+	return sum, nil
+}
+
+func (r *ledgerRepository) ListByAccount(ctx context.Context, account string, limit int) ([]domain.LedgerEntry, error) {
+	r.store.mu.Lock()
+	var entries []domain.LedgerEntry
+	for _, entry := range r.store.entries {
+		if entry.Account == account {
+			entries = append(entries, entry)
+		}
+	}
+	r.store.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].CreatedAt.After(entries[j].CreatedAt) })
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
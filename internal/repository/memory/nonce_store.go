@@ -0,0 +1,36 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"digital-wallet/internal/domain"
+)
+
+// nonceStore keeps claimed nonces in a map, dropping expired ones as it goes.
+type nonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time // Expiry by nonce
+}
+
+func NewNonceStore() domain.NonceStore {
+	return &nonceStore{nonces: make(map[string]time.Time)}
+}
+
+func (s *nonceStore) Claim(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for n, expires := range s.nonces {
+		if !now.Before(expires) {
+			delete(s.nonces, n)
+		}
+	}
+	if _, ok := s.nonces[nonce]; ok {
+		return false, nil
+	}
+	s.nonces[nonce] = now.Add(ttl)
+	return true, nil
+}
@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"digital-wallet/internal/domain"
+)
+
+type outboxRepository struct {
+	store *Store
+}
+
+func NewOutboxRepository(store *Store) domain.OutboxRepository {
+	return &outboxRepository{store: store}
+}
+
+func (r *outboxRepository) Enqueue(ctx context.Context, event *domain.OutboxEvent) error {
+	if event.NextAttemptAt.IsZero() {
+		event.NextAttemptAt = time.Now()
+	}
+	event.CreatedAt = time.Now()
+	return r.store.within(ctx, func(t *storeTx) error {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		// Like a sequence, the counter is not rolled back.
+		r.store.outboxSeq++
+		event.ID = r.store.outboxSeq
+		put(t, r.store.outbox, event.ID, *event)
+		return nil
+	})
+}
+
+func (r *outboxRepository) WithRelayLock(ctx context.Context, fn func(ctx context.Context) error) (bool, error) {
+	if !r.store.relayLock.TryLock() {
+		return false, nil
+	}
+	defer r.store.relayLock.Unlock()
+	return true, r.store.withTx(ctx, fn)
+}
+
+func (r *outboxRepository) ListPending(ctx context.Context, limit int) ([]domain.OutboxEvent, error) {
+	r.store.mu.Lock()
+	var events []domain.OutboxEvent
+	for _, event := range r.store.outbox {
+		if event.DispatchedAt == nil {
+			events = append(events, event)
+		}
+	}
+	r.store.mu.Unlock()
+
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+func (r *outboxRepository) MarkDispatched(ctx context.Context, id int64, at time.Time) error {
+	return r.update(ctx, id, func(e *domain.OutboxEvent) {
+		e.DispatchedAt = &at
+		e.Attempts++
+		e.LastError = ""
+	})
+}
+
+func (r *outboxRepository) MarkFailed(ctx context.Context, id int64, lastErr string, nextAttemptAt time.Time) error {
+	return r.update(ctx, id, func(e *domain.OutboxEvent) {
+		e.Attempts++
+		e.LastError = lastErr
+		e.NextAttemptAt = nextAttemptAt
+	})
+}
+
+func (r *outboxRepository) update(ctx context.Context, id int64, fn func(e *domain.OutboxEvent)) error {
+	return r.store.within(ctx, func(t *storeTx) error {
+		if err := r.store.lock(ctx, t, rowKey("outbox_events", id)); err != nil {
+			return err
+		}
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		event, ok := r.store.outbox[id]
+		if !ok {
+			return nil
+		}
+		fn(&event)
+		put(t, r.store.outbox, id, event)
+		return nil
+	})
+}
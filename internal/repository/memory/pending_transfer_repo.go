@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+type pendingTransferRepository struct {
+	store *Store
+}
+
+func NewPendingTransferRepository(store *Store) domain.PendingTransferRepository {
+	return &pendingTransferRepository{store: store}
+}
+
+func (r *pendingTransferRepository) Create(ctx context.Context, pending *domain.PendingTransfer) error {
+	if pending.ID == uuid.Nil {
+		pending.ID = uuid.New()
+	}
+	if pending.Amount <= 0 {
+		return fmt.Errorf("%w: pending transfer amount %d", errCheckViolation, pending.Amount)
+	}
+	now := time.Now()
+	pending.CreatedAt, pending.UpdatedAt = now, now
+
+	return r.store.within(ctx, func(t *storeTx) error {
+		if err := r.store.lock(ctx, t, rowKey("pending_transfers", pending.ID)); err != nil {
+			return err
+		}
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		if _, ok := r.store.pending[pending.ID]; ok {
+			return fmt.Errorf("memory: pending transfer %s already exists", pending.ID)
+		}
+		put(t, r.store.pending, pending.ID, *pending)
+		return nil
+	})
+}
+
+func (r *pendingTransferRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.PendingTransfer, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	pending, ok := r.store.pending[id]
+	if !ok {
+		return nil, domain.ErrPendingNotFound
+	}
+	return &pending, nil
+}
+
+func (r *pendingTransferRepository) GetByIDWithLock(ctx context.Context, id uuid.UUID) (*domain.PendingTransfer, error) {
+	var pending *domain.PendingTransfer
+	err := r.store.within(ctx, func(t *storeTx) error {
+		if err := r.store.lock(ctx, t, rowKey("pending_transfers", id)); err != nil {
+			return err
+		}
+		var err error
+		pending, err = r.GetByID(ctx, id)
+		return err
+	})
+	return pending, err
+}
+
+func (r *pendingTransferRepository) Update(ctx context.Context, pending *domain.PendingTransfer) error {
+	pending.UpdatedAt = time.Now()
+	return r.store.within(ctx, func(t *storeTx) error {
+		if err := r.store.lock(ctx, t, rowKey("pending_transfers", pending.ID)); err != nil {
+			return err
+		}
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		stored, ok := r.store.pending[pending.ID]
+		if !ok {
+			return nil
+		}
+		stored.CapturedAmount = pending.CapturedAmount
+		stored.Status = pending.Status
+		stored.TransactionID = pending.TransactionID
+		stored.UpdatedAt = pending.UpdatedAt
+		put(t, r.store.pending, pending.ID, stored)
+		return nil
+	})
+}
+
+func (r *pendingTransferRepository) ListExpired(ctx context.Context, now time.Time, limit int) ([]domain.PendingTransfer, error) {
+	r.store.mu.Lock()
+	var pending []domain.PendingTransfer
+	for _, p := range r.store.pending {
+		if p.Status == domain.PendingStatusPending && !p.ExpiresAt.After(now) {
+			pending = append(pending, p)
+		}
+	}
+	r.store.mu.Unlock()
+
+	sort.Slice(pending, func(i, j int) bool { return pending[i].ExpiresAt.Before(pending[j].ExpiresAt) })
+	if limit > 0 && len(pending) > limit {
+		pending = pending[:limit]
+	}
+	return pending, nil
+}
@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+type quoteRepository struct {
+	store *Store
+}
+
+func NewQuoteRepository(store *Store) domain.QuoteRepository {
+	return &quoteRepository{store: store}
+}
+
+func (r *quoteRepository) Create(ctx context.Context, quote *domain.FXQuote) error {
+	if quote.ID == uuid.Nil {
+		quote.ID = uuid.New()
+	}
+	quote.CreatedAt = time.Now()
+
+	return r.store.within(ctx, func(t *storeTx) error {
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		put(t, r.store.quotes, quote.ID, *quote)
+		return nil
+	})
+}
+
+func (r *quoteRepository) GetByIDWithLock(ctx context.Context, id uuid.UUID) (*domain.FXQuote, error) {
+	var quote domain.FXQuote
+	err := r.store.within(ctx, func(t *storeTx) error {
+		if err := r.store.lock(ctx, t, rowKey("fx_quotes", id)); err != nil {
+			return err
+		}
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		var ok bool
+		if quote, ok = r.store.quotes[id]; !ok {
+			return domain.ErrQuoteNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+func (r *quoteRepository) MarkUsed(ctx context.Context, id, transactionID uuid.UUID) error {
+	return r.store.within(ctx, func(t *storeTx) error {
+		if err := r.store.lock(ctx, t, rowKey("fx_quotes", id)); err != nil {
+			return err
+		}
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		quote, ok := r.store.quotes[id]
+		if !ok {
+			return nil
+		}
+		quote.TransactionID = &transactionID
+		put(t, r.store.quotes, id, quote)
+		return nil
+	})
+}
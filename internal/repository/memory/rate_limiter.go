@@ -0,0 +1,42 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"digital-wallet/internal/domain"
+)
+
+type rateWindow struct {
+	count int
+	ends  time.Time
+}
+
+// rateLimiter counts requests in fixed windows like the Redis rate limiter,
+// for a single process.
+type rateLimiter struct {
+	mu      sync.Mutex
+	windows map[string]rateWindow
+}
+
+func NewRateLimiter() domain.RateLimiter {
+	return &rateLimiter{windows: make(map[string]rateWindow)}
+}
+
+func (l *rateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[key]
+	if !ok || !now.Before(w.ends) {
+		w = rateWindow{ends: now.Add(window)}
+	}
+	w.count++
+	l.windows[key] = w
+	if w.count <= limit {
+		return true, 0, nil
+	}
+	return false, w.ends.Sub(now), nil
+}
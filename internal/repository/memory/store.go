@@ -0,0 +1,197 @@
+// Package memory implements the domain repositories in process memory, so the
+// service and handlers can run and be tested without Postgres, Redis or
+// RabbitMQ.
+//
+// Transactions behave like Postgres row locks: locking a row, or writing it,
+// blocks other transactions that lock or write the same row until the holder
+// commits or rolls back, and a rollback undoes every write of the
+// transaction. Unlike Postgres, writes are visible to other readers before
+// they commit.
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+// errCheckViolation mirrors a Postgres check constraint failure.
+var errCheckViolation = errors.New("memory: check constraint violated")
+
+type stripeKey struct {
+	walletID uuid.UUID
+	stripe   int
+}
+
+type idempotencyKey struct {
+	scope string
+	key   string
+}
+
+// Store holds the tables shared by the repositories created from it.
+type Store struct {
+	mu    sync.Mutex
+	locks map[string]*rowLock
+
+	wallets      map[uuid.UUID]domain.Wallet
+	stripes      map[stripeKey]domain.WalletStripe
+	transactions map[uuid.UUID]domain.Transaction
+	entries      map[uuid.UUID]domain.LedgerEntry
+	pending      map[uuid.UUID]domain.PendingTransfer
+	quotes       map[uuid.UUID]domain.FXQuote
+	apiKeys      map[uuid.UUID]domain.APIKey
+	idempotency  map[idempotencyKey]domain.IdempotencyKey
+	outbox       map[int64]domain.OutboxEvent
+	outboxSeq    int64
+	auditLog     map[int64]domain.AdminAuditEntry
+	auditSeq     int64
+
+	relayLock sync.Mutex
+}
+
+func NewStore() *Store {
+	return &Store{
+		locks:        make(map[string]*rowLock),
+		wallets:      make(map[uuid.UUID]domain.Wallet),
+		stripes:      make(map[stripeKey]domain.WalletStripe),
+		transactions: make(map[uuid.UUID]domain.Transaction),
+		entries:      make(map[uuid.UUID]domain.LedgerEntry),
+		pending:      make(map[uuid.UUID]domain.PendingTransfer),
+		quotes:       make(map[uuid.UUID]domain.FXQuote),
+		apiKeys:      make(map[uuid.UUID]domain.APIKey),
+		idempotency:  make(map[idempotencyKey]domain.IdempotencyKey),
+		outbox:       make(map[int64]domain.OutboxEvent),
+		auditLog:     make(map[int64]domain.AdminAuditEntry),
+	}
+}
+
+type rowLock struct {
+	owner    *storeTx
+	released chan struct{}
+}
+
+// storeTx is a transaction: the row locks it holds and how to undo its writes.
+type storeTx struct {
+	locks []string
+	undo  []func()
+}
+
+type storeTxKey struct{}
+
+func txFromContext(ctx context.Context) *storeTx {
+	t, _ := ctx.Value(storeTxKey{}).(*storeTx)
+	return t
+}
+
+// transact runs fn in a new transaction, committing it if fn succeeds and
+// rolling it back if fn fails or panics.
+func (s *Store) transact(fn func(t *storeTx) error) (err error) {
+	t := &storeTx{}
+	committed := false
+	defer func() {
+		s.finish(t, !committed)
+	}()
+	err = fn(t)
+	committed = err == nil
+	return err
+}
+
+// within runs fn in the transaction carried by ctx, or in its own
+// transaction when ctx carries none.
+func (s *Store) within(ctx context.Context, fn func(t *storeTx) error) error {
+	if t := txFromContext(ctx); t != nil {
+		return fn(t)
+	}
+	return s.transact(fn)
+}
+
+// withTx runs fn in a transaction carried by the context passed to fn. If
+// ctx already carries a transaction, fn joins it.
+func (s *Store) withTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if txFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+	return s.transact(func(t *storeTx) error {
+		return fn(context.WithValue(ctx, storeTxKey{}, t))
+	})
+}
+
+func (s *Store) finish(t *storeTx, rollback bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rollback {
+		for i := len(t.undo) - 1; i >= 0; i-- {
+			t.undo[i]()
+		}
+	}
+	for _, key := range t.locks {
+		close(s.locks[key].released)
+		delete(s.locks, key)
+	}
+}
+
+// lock takes the row lock key for t, waiting while another transaction holds it.
+func (s *Store) lock(ctx context.Context, t *storeTx, key string) error {
+	for {
+		s.mu.Lock()
+		held, ok := s.locks[key]
+		if !ok {
+			s.locks[key] = &rowLock{owner: t, released: make(chan struct{})}
+			t.locks = append(t.locks, key)
+			s.mu.Unlock()
+			return nil
+		}
+		s.mu.Unlock()
+		if held.owner == t {
+			return nil
+		}
+
+		select {
+		case <-held.released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// put stores value under key in table as part of t. The caller holds s.mu.
+func put[K comparable, V any](t *storeTx, table map[K]V, key K, value V) {
+	old, existed := table[key]
+	table[key] = value
+	t.undo = append(t.undo, func() {
+		if existed {
+			table[key] = old
+		} else {
+			delete(table, key)
+		}
+	})
+}
+
+// remove deletes key from table as part of t. The caller holds s.mu.
+func remove[K comparable, V any](t *storeTx, table map[K]V, key K) {
+	old, existed := table[key]
+	if !existed {
+		return
+	}
+	delete(table, key)
+	t.undo = append(t.undo, func() {
+		table[key] = old
+	})
+}
+
+// rowKey names the row of table with primary key id for row locking.
+func rowKey(table string, id any) string {
+	return fmt.Sprintf("%s/%v", table, id)
+}
+
+func walletKey(id uuid.UUID) string {
+	return rowKey("wallets", id)
+}
+
+func stripeRowKey(id uuid.UUID, stripe int) string {
+	return rowKey("wallet_stripes", fmt.Sprintf("%s/%d", id, stripe))
+}
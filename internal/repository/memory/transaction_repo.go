@@ -0,0 +1,145 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+type transactionRepository struct {
+	store *Store
+}
+
+func NewTransactionRepository(store *Store) domain.TransactionRepository {
+	return &transactionRepository{store: store}
+}
+
+func (r *transactionRepository) Create(ctx context.Context, transaction *domain.Transaction) error {
+	if transaction.ID == uuid.Nil {
+		transaction.ID = uuid.New()
+	}
+	if transaction.Currency == "" {
+		transaction.Currency = domain.DefaultCurrency
+	}
+	transaction.CreatedAt = time.Now()
+
+	return r.store.within(ctx, func(t *storeTx) error {
+		if err := r.store.lock(ctx, t, rowKey("transactions", transaction.ID)); err != nil {
+			return err
+		}
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		if _, ok := r.store.transactions[transaction.ID]; ok {
+			return fmt.Errorf("memory: transaction %s already exists", transaction.ID)
+		}
+		put(t, r.store.transactions, transaction.ID, *transaction)
+		return nil
+	})
+}
+
+func (r *transactionRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Transaction, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	transaction, ok := r.store.transactions[id]
+	if !ok {
+		return nil, domain.ErrTransactionNotFound
+	}
+	return &transaction, nil
+}
+
+func (r *transactionRepository) GetByIDWithLock(ctx context.Context, id uuid.UUID) (*domain.Transaction, error) {
+	var transaction *domain.Transaction
+	err := r.store.within(ctx, func(t *storeTx) error {
+		if err := r.store.lock(ctx, t, rowKey("transactions", id)); err != nil {
+			return err
+		}
+		var err error
+		transaction, err = r.GetByID(ctx, id)
+		return err
+	})
+	return transaction, err
+}
+
+func (r *transactionRepository) AddRefundedAmount(ctx context.Context, id uuid.UUID, amount int64) error {
+	return r.store.within(ctx, func(t *storeTx) error {
+		if err := r.store.lock(ctx, t, rowKey("transactions", id)); err != nil {
+			return err
+		}
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		transaction, ok := r.store.transactions[id]
+		if !ok || transaction.RefundedAmount+amount > transaction.Amount {
+			return domain.ErrRefundExceedsAmount
+		}
+		transaction.RefundedAmount += amount
+		put(t, r.store.transactions, id, transaction)
+		return nil
+	})
+}
+
+func (r *transactionRepository) ListByWallet(ctx context.Context, filter domain.TransactionFilter) ([]domain.Transaction, error) {
+	r.store.mu.Lock()
+	var transactions []domain.Transaction
+	for _, t := range r.store.transactions {
+		if matchesFilter(t, filter) {
+			transactions = append(transactions, t)
+		}
+	}
+	r.store.mu.Unlock()
+
+	sort.Slice(transactions, func(i, j int) bool {
+		return newerThan(transactions[i].CreatedAt, transactions[i].ID, transactions[j].CreatedAt, transactions[j].ID)
+	})
+	if filter.Limit > 0 && len(transactions) > filter.Limit {
+		transactions = transactions[:filter.Limit]
+	}
+	return transactions, nil
+}
+
+func matchesFilter(t domain.Transaction, filter domain.TransactionFilter) bool {
+	sent := t.SenderID != nil && *t.SenderID == filter.WalletID
+	received := t.ReceiverID != nil && *t.ReceiverID == filter.WalletID
+	switch filter.Direction {
+	case domain.DirectionIncoming:
+		if !received {
+			return false
+		}
+	case domain.DirectionOutgoing:
+		if !sent {
+			return false
+		}
+	default:
+		if !sent && !received {
+			return false
+		}
+	}
+
+	switch {
+	case filter.Type != "" && t.Type != filter.Type:
+		return false
+	case filter.MinAmount != nil && t.Amount < *filter.MinAmount:
+		return false
+	case filter.MaxAmount != nil && t.Amount > *filter.MaxAmount:
+		return false
+	case filter.From != nil && t.CreatedAt.Before(*filter.From):
+		return false
+	case filter.To != nil && !t.CreatedAt.Before(*filter.To):
+		return false
+	case filter.After != nil && !newerThan(filter.After.CreatedAt, filter.After.ID, t.CreatedAt, t.ID):
+		return false
+	}
+	return true
+}
+
+// newerThan orders by (created_at, id) descending, the history order.
+// IDs compare like Postgres compares uuids, byte by byte.
+func newerThan(aTime time.Time, aID uuid.UUID, bTime time.Time, bID uuid.UUID) bool {
+	if !aTime.Equal(bTime) {
+		return aTime.After(bTime)
+	}
+	return aID.String() > bID.String()
+}
@@ -0,0 +1,332 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+type walletRepository struct {
+	store *Store
+}
+
+func NewWalletRepository(store *Store) domain.WalletRepository {
+	return &walletRepository{store: store}
+}
+
+func (r *walletRepository) Create(ctx context.Context, wallet *domain.Wallet) error {
+	if wallet.ID == uuid.Nil {
+		wallet.ID = uuid.New()
+	}
+	if wallet.Currency == "" {
+		wallet.Currency = domain.DefaultCurrency
+	}
+	if wallet.Status == "" {
+		wallet.Status = domain.WalletStatusActive
+	}
+	now := time.Now()
+	wallet.CreatedAt, wallet.UpdatedAt = now, now
+	if err := checkWallet(*wallet); err != nil {
+		return err
+	}
+
+	return r.store.within(ctx, func(t *storeTx) error {
+		if err := r.store.lock(ctx, t, walletKey(wallet.ID)); err != nil {
+			return err
+		}
+		r.store.mu.Lock()
+		defer r.store.mu.Unlock()
+		if _, ok := r.store.wallets[wallet.ID]; ok {
+			return fmt.Errorf("memory: wallet %s already exists", wallet.ID)
+		}
+		put(t, r.store.wallets, wallet.ID, *wallet)
+		return nil
+	})
+}
+
+func (r *walletRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Wallet, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	wallet, ok := r.store.wallets[id]
+	if !ok {
+		return nil, domain.ErrWalletNotFound
+	}
+	return &wallet, nil
+}
+
+func (r *walletRepository) GetByIDWithLock(ctx context.Context, id uuid.UUID) (*domain.Wallet, error) {
+	var wallet *domain.Wallet
+	err := r.store.within(ctx, func(t *storeTx) error {
+		if err := r.store.lock(ctx, t, walletKey(id)); err != nil {
+			return err
+		}
+		var err error
+		wallet, err = r.GetByID(ctx, id)
+		return err
+	})
+	return wallet, err
+}
+
+// update applies fn to a locked copy of the wallet and stores the result if
+// it passes the table's check constraints.
+func (r *walletRepository) update(ctx context.Context, t *storeTx, id uuid.UUID, fn func(w *domain.Wallet)) error {
+	if err := r.store.lock(ctx, t, walletKey(id)); err != nil {
+		return err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	wallet, ok := r.store.wallets[id]
+	if !ok {
+		return domain.ErrWalletNotFound
+	}
+	fn(&wallet)
+	wallet.UpdatedAt = time.Now()
+	if err := checkWallet(wallet); err != nil {
+		return err
+	}
+	put(t, r.store.wallets, id, wallet)
+	return nil
+}
+
+func (r *walletRepository) AdjustBalance(ctx context.Context, id uuid.UUID, delta int64) error {
+	return r.store.within(ctx, func(t *storeTx) error {
+		return r.update(ctx, t, id, func(w *domain.Wallet) {
+			w.Balance += delta
+			w.Version++
+		})
+	})
+}
+
+func (r *walletRepository) AdjustHeld(ctx context.Context, id uuid.UUID, delta int64) error {
+	return r.store.within(ctx, func(t *storeTx) error {
+		return r.update(ctx, t, id, func(w *domain.Wallet) {
+			w.Held += delta
+			w.Version++
+		})
+	})
+}
+
+func (r *walletRepository) TransferBalance(ctx context.Context, senderID, receiverID uuid.UUID, amount domain.Money) (sender, receiver *domain.Wallet, err error) {
+	err = r.store.within(ctx, func(t *storeTx) error {
+		// Lock in ID order, like the Postgres statement does.
+		ids := []uuid.UUID{senderID, receiverID}
+		sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+		for _, id := range ids {
+			if err := r.store.lock(ctx, t, walletKey(id)); err != nil {
+				return err
+			}
+		}
+
+		r.store.mu.Lock()
+		s, sok := r.store.wallets[senderID]
+		rc, rok := r.store.wallets[receiverID]
+		r.store.mu.Unlock()
+		if !sok || !rok {
+			return domain.ErrWalletNotFound
+		}
+		if s.Currency != amount.Currency || rc.Currency != amount.Currency {
+			return domain.ErrCurrencyMismatch
+		}
+		if s.AvailableBalance() < amount.Amount {
+			return domain.ErrInsufficientFunds
+		}
+		sender, receiver = &s, &rc
+
+		if err := r.update(ctx, t, senderID, func(w *domain.Wallet) {
+			w.Balance -= amount.Amount
+			w.Version++
+		}); err != nil {
+			return err
+		}
+		return r.update(ctx, t, receiverID, func(w *domain.Wallet) {
+			w.Balance += amount.Amount
+			w.Version++
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return sender, receiver, nil
+}
+
+func (r *walletRepository) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.store.withTx(ctx, fn)
+}
+
+func (r *walletRepository) SetStripes(ctx context.Context, id uuid.UUID, stripes int) error {
+	return r.store.within(ctx, func(t *storeTx) error {
+		for i := 0; i < stripes; i++ {
+			if err := r.store.lock(ctx, t, stripeRowKey(id, i)); err != nil {
+				return err
+			}
+			r.store.mu.Lock()
+			key := stripeKey{walletID: id, stripe: i}
+			if _, ok := r.store.stripes[key]; !ok {
+				put(t, r.store.stripes, key, domain.WalletStripe{WalletID: id, Stripe: i})
+			}
+			r.store.mu.Unlock()
+		}
+		return r.update(ctx, t, id, func(w *domain.Wallet) {
+			w.Stripes = stripes
+			w.Version++
+		})
+	})
+}
+
+func (r *walletRepository) CreditStripe(ctx context.Context, id uuid.UUID, stripe int, amount int64) error {
+	return r.store.within(ctx, func(t *storeTx) error {
+		return r.updateStripe(ctx, t, id, stripe, amount)
+	})
+}
+
+// updateStripe adds delta to a locked stripe.
+func (r *walletRepository) updateStripe(ctx context.Context, t *storeTx, id uuid.UUID, stripe int, delta int64) error {
+	if err := r.store.lock(ctx, t, stripeRowKey(id, stripe)); err != nil {
+		return err
+	}
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	key := stripeKey{walletID: id, stripe: stripe}
+	row, ok := r.store.stripes[key]
+	if !ok {
+		return domain.ErrWalletNotFound
+	}
+	row.Balance += delta
+	row.Version++
+	if row.Balance < 0 {
+		return fmt.Errorf("%w: stripe %d of wallet %s would go negative", errCheckViolation, stripe, id)
+	}
+	put(t, r.store.stripes, key, row)
+	return nil
+}
+
+func (r *walletRepository) BorrowFromStripes(ctx context.Context, id uuid.UUID, amount int64) (int64, error) {
+	var moved int64
+	err := r.store.within(ctx, func(t *storeTx) error {
+		// Stripes are locked in index order so concurrent borrowers cannot deadlock.
+		for _, stripe := range r.stripeIndexes(id) {
+			if moved == amount {
+				break
+			}
+			if err := r.store.lock(ctx, t, stripeRowKey(id, stripe)); err != nil {
+				return err
+			}
+			r.store.mu.Lock()
+			balance := r.store.stripes[stripeKey{walletID: id, stripe: stripe}].Balance
+			r.store.mu.Unlock()
+			if balance <= 0 {
+				continue
+			}
+			take := min(balance, amount-moved)
+			if err := r.updateStripe(ctx, t, id, stripe, -take); err != nil {
+				return err
+			}
+			moved += take
+		}
+
+		if moved > 0 {
+			return r.update(ctx, t, id, func(w *domain.Wallet) {
+				w.Balance += moved
+				w.Version++
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return moved, nil
+}
+
+func (r *walletRepository) CollectStripes(ctx context.Context, id uuid.UUID) (int64, error) {
+	var moved int64
+	err := r.store.within(ctx, func(t *storeTx) error {
+		for _, stripe := range r.stripeIndexes(id) {
+			if err := r.store.lock(ctx, t, stripeRowKey(id, stripe)); err != nil {
+				return err
+			}
+			r.store.mu.Lock()
+			balance := r.store.stripes[stripeKey{walletID: id, stripe: stripe}].Balance
+			r.store.mu.Unlock()
+			if balance <= 0 {
+				continue
+			}
+			if err := r.updateStripe(ctx, t, id, stripe, -balance); err != nil {
+				return err
+			}
+			moved += balance
+		}
+
+		if moved > 0 {
+			return r.update(ctx, t, id, func(w *domain.Wallet) {
+				w.Balance += moved
+				w.Version++
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return moved, nil
+}
+
+func (r *walletRepository) SumStripes(ctx context.Context, id uuid.UUID) (balance, version int64, err error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for key, stripe := range r.store.stripes {
+		if key.walletID == id {
+			balance += stripe.Balance
+			version += stripe.Version
+		}
+	}
+	return balance, version, nil
+}
+
+func (r *walletRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.Wallet, error) {
+	r.store.mu.Lock()
+	var wallets []domain.Wallet
+	for _, wallet := range r.store.wallets {
+		if wallet.UserID == userID {
+			wallets = append(wallets, wallet)
+		}
+	}
+	r.store.mu.Unlock()
+
+	sort.Slice(wallets, func(i, j int) bool { return wallets[i].CreatedAt.Before(wallets[j].CreatedAt) })
+	return wallets, nil
+}
+
+func (r *walletRepository) SetStatus(ctx context.Context, id uuid.UUID, status domain.WalletStatus) error {
+	return r.store.within(ctx, func(t *storeTx) error {
+		return r.update(ctx, t, id, func(w *domain.Wallet) {
+			w.Status = status
+			w.Version++
+		})
+	})
+}
+
+func (r *walletRepository) stripeIndexes(id uuid.UUID) []int {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var indexes []int
+	for key := range r.store.stripes {
+		if key.walletID == id {
+			indexes = append(indexes, key.stripe)
+		}
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// checkWallet enforces the check constraints of the wallets table.
+func checkWallet(w domain.Wallet) error {
+	if w.Balance < 0 || w.Held < 0 || w.Held > w.Balance {
+		return fmt.Errorf("%w: wallet %s balance %d held %d", errCheckViolation, w.ID, w.Balance, w.Held)
+	}
+	return nil
+}
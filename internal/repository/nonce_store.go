@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+type nonceStore struct {
+	client *redis.Client
+}
+
+// NewNonceStore returns a NonceStore in Redis, so a request replayed to
+// another server instance is rejected too.
+func NewNonceStore(client *redis.Client) domain.NonceStore {
+	return &nonceStore{client: client}
+}
+
+func (s *nonceStore) Claim(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, "nonce:"+nonce, 1, ttl).Result()
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"gorm.io/gorm"
+)
+
+// outboxRelayLockKey is the Postgres advisory lock held while relaying.
+const outboxRelayLockKey = 7_340_001
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) domain.OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+func (r *outboxRepository) Enqueue(ctx context.Context, event *domain.OutboxEvent) error {
+	conn := dbFor(ctx, r.db)
+	if event.NextAttemptAt.IsZero() {
+		event.NextAttemptAt = time.Now()
+	}
+	return conn.Create(event).Error
+}
+
+func (r *outboxRepository) WithRelayLock(ctx context.Context, fn func(ctx context.Context) error) (bool, error) {
+	acquired := false
+	err := withTx(ctx, r.db, func(ctx context.Context) error {
+		if err := dbFor(ctx, r.db).Raw("SELECT pg_try_advisory_xact_lock(?)", outboxRelayLockKey).Scan(&acquired).Error; err != nil {
+			return err
+		}
+		if !acquired {
+			return nil
+		}
+		return fn(ctx)
+	})
+	return acquired, err
+}
+
+func (r *outboxRepository) ListPending(ctx context.Context, limit int) ([]domain.OutboxEvent, error) {
+	conn := dbFor(ctx, r.db)
+	var events []domain.OutboxEvent
+	err := conn.
+		Where("dispatched_at IS NULL").
+		Order("id").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+func (r *outboxRepository) MarkDispatched(ctx context.Context, id int64, at time.Time) error {
+	conn := dbFor(ctx, r.db)
+	return conn.Model(&domain.OutboxEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"dispatched_at": at,
+		"attempts":      gorm.Expr("attempts + 1"),
+		"last_error":    "",
+	}).Error
+}
+
+func (r *outboxRepository) MarkFailed(ctx context.Context, id int64, lastErr string, nextAttemptAt time.Time) error {
+	conn := dbFor(ctx, r.db)
+	return conn.Model(&domain.OutboxEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      lastErr,
+		"next_attempt_at": nextAttemptAt,
+	}).Error
+}
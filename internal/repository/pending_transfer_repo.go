@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type pendingTransferRepository struct {
+	db *gorm.DB
+}
+
+func NewPendingTransferRepository(db *gorm.DB) domain.PendingTransferRepository {
+	return &pendingTransferRepository{db: db}
+}
+
+func (r *pendingTransferRepository) Create(ctx context.Context, pending *domain.PendingTransfer) error {
+	conn := dbFor(ctx, r.db)
+	return conn.Create(pending).Error
+}
+
+func (r *pendingTransferRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.PendingTransfer, error) {
+	var pending domain.PendingTransfer
+	err := dbFor(ctx, r.db).First(&pending, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrPendingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pending, nil
+}
+
+func (r *pendingTransferRepository) GetByIDWithLock(ctx context.Context, id uuid.UUID) (*domain.PendingTransfer, error) {
+	var pending domain.PendingTransfer
+	conn := dbFor(ctx, r.db)
+	err := conn.Clauses(clause.Locking{Strength: "UPDATE"}).First(&pending, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrPendingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pending, nil
+}
+
+func (r *pendingTransferRepository) Update(ctx context.Context, pending *domain.PendingTransfer) error {
+	conn := dbFor(ctx, r.db)
+	return conn.Model(pending).Select("captured_amount", "status", "transaction_id", "updated_at").Updates(pending).Error
+}
+
+func (r *pendingTransferRepository) ListExpired(ctx context.Context, now time.Time, limit int) ([]domain.PendingTransfer, error) {
+	var pending []domain.PendingTransfer
+	err := dbFor(ctx, r.db).
+		Where("status = ? AND expires_at <= ?", domain.PendingStatusPending, now).
+		Order("expires_at").
+		Limit(limit).
+		Find(&pending).Error
+	return pending, err
+}
@@ -0,0 +1,53 @@
+package pgxrepo
+
+import (
+	"context"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const adminAuditColumns = `id, actor, role, action, wallet_id, user_id, transaction_id, reason_code, note, created_at`
+
+var (
+	stmtAdminAuditInsert = statement("admin_audit_insert", `
+INSERT INTO admin_audit_entries (actor, role, action, wallet_id, user_id, transaction_id, reason_code, note, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`)
+	stmtAdminAuditList = statement("admin_audit_list", `
+SELECT `+adminAuditColumns+` FROM admin_audit_entries
+WHERE $1::uuid IS NULL OR wallet_id = $1 ORDER BY id DESC LIMIT $2`)
+)
+
+type adminAuditRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAdminAuditRepository(pool *pgxpool.Pool) domain.AdminAuditRepository {
+	return &adminAuditRepository{pool: pool}
+}
+
+func (r *adminAuditRepository) Append(ctx context.Context, entry *domain.AdminAuditEntry) error {
+	entry.CreatedAt = time.Now()
+	return writeReturning(ctx, r.pool, func(row pgx.Row) error {
+		return row.Scan(&entry.ID)
+	}, stmtAdminAuditInsert, entry.Actor, entry.Role, entry.Action, entry.WalletID, entry.UserID, entry.TransactionID,
+		entry.ReasonCode, entry.Note, entry.CreatedAt)
+}
+
+func (r *adminAuditRepository) List(ctx context.Context, filter domain.AdminAuditFilter) ([]domain.AdminAuditEntry, error) {
+	q, err := reader(ctx, r.pool)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.Query(ctx, stmtAdminAuditList, filter.WalletID, filter.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.AdminAuditEntry, error) {
+		var e domain.AdminAuditEntry
+		err := row.Scan(&e.ID, &e.Actor, &e.Role, &e.Action, &e.WalletID, &e.UserID, &e.TransactionID, &e.ReasonCode, &e.Note, &e.CreatedAt)
+		return e, err
+	})
+}
@@ -0,0 +1,91 @@
+package pgxrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const apiKeyColumns = `id, name, prefix, key_hash, signing_secret, scopes, owner_id, admin_role, rate_limit, expires_at, revoked_at, rotated_from, created_at`
+
+var (
+	stmtAPIKeyInsert = statement("api_key_insert", `
+INSERT INTO api_keys (`+apiKeyColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`)
+	stmtAPIKeyGetByHash = statement("api_key_get_by_hash", `
+SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1`)
+	stmtAPIKeyGetForUpdate = statement("api_key_get_for_update", `
+SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1 FOR UPDATE`)
+	stmtAPIKeyList = statement("api_key_list", `
+SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at DESC, id`)
+	stmtAPIKeyUpdate = statement("api_key_update", `
+UPDATE api_keys SET expires_at = $2, revoked_at = $3 WHERE id = $1`)
+)
+
+type apiKeyRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(pool *pgxpool.Pool) domain.APIKeyRepository {
+	return &apiKeyRepository{pool: pool}
+}
+
+func scanAPIKey(row pgx.Row) (domain.APIKey, error) {
+	var k domain.APIKey
+	err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.KeyHash, &k.SigningSecret, &k.Scopes, &k.OwnerID, &k.AdminRole, &k.RateLimit, &k.ExpiresAt, &k.RevokedAt, &k.RotatedFrom, &k.CreatedAt)
+	return k, err
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *domain.APIKey) error {
+	if key.ID == uuid.Nil {
+		key.ID = uuid.New()
+	}
+	key.CreatedAt = time.Now()
+	return write(ctx, r.pool, nil, stmtAPIKeyInsert,
+		key.ID, key.Name, key.Prefix, key.KeyHash, key.SigningSecret, key.Scopes, key.OwnerID, key.AdminRole, key.RateLimit, key.ExpiresAt, key.RevokedAt, key.RotatedFrom, key.CreatedAt)
+}
+
+func (r *apiKeyRepository) GetByHash(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	return r.get(ctx, stmtAPIKeyGetByHash, keyHash)
+}
+
+func (r *apiKeyRepository) GetByIDWithLock(ctx context.Context, id uuid.UUID) (*domain.APIKey, error) {
+	return r.get(ctx, stmtAPIKeyGetForUpdate, id)
+}
+
+func (r *apiKeyRepository) get(ctx context.Context, stmt string, arg any) (*domain.APIKey, error) {
+	q, err := reader(ctx, r.pool)
+	if err != nil {
+		return nil, err
+	}
+	key, err := scanAPIKey(q.QueryRow(ctx, stmt, arg))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) List(ctx context.Context) ([]domain.APIKey, error) {
+	q, err := reader(ctx, r.pool)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.Query(ctx, stmtAPIKeyList)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.APIKey, error) {
+		return scanAPIKey(row)
+	})
+}
+
+func (r *apiKeyRepository) Update(ctx context.Context, key *domain.APIKey) error {
+	return write(ctx, r.pool, nil, stmtAPIKeyUpdate, key.ID, key.ExpiresAt, key.RevokedAt)
+}
@@ -0,0 +1,70 @@
+package pgxrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	stmtIdempotencyGet = statement("idempotency_get", `
+SELECT scope, key, request_hash, transaction_id, response_status, response_body, created_at, expires_at
+FROM idempotency_keys WHERE scope = $1 AND key = $2 AND expires_at > $3`)
+	// An expired record may still occupy the key until it is purged, so it is
+	// overwritten in place. A live record leaves the row untouched and the
+	// insert affects nothing.
+	stmtIdempotencyInsert = statement("idempotency_insert", `
+INSERT INTO idempotency_keys (scope, key, request_hash, transaction_id, response_status, response_body, created_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (scope, key) DO UPDATE SET
+	request_hash = excluded.request_hash, transaction_id = excluded.transaction_id,
+	response_status = excluded.response_status, response_body = excluded.response_body,
+	created_at = excluded.created_at, expires_at = excluded.expires_at
+WHERE idempotency_keys.expires_at <= $7`)
+	stmtIdempotencyDeleteExpired = statement("idempotency_delete_expired", `
+DELETE FROM idempotency_keys WHERE expires_at <= $1`)
+)
+
+type idempotencyRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewIdempotencyRepository(pool *pgxpool.Pool) domain.IdempotencyRepository {
+	return &idempotencyRepository{pool: pool}
+}
+
+func (r *idempotencyRepository) Get(ctx context.Context, scope, key string) (*domain.IdempotencyKey, error) {
+	q, err := reader(ctx, r.pool)
+	if err != nil {
+		return nil, err
+	}
+	var k domain.IdempotencyKey
+	err = q.QueryRow(ctx, stmtIdempotencyGet, scope, key, time.Now()).Scan(
+		&k.Scope, &k.Key, &k.RequestHash, &k.TransactionID, &k.ResponseStatus, &k.ResponseBody, &k.CreatedAt, &k.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (r *idempotencyRepository) Create(ctx context.Context, record *domain.IdempotencyKey) error {
+	record.CreatedAt = time.Now()
+	return writeNow(ctx, r.pool, affects(domain.ErrIdempotencyKeyInUse), stmtIdempotencyInsert,
+		record.Scope, record.Key, record.RequestHash, record.TransactionID, record.ResponseStatus, record.ResponseBody, record.CreatedAt, record.ExpiresAt)
+}
+
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	q, err := reader(ctx, r.pool)
+	if err != nil {
+		return 0, err
+	}
+	tag, err := q.Exec(ctx, stmtIdempotencyDeleteExpired, now)
+	return tag.RowsAffected(), err
+}
@@ -0,0 +1,87 @@
+package pgxrepo
+
+import (
+	"context"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	stmtLedgerInsert = statement("ledger_insert", `
+INSERT INTO ledger_entries (id, transaction_id, account, amount, currency, created_at) VALUES ($1, $2, $3, $4, $5, $6)`)
+	stmtLedgerByTransaction = statement("ledger_by_transaction", `
+SELECT id, transaction_id, account, amount, currency, created_at FROM ledger_entries WHERE transaction_id = $1 ORDER BY amount`)
+	stmtLedgerSum = statement("ledger_sum", `
+SELECT COALESCE(SUM(amount), 0) FROM ledger_entries WHERE account = $1`)
+	stmtLedgerByAccount = statement("ledger_by_account", `
+SELECT id, transaction_id, account, amount, currency, created_at FROM ledger_entries WHERE account = $1 ORDER BY created_at DESC, id LIMIT $2`)
+)
+
+type ledgerRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewLedgerRepository(pool *pgxpool.Pool) domain.LedgerRepository {
+	return &ledgerRepository{pool: pool}
+}
+
+func (r *ledgerRepository) CreateEntries(ctx context.Context, entries []domain.LedgerEntry) error {
+	now := time.Now()
+	return withTx(ctx, r.pool, func(ctx context.Context) error {
+		for i := range entries {
+			e := &entries[i]
+			if e.ID == uuid.Nil {
+				e.ID = uuid.New()
+			}
+			e.CreatedAt = now
+			if err := write(ctx, r.pool, nil, stmtLedgerInsert, e.ID, e.TransactionID, e.Account, e.Amount, e.Currency, e.CreatedAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *ledgerRepository) ListByTransaction(ctx context.Context, transactionID uuid.UUID) ([]domain.LedgerEntry, error) {
+	q, err := reader(ctx, r.pool)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.Query(ctx, stmtLedgerByTransaction, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanLedgerEntry)
+}
+
+func (r *ledgerRepository) SumByAccount(ctx context.Context, account string) (int64, error) {
+	q, err := reader(ctx, r.pool)
+	if err != nil {
+		return 0, err
+	}
+	var sum int64
+	err = q.QueryRow(ctx, stmtLedgerSum, account).Scan(&sum)
+	return sum, err
+}
+
+func (r *ledgerRepository) ListByAccount(ctx context.Context, account string, limit int) ([]domain.LedgerEntry, error) {
+	q, err := reader(ctx, r.pool)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.Query(ctx, stmtLedgerByAccount, account, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, scanLedgerEntry)
+}
+
+func scanLedgerEntry(row pgx.CollectableRow) (domain.LedgerEntry, error) {
+	var e domain.LedgerEntry
+	err := row.Scan(&e.ID, &e.TransactionID, &e.Account, &e.Amount, &e.Currency, &e.CreatedAt)
+	return e, err
+}
@@ -0,0 +1,89 @@
+package pgxrepo
+
+import (
+	"context"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// outboxRelayLockKey is the advisory lock held while relaying, the same key
+// as the GORM repository's so relays on either backend exclude each other.
+const outboxRelayLockKey = 7_340_001
+
+var (
+	stmtOutboxInsert = statement("outbox_insert", `
+INSERT INTO outbox_events (event_type, sender_id, receiver_id, payload, attempts, next_attempt_at, created_at)
+VALUES ($1, $2, $3, $4, 0, $5, $6) RETURNING id`)
+	stmtOutboxRelayLock = statement("outbox_relay_lock", `
+SELECT pg_try_advisory_xact_lock($1)`)
+	stmtOutboxPending = statement("outbox_pending", `
+SELECT id, event_type, sender_id, receiver_id, payload, attempts, COALESCE(last_error, ''), next_attempt_at, dispatched_at, created_at
+FROM outbox_events WHERE dispatched_at IS NULL ORDER BY id LIMIT $1`)
+	stmtOutboxDispatched = statement("outbox_dispatched", `
+UPDATE outbox_events SET dispatched_at = $2, attempts = attempts + 1, last_error = '' WHERE id = $1`)
+	stmtOutboxFailed = statement("outbox_failed", `
+UPDATE outbox_events SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3 WHERE id = $1`)
+)
+
+type outboxRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewOutboxRepository(pool *pgxpool.Pool) domain.OutboxRepository {
+	return &outboxRepository{pool: pool}
+}
+
+func (r *outboxRepository) Enqueue(ctx context.Context, event *domain.OutboxEvent) error {
+	if event.NextAttemptAt.IsZero() {
+		event.NextAttemptAt = time.Now()
+	}
+	event.CreatedAt = time.Now()
+	return writeReturning(ctx, r.pool, func(row pgx.Row) error {
+		return row.Scan(&event.ID)
+	}, stmtOutboxInsert, event.EventType, event.SenderID, event.ReceiverID, event.Payload, event.NextAttemptAt, event.CreatedAt)
+}
+
+func (r *outboxRepository) WithRelayLock(ctx context.Context, fn func(ctx context.Context) error) (bool, error) {
+	acquired := false
+	err := withTx(ctx, r.pool, func(ctx context.Context) error {
+		q, err := reader(ctx, r.pool)
+		if err != nil {
+			return err
+		}
+		if err := q.QueryRow(ctx, stmtOutboxRelayLock, outboxRelayLockKey).Scan(&acquired); err != nil {
+			return err
+		}
+		if !acquired {
+			return nil
+		}
+		return fn(ctx)
+	})
+	return acquired, err
+}
+
+func (r *outboxRepository) ListPending(ctx context.Context, limit int) ([]domain.OutboxEvent, error) {
+	q, err := reader(ctx, r.pool)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.Query(ctx, stmtOutboxPending, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.OutboxEvent, error) {
+		var e domain.OutboxEvent
+		err := row.Scan(&e.ID, &e.EventType, &e.SenderID, &e.ReceiverID, &e.Payload, &e.Attempts, &e.LastError, &e.NextAttemptAt, &e.DispatchedAt, &e.CreatedAt)
+		return e, err
+	})
+}
+
+func (r *outboxRepository) MarkDispatched(ctx context.Context, id int64, at time.Time) error {
+	return write(ctx, r.pool, nil, stmtOutboxDispatched, id, at)
+}
+
+func (r *outboxRepository) MarkFailed(ctx context.Context, id int64, lastErr string, nextAttemptAt time.Time) error {
+	return write(ctx, r.pool, nil, stmtOutboxFailed, id, lastErr, nextAttemptAt)
+}
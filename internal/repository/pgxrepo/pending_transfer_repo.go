@@ -0,0 +1,95 @@
+package pgxrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const pendingColumns = `id, sender_id, receiver_id, amount, currency, captured_amount, status, transaction_id, expires_at, created_at, updated_at`
+
+var (
+	stmtPendingInsert = statement("pending_insert", `
+INSERT INTO pending_transfers (`+pendingColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`)
+	stmtPendingGet = statement("pending_get", `
+SELECT `+pendingColumns+` FROM pending_transfers WHERE id = $1`)
+	stmtPendingGetForUpdate = statement("pending_get_for_update", `
+SELECT `+pendingColumns+` FROM pending_transfers WHERE id = $1 FOR UPDATE`)
+	stmtPendingUpdate = statement("pending_update", `
+UPDATE pending_transfers SET captured_amount = $2, status = $3, transaction_id = $4, updated_at = $5 WHERE id = $1`)
+	stmtPendingExpired = statement("pending_expired", `
+SELECT `+pendingColumns+` FROM pending_transfers WHERE status = $1 AND expires_at <= $2 ORDER BY expires_at LIMIT $3`)
+)
+
+type pendingTransferRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPendingTransferRepository(pool *pgxpool.Pool) domain.PendingTransferRepository {
+	return &pendingTransferRepository{pool: pool}
+}
+
+func scanPending(row pgx.Row) (domain.PendingTransfer, error) {
+	var p domain.PendingTransfer
+	err := row.Scan(&p.ID, &p.SenderID, &p.ReceiverID, &p.Amount, &p.Currency, &p.CapturedAmount, &p.Status, &p.TransactionID, &p.ExpiresAt, &p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+func (r *pendingTransferRepository) Create(ctx context.Context, pending *domain.PendingTransfer) error {
+	if pending.ID == uuid.Nil {
+		pending.ID = uuid.New()
+	}
+	now := time.Now()
+	pending.CreatedAt, pending.UpdatedAt = now, now
+	return write(ctx, r.pool, nil, stmtPendingInsert,
+		pending.ID, pending.SenderID, pending.ReceiverID, pending.Amount, pending.Currency, pending.CapturedAmount,
+		pending.Status, pending.TransactionID, pending.ExpiresAt, pending.CreatedAt, pending.UpdatedAt)
+}
+
+func (r *pendingTransferRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.PendingTransfer, error) {
+	return r.get(ctx, stmtPendingGet, id)
+}
+
+func (r *pendingTransferRepository) GetByIDWithLock(ctx context.Context, id uuid.UUID) (*domain.PendingTransfer, error) {
+	return r.get(ctx, stmtPendingGetForUpdate, id)
+}
+
+func (r *pendingTransferRepository) get(ctx context.Context, stmt string, id uuid.UUID) (*domain.PendingTransfer, error) {
+	q, err := reader(ctx, r.pool)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := scanPending(q.QueryRow(ctx, stmt, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrPendingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pending, nil
+}
+
+func (r *pendingTransferRepository) Update(ctx context.Context, pending *domain.PendingTransfer) error {
+	pending.UpdatedAt = time.Now()
+	return write(ctx, r.pool, nil, stmtPendingUpdate,
+		pending.ID, pending.CapturedAmount, pending.Status, pending.TransactionID, pending.UpdatedAt)
+}
+
+func (r *pendingTransferRepository) ListExpired(ctx context.Context, now time.Time, limit int) ([]domain.PendingTransfer, error) {
+	q, err := reader(ctx, r.pool)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.Query(ctx, stmtPendingExpired, domain.PendingStatusPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.PendingTransfer, error) {
+		return scanPending(row)
+	})
+}
@@ -0,0 +1,62 @@
+package pgxrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	stmtQuoteInsert = statement("quote_insert", `
+INSERT INTO fx_quotes (id, sender_id, source_currency, target_currency, source_amount, fee, rate, target_amount, expires_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7::text::numeric, $8, $9, $10)`)
+	stmtQuoteGetForUpdate = statement("quote_get_for_update", `
+SELECT id, sender_id, source_currency, target_currency, source_amount, fee, rate::text, target_amount, expires_at, transaction_id, created_at
+FROM fx_quotes WHERE id = $1 FOR UPDATE`)
+	stmtQuoteMarkUsed = statement("quote_mark_used", `
+UPDATE fx_quotes SET transaction_id = $2 WHERE id = $1`)
+)
+
+type quoteRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewQuoteRepository(pool *pgxpool.Pool) domain.QuoteRepository {
+	return &quoteRepository{pool: pool}
+}
+
+func (r *quoteRepository) Create(ctx context.Context, quote *domain.FXQuote) error {
+	if quote.ID == uuid.Nil {
+		quote.ID = uuid.New()
+	}
+	quote.CreatedAt = time.Now()
+	return write(ctx, r.pool, nil, stmtQuoteInsert,
+		quote.ID, quote.SenderID, quote.SourceCurrency, quote.TargetCurrency, quote.SourceAmount, quote.Fee, quote.Rate, quote.TargetAmount, quote.ExpiresAt, quote.CreatedAt)
+}
+
+func (r *quoteRepository) GetByIDWithLock(ctx context.Context, id uuid.UUID) (*domain.FXQuote, error) {
+	q, err := reader(ctx, r.pool)
+	if err != nil {
+		return nil, err
+	}
+	var quote domain.FXQuote
+	err = q.QueryRow(ctx, stmtQuoteGetForUpdate, id).Scan(
+		&quote.ID, &quote.SenderID, &quote.SourceCurrency, &quote.TargetCurrency, &quote.SourceAmount, &quote.Fee, &quote.Rate,
+		&quote.TargetAmount, &quote.ExpiresAt, &quote.TransactionID, &quote.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrQuoteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+func (r *quoteRepository) MarkUsed(ctx context.Context, id, transactionID uuid.UUID) error {
+	return write(ctx, r.pool, nil, stmtQuoteMarkUsed, id, transactionID)
+}
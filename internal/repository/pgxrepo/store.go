@@ -0,0 +1,162 @@
+// Package pgxrepo implements the domain repositories directly on pgx, without
+// GORM's reflection. Every fixed query is a named statement prepared once per
+// connection by PrepareStatements.
+//
+// Inside a transaction, writes that return nothing the service needs are not
+// sent right away: they are queued and sent together in one pgx.Batch before
+// the next read or the commit, so a transfer's balance updates, transaction
+// row, ledger entries and outbox event cost a single round trip. An error
+// from a queued write, such as ErrWalletNotFound or a check constraint, is
+// returned by that next read or by WithTx, and rolls the transaction back.
+// Writes whose error the service acts on, such as ErrIdempotencyKeyInUse or
+// ErrRefundExceedsAmount, use writeNow instead, so that their error is
+// returned by the repository method that made them.
+package pgxrepo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// statements holds the SQL of every named statement, by name.
+var statements = make(map[string]string)
+
+// statement registers sql to be prepared as name and returns name.
+func statement(name, sql string) string {
+	statements[name] = sql
+	return name
+}
+
+// PrepareStatements prepares the repositories' statements on conn. Pass it as
+// the pool's AfterConnect hook.
+func PrepareStatements(ctx context.Context, conn *pgx.Conn) error {
+	for name, sql := range statements {
+		if _, err := conn.Prepare(ctx, name, sql); err != nil {
+			return fmt.Errorf("prepare %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// querier is what pgx.Tx and *pgxpool.Pool have in common.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type txKey struct{}
+
+// txState is a transaction and the writes queued on it.
+type txState struct {
+	tx    pgx.Tx
+	batch *pgx.Batch
+}
+
+func txFrom(ctx context.Context) *txState {
+	t, _ := ctx.Value(txKey{}).(*txState)
+	return t
+}
+
+// flush sends the queued writes.
+func (t *txState) flush(ctx context.Context) error {
+	if t.batch.Len() == 0 {
+		return nil
+	}
+	batch := t.batch
+	t.batch = &pgx.Batch{}
+	return t.tx.SendBatch(ctx, batch).Close()
+}
+
+// withTx runs fn in a transaction carried by the context passed to fn. If
+// ctx already carries a transaction, fn joins it.
+func withTx(ctx context.Context, pool *pgxpool.Pool, fn func(ctx context.Context) error) error {
+	if txFrom(ctx) != nil {
+		return fn(ctx)
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx) // No-op after commit
+
+	t := &txState{tx: tx, batch: &pgx.Batch{}}
+	if err := fn(context.WithValue(ctx, txKey{}, t)); err != nil {
+		return err
+	}
+	if err := t.flush(ctx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// reader returns the transaction carried by ctx, with its queued writes
+// sent, or pool outside a transaction.
+func reader(ctx context.Context, pool *pgxpool.Pool) (querier, error) {
+	t := txFrom(ctx)
+	if t == nil {
+		return pool, nil
+	}
+	if err := t.flush(ctx); err != nil {
+		return nil, err
+	}
+	return t.tx, nil
+}
+
+// write runs stmt, queueing it if ctx carries a transaction. check, if not
+// nil, inspects the command tag once the statement has run.
+func write(ctx context.Context, pool *pgxpool.Pool, check func(pgconn.CommandTag) error, stmt string, args ...any) error {
+	if t := txFrom(ctx); t != nil {
+		t.batch.Queue(stmt, args...).Exec(func(tag pgconn.CommandTag) error {
+			if check == nil {
+				return nil
+			}
+			return check(tag)
+		})
+		return nil
+	}
+
+	tag, err := pool.Exec(ctx, stmt, args...)
+	if err != nil || check == nil {
+		return err
+	}
+	return check(tag)
+}
+
+// writeNow is write for a statement whose error must be returned by the
+// call that makes it. Inside a transaction it is sent together with the
+// writes queued before it.
+func writeNow(ctx context.Context, pool *pgxpool.Pool, check func(pgconn.CommandTag) error, stmt string, args ...any) error {
+	if err := write(ctx, pool, check, stmt, args...); err != nil {
+		return err
+	}
+	if t := txFrom(ctx); t != nil {
+		return t.flush(ctx)
+	}
+	return nil
+}
+
+// writeReturning is write for a statement returning one row, which scan
+// reads once the statement has run.
+func writeReturning(ctx context.Context, pool *pgxpool.Pool, scan func(pgx.Row) error, stmt string, args ...any) error {
+	if t := txFrom(ctx); t != nil {
+		t.batch.Queue(stmt, args...).QueryRow(scan)
+		return nil
+	}
+	return scan(pool.QueryRow(ctx, stmt, args...))
+}
+
+// affects returns a check that fails with err unless the statement changed a row.
+func affects(err error) func(pgconn.CommandTag) error {
+	return func(tag pgconn.CommandTag) error {
+		if tag.RowsAffected() == 0 {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,141 @@
+package pgxrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const transactionColumns = `id, sender_id, receiver_id, amount, currency, type, quote_id, refunded_amount, original_transaction_id, created_at`
+
+var (
+	stmtTransactionInsert = statement("transaction_insert", `
+INSERT INTO transactions (`+transactionColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`)
+	stmtTransactionGet = statement("transaction_get", `
+SELECT `+transactionColumns+` FROM transactions WHERE id = $1`)
+	stmtTransactionGetForUpdate = statement("transaction_get_for_update", `
+SELECT `+transactionColumns+` FROM transactions WHERE id = $1 FOR UPDATE`)
+	// The guard keeps the refunded total within the original amount even if a
+	// caller skipped the check.
+	stmtTransactionAddRefunded = statement("transaction_add_refunded", `
+UPDATE transactions SET refunded_amount = refunded_amount + $2 WHERE id = $1 AND refunded_amount + $2 <= amount`)
+)
+
+type transactionRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewTransactionRepository(pool *pgxpool.Pool) domain.TransactionRepository {
+	return &transactionRepository{pool: pool}
+}
+
+func scanTransaction(row pgx.Row) (domain.Transaction, error) {
+	var t domain.Transaction
+	err := row.Scan(&t.ID, &t.SenderID, &t.ReceiverID, &t.Amount, &t.Currency, &t.Type, &t.QuoteID, &t.RefundedAmount, &t.OriginalTransactionID, &t.CreatedAt)
+	return t, err
+}
+
+func (r *transactionRepository) Create(ctx context.Context, transaction *domain.Transaction) error {
+	if transaction.ID == uuid.Nil {
+		transaction.ID = uuid.New()
+	}
+	if transaction.Currency == "" {
+		transaction.Currency = domain.DefaultCurrency
+	}
+	transaction.CreatedAt = time.Now()
+	return write(ctx, r.pool, nil, stmtTransactionInsert,
+		transaction.ID, transaction.SenderID, transaction.ReceiverID, transaction.Amount, transaction.Currency,
+		transaction.Type, transaction.QuoteID, transaction.RefundedAmount, transaction.OriginalTransactionID, transaction.CreatedAt)
+}
+
+func (r *transactionRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Transaction, error) {
+	return r.get(ctx, stmtTransactionGet, id)
+}
+
+func (r *transactionRepository) GetByIDWithLock(ctx context.Context, id uuid.UUID) (*domain.Transaction, error) {
+	return r.get(ctx, stmtTransactionGetForUpdate, id)
+}
+
+func (r *transactionRepository) get(ctx context.Context, stmt string, id uuid.UUID) (*domain.Transaction, error) {
+	q, err := reader(ctx, r.pool)
+	if err != nil {
+		return nil, err
+	}
+	transaction, err := scanTransaction(q.QueryRow(ctx, stmt, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+func (r *transactionRepository) AddRefundedAmount(ctx context.Context, id uuid.UUID, amount int64) error {
+	return writeNow(ctx, r.pool, affects(domain.ErrRefundExceedsAmount), stmtTransactionAddRefunded, id, amount)
+}
+
+// ListByWallet builds its query from the filter, so it is not a named
+// statement; pgx still caches its plan per distinct SQL.
+func (r *transactionRepository) ListByWallet(ctx context.Context, filter domain.TransactionFilter) ([]domain.Transaction, error) {
+	var where []string
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	switch filter.Direction {
+	case domain.DirectionIncoming:
+		where = append(where, "receiver_id = "+arg(filter.WalletID))
+	case domain.DirectionOutgoing:
+		where = append(where, "sender_id = "+arg(filter.WalletID))
+	default:
+		id := arg(filter.WalletID)
+		where = append(where, "(sender_id = "+id+" OR receiver_id = "+id+")")
+	}
+
+	if filter.Type != "" {
+		where = append(where, "type = "+arg(filter.Type))
+	}
+	if filter.MinAmount != nil {
+		where = append(where, "amount >= "+arg(*filter.MinAmount))
+	}
+	if filter.MaxAmount != nil {
+		where = append(where, "amount <= "+arg(*filter.MaxAmount))
+	}
+	if filter.From != nil {
+		where = append(where, "created_at >= "+arg(*filter.From))
+	}
+	if filter.To != nil {
+		where = append(where, "created_at < "+arg(*filter.To))
+	}
+	if filter.After != nil {
+		where = append(where, "(created_at, id) < ("+arg(filter.After.CreatedAt)+", "+arg(filter.After.ID)+")")
+	}
+
+	sql := "SELECT " + transactionColumns + " FROM transactions WHERE " + strings.Join(where, " AND ") +
+		" ORDER BY created_at DESC, id DESC"
+	if filter.Limit > 0 {
+		sql += " LIMIT " + arg(filter.Limit)
+	}
+
+	q, err := reader(ctx, r.pool)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.Transaction, error) {
+		return scanTransaction(row)
+	})
+}
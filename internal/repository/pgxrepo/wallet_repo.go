@@ -0,0 +1,287 @@
+package pgxrepo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const walletColumns = `id, user_id, balance, held_balance, currency, stripes, status, version, created_at, updated_at`
+
+var (
+	stmtWalletInsert = statement("wallet_insert", `
+INSERT INTO wallets (`+walletColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`)
+	stmtWalletGet = statement("wallet_get", `
+SELECT `+walletColumns+` FROM wallets WHERE id = $1`)
+	stmtWalletGetForUpdate = statement("wallet_get_for_update", `
+SELECT `+walletColumns+` FROM wallets WHERE id = $1 FOR UPDATE`)
+	stmtWalletAdjustBalance = statement("wallet_adjust_balance", `
+UPDATE wallets SET balance = balance + $2, version = version + 1, updated_at = now() WHERE id = $1`)
+	stmtWalletAdjustHeld = statement("wallet_adjust_held", `
+UPDATE wallets SET held_balance = held_balance + $2, version = version + 1, updated_at = now() WHERE id = $1`)
+	stmtWalletSetStripes = statement("wallet_set_stripes", `
+UPDATE wallets SET stripes = $2, version = version + 1, updated_at = now() WHERE id = $1`)
+	stmtWalletSetStatus = statement("wallet_set_status", `
+UPDATE wallets SET status = $2, version = version + 1, updated_at = now() WHERE id = $1`)
+	stmtWalletsByUser = statement("wallets_by_user", `
+SELECT `+walletColumns+` FROM wallets WHERE user_id = $1 ORDER BY created_at, id`)
+
+	// The same statement as the GORM repository's transferBalanceSQL.
+	stmtWalletTransfer = statement("wallet_transfer", `
+WITH locked AS (
+	SELECT * FROM wallets WHERE id IN ($1, $2) ORDER BY id FOR UPDATE
+), debit AS (
+	UPDATE wallets SET balance = balance - $3, version = version + 1, updated_at = now()
+	WHERE id = $1
+		AND balance - held_balance >= $3
+		AND (SELECT count(*) FROM locked WHERE currency = $4) = 2
+	RETURNING id
+), credit AS (
+	UPDATE wallets SET balance = balance + $3, version = version + 1, updated_at = now()
+	WHERE id = $2 AND EXISTS (SELECT 1 FROM debit)
+	RETURNING id
+)
+SELECT `+walletColumns+`, (SELECT count(*) FROM credit) FROM locked`)
+
+	stmtStripeCreate = statement("stripe_create", `
+INSERT INTO wallet_stripes (wallet_id, stripe, balance, version)
+SELECT $1, s, 0, 0 FROM generate_series(0, $2::int - 1) AS s
+ON CONFLICT DO NOTHING`)
+	stmtStripeAdjust = statement("stripe_adjust", `
+UPDATE wallet_stripes SET balance = balance + $3, version = version + 1 WHERE wallet_id = $1 AND stripe = $2`)
+	stmtStripeLockFunded = statement("stripe_lock_funded", `
+SELECT stripe, balance FROM wallet_stripes WHERE wallet_id = $1 AND balance > 0 ORDER BY stripe FOR UPDATE`)
+	stmtStripeLockAll = statement("stripe_lock_all", `
+SELECT COALESCE(SUM(balance), 0) FROM (
+	SELECT balance FROM wallet_stripes WHERE wallet_id = $1 ORDER BY stripe FOR UPDATE
+) AS locked`)
+	stmtStripeEmpty = statement("stripe_empty", `
+UPDATE wallet_stripes SET balance = 0, version = version + 1 WHERE wallet_id = $1 AND balance > 0`)
+	stmtStripeSum = statement("stripe_sum", `
+SELECT COALESCE(SUM(balance), 0), COALESCE(SUM(version), 0) FROM wallet_stripes WHERE wallet_id = $1`)
+)
+
+type walletRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewWalletRepository(pool *pgxpool.Pool) domain.WalletRepository {
+	return &walletRepository{pool: pool}
+}
+
+func scanWallet(row pgx.Row, extra ...any) (*domain.Wallet, error) {
+	var w domain.Wallet
+	dest := append([]any{&w.ID, &w.UserID, &w.Balance, &w.Held, &w.Currency, &w.Stripes, &w.Status, &w.Version, &w.CreatedAt, &w.UpdatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrWalletNotFound
+		}
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (r *walletRepository) Create(ctx context.Context, wallet *domain.Wallet) error {
+	if wallet.ID == uuid.Nil {
+		wallet.ID = uuid.New()
+	}
+	if wallet.Currency == "" {
+		wallet.Currency = domain.DefaultCurrency
+	}
+	if wallet.Status == "" {
+		wallet.Status = domain.WalletStatusActive
+	}
+	now := time.Now()
+	wallet.CreatedAt, wallet.UpdatedAt = now, now
+	return write(ctx, r.pool, nil, stmtWalletInsert,
+		wallet.ID, wallet.UserID, wallet.Balance, wallet.Held, wallet.Currency, wallet.Stripes, wallet.Status, wallet.Version, wallet.CreatedAt, wallet.UpdatedAt)
+}
+
+func (r *walletRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Wallet, error) {
+	q, err := reader(ctx, r.pool)
+	if err != nil {
+		return nil, err
+	}
+	return scanWallet(q.QueryRow(ctx, stmtWalletGet, id))
+}
+
+func (r *walletRepository) GetByIDWithLock(ctx context.Context, id uuid.UUID) (*domain.Wallet, error) {
+	q, err := reader(ctx, r.pool)
+	if err != nil {
+		return nil, err
+	}
+	return scanWallet(q.QueryRow(ctx, stmtWalletGetForUpdate, id))
+}
+
+func (r *walletRepository) AdjustBalance(ctx context.Context, id uuid.UUID, delta int64) error {
+	return write(ctx, r.pool, affects(domain.ErrWalletNotFound), stmtWalletAdjustBalance, id, delta)
+}
+
+func (r *walletRepository) AdjustHeld(ctx context.Context, id uuid.UUID, delta int64) error {
+	return write(ctx, r.pool, affects(domain.ErrWalletNotFound), stmtWalletAdjustHeld, id, delta)
+}
+
+func (r *walletRepository) TransferBalance(ctx context.Context, senderID, receiverID uuid.UUID, amount domain.Money) (sender, receiver *domain.Wallet, err error) {
+	q, err := reader(ctx, r.pool)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := q.Query(ctx, stmtWalletTransfer, senderID, receiverID, amount.Amount, amount.Currency)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var wallets []*domain.Wallet
+	var moved int
+	for rows.Next() {
+		w, err := scanWallet(rows, &moved)
+		if err != nil {
+			return nil, nil, err
+		}
+		wallets = append(wallets, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(wallets) < 2 {
+		return nil, nil, domain.ErrWalletNotFound
+	}
+
+	for _, w := range wallets {
+		if w.Currency != amount.Currency {
+			return nil, nil, domain.ErrCurrencyMismatch
+		}
+		if w.ID == senderID {
+			sender = w
+		} else {
+			receiver = w
+		}
+	}
+	if moved == 0 {
+		return nil, nil, domain.ErrInsufficientFunds
+	}
+	return sender, receiver, nil
+}
+
+func (r *walletRepository) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return withTx(ctx, r.pool, fn)
+}
+
+func (r *walletRepository) SetStripes(ctx context.Context, id uuid.UUID, stripes int) error {
+	if err := write(ctx, r.pool, nil, stmtStripeCreate, id, stripes); err != nil {
+		return err
+	}
+	return write(ctx, r.pool, affects(domain.ErrWalletNotFound), stmtWalletSetStripes, id, stripes)
+}
+
+func (r *walletRepository) CreditStripe(ctx context.Context, id uuid.UUID, stripe int, amount int64) error {
+	return write(ctx, r.pool, affects(domain.ErrWalletNotFound), stmtStripeAdjust, id, stripe, amount)
+}
+
+func (r *walletRepository) BorrowFromStripes(ctx context.Context, id uuid.UUID, amount int64) (int64, error) {
+	var moved int64
+	// The stripes are locked, and the debits must run in the same transaction.
+	err := withTx(ctx, r.pool, func(ctx context.Context) error {
+		q, err := reader(ctx, r.pool)
+		if err != nil {
+			return err
+		}
+		// Stripes are locked in index order so concurrent borrowers cannot deadlock.
+		rows, err := q.Query(ctx, stmtStripeLockFunded, id)
+		if err != nil {
+			return err
+		}
+		type funded struct {
+			stripe  int
+			balance int64
+		}
+		stripes, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (funded, error) {
+			var f funded
+			err := row.Scan(&f.stripe, &f.balance)
+			return f, err
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, s := range stripes {
+			if moved == amount {
+				break
+			}
+			take := min(s.balance, amount-moved)
+			if err := write(ctx, r.pool, nil, stmtStripeAdjust, id, s.stripe, -take); err != nil {
+				return err
+			}
+			moved += take
+		}
+		if moved > 0 {
+			return r.AdjustBalance(ctx, id, moved)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return moved, nil
+}
+
+func (r *walletRepository) CollectStripes(ctx context.Context, id uuid.UUID) (int64, error) {
+	var moved int64
+	err := withTx(ctx, r.pool, func(ctx context.Context) error {
+		q, err := reader(ctx, r.pool)
+		if err != nil {
+			return err
+		}
+		if err := q.QueryRow(ctx, stmtStripeLockAll, id).Scan(&moved); err != nil {
+			return err
+		}
+		if moved == 0 {
+			return nil
+		}
+		if err := write(ctx, r.pool, nil, stmtStripeEmpty, id); err != nil {
+			return err
+		}
+		return r.AdjustBalance(ctx, id, moved)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return moved, nil
+}
+
+func (r *walletRepository) SumStripes(ctx context.Context, id uuid.UUID) (balance, version int64, err error) {
+	q, err := reader(ctx, r.pool)
+	if err != nil {
+		return 0, 0, err
+	}
+	err = q.QueryRow(ctx, stmtStripeSum, id).Scan(&balance, &version)
+	return balance, version, err
+}
+
+func (r *walletRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.Wallet, error) {
+	q, err := reader(ctx, r.pool)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.Query(ctx, stmtWalletsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.Wallet, error) {
+		w, err := scanWallet(row)
+		if err != nil {
+			return domain.Wallet{}, err
+		}
+		return *w, nil
+	})
+}
+
+func (r *walletRepository) SetStatus(ctx context.Context, id uuid.UUID, status domain.WalletStatus) error {
+	return write(ctx, r.pool, affects(domain.ErrWalletNotFound), stmtWalletSetStatus, id, status)
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type quoteRepository struct {
+	db *gorm.DB
+}
+
+func NewQuoteRepository(db *gorm.DB) domain.QuoteRepository {
+	return &quoteRepository{db: db}
+}
+
+func (r *quoteRepository) Create(ctx context.Context, quote *domain.FXQuote) error {
+	return dbFor(ctx, r.db).Create(quote).Error
+}
+
+func (r *quoteRepository) GetByIDWithLock(ctx context.Context, id uuid.UUID) (*domain.FXQuote, error) {
+	var quote domain.FXQuote
+	conn := dbFor(ctx, r.db)
+	err := conn.Clauses(clause.Locking{Strength: "UPDATE"}).First(&quote, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrQuoteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &quote, nil
+}
+
+func (r *quoteRepository) MarkUsed(ctx context.Context, id, transactionID uuid.UUID) error {
+	conn := dbFor(ctx, r.db)
+	return conn.Model(&domain.FXQuote{}).Where("id = ?", id).Update("transaction_id", transactionID).Error
+}
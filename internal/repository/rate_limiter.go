@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/redis/go-redis/v9"
+)
+
+// rateLimitScript counts a request in the window KEYS[1], which starts with
+// its first request and lasts ARGV[1] milliseconds. It returns the count and
+// the milliseconds left in the window.
+var rateLimitScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return {count, redis.call('PTTL', KEYS[1])}
+`)
+
+type rateLimiter struct {
+	client *redis.Client
+}
+
+// NewRateLimiter returns a RateLimiter whose counters live in Redis, so the
+// limits hold across server instances.
+func NewRateLimiter(client *redis.Client) domain.RateLimiter {
+	return &rateLimiter{client: client}
+}
+
+func (l *rateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	res, err := rateLimitScript.Run(ctx, l.client, []string{"ratelimit:" + key}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	count, ttl := res[0], time.Duration(res[1])*time.Millisecond
+	if count <= int64(limit) {
+		return true, 0, nil
+	}
+	return false, ttl, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"digital-wallet/internal/domain"
+)
+
+// staticRateProvider serves rates from a fixed table, for offline use and
+// tests. A missing direction is derived from the inverse rate.
+type staticRateProvider struct {
+	rates map[[2]domain.Currency]*big.Rat
+}
+
+// NewStaticRateProvider builds a RateProvider from rates keyed "FROM/TO",
+// e.g. {"USD/EUR": "0.92"}. Rates are decimal strings.
+func NewStaticRateProvider(rates map[string]string) (domain.RateProvider, error) {
+	p := &staticRateProvider{rates: make(map[[2]domain.Currency]*big.Rat)}
+	for pair, value := range rates {
+		from, to, ok := strings.Cut(pair, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid currency pair %q, expected FROM/TO", pair)
+		}
+		fromCur, err := domain.ParseCurrency(from)
+		if err != nil {
+			return nil, err
+		}
+		toCur, err := domain.ParseCurrency(to)
+		if err != nil {
+			return nil, err
+		}
+		rate, ok := new(big.Rat).SetString(value)
+		if !ok || rate.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid rate %q for %s", value, pair)
+		}
+		p.rates[[2]domain.Currency{fromCur, toCur}] = rate
+	}
+	return p, nil
+}
+
+// LoadRateFile reads a JSON object of "FROM/TO" pairs to decimal rates.
+func LoadRateFile(path string) (domain.RateProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate file: %w", err)
+	}
+	var rates map[string]string
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("failed to parse rate file: %w", err)
+	}
+	return NewStaticRateProvider(rates)
+}
+
+func (p *staticRateProvider) Rate(_ context.Context, from, to domain.Currency) (*big.Rat, error) {
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+	if rate, ok := p.rates[[2]domain.Currency{from, to}]; ok {
+		return new(big.Rat).Set(rate), nil
+	}
+	if rate, ok := p.rates[[2]domain.Currency{to, from}]; ok {
+		return new(big.Rat).Inv(rate), nil
+	}
+	return nil, fmt.Errorf("%w: %s/%s", domain.ErrRateUnavailable, from, to)
+}
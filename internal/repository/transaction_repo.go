@@ -2,9 +2,12 @@ package repository
 
 import (
 	"context"
+	"errors"
 
 	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type transactionRepository struct {
@@ -15,10 +18,84 @@ func NewTransactionRepository(db *gorm.DB) domain.TransactionRepository {
 	return &transactionRepository{db: db}
 }
 
-func (r *transactionRepository) Create(ctx context.Context, tx *gorm.DB, transaction *domain.Transaction) error {
-	conn := r.db
-	if tx != nil {
-		conn = tx
+func (r *transactionRepository) Create(ctx context.Context, transaction *domain.Transaction) error {
+	conn := dbFor(ctx, r.db)
+	return conn.Create(transaction).Error
+}
+
+func (r *transactionRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Transaction, error) {
+	var transaction domain.Transaction
+	err := readerFor(ctx, r.db).First(&transaction, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+func (r *transactionRepository) GetByIDWithLock(ctx context.Context, id uuid.UUID) (*domain.Transaction, error) {
+	var transaction domain.Transaction
+	conn := dbFor(ctx, r.db)
+	err := conn.Clauses(clause.Locking{Strength: "UPDATE"}).First(&transaction, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrTransactionNotFound
 	}
-	return conn.WithContext(ctx).Create(transaction).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+func (r *transactionRepository) AddRefundedAmount(ctx context.Context, id uuid.UUID, amount int64) error {
+	conn := dbFor(ctx, r.db)
+	// The guard keeps the refunded total within the original amount even if a
+	// caller skipped the check.
+	result := conn.Model(&domain.Transaction{}).
+		Where("id = ? AND refunded_amount + ? <= amount", id, amount).
+		Update("refunded_amount", gorm.Expr("refunded_amount + ?", amount))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrRefundExceedsAmount
+	}
+	return nil
+}
+
+func (r *transactionRepository) ListByWallet(ctx context.Context, filter domain.TransactionFilter) ([]domain.Transaction, error) {
+	q := readerFor(ctx, r.db).Model(&domain.Transaction{})
+
+	switch filter.Direction {
+	case domain.DirectionIncoming:
+		q = q.Where("receiver_id = ?", filter.WalletID)
+	case domain.DirectionOutgoing:
+		q = q.Where("sender_id = ?", filter.WalletID)
+	default:
+		q = q.Where("(sender_id = ? OR receiver_id = ?)", filter.WalletID, filter.WalletID)
+	}
+
+	if filter.Type != "" {
+		q = q.Where("type = ?", filter.Type)
+	}
+	if filter.MinAmount != nil {
+		q = q.Where("amount >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		q = q.Where("amount <= ?", *filter.MaxAmount)
+	}
+	if filter.From != nil {
+		q = q.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		q = q.Where("created_at < ?", *filter.To)
+	}
+	if filter.After != nil {
+		q = q.Where("(created_at, id) < (?, ?)", filter.After.CreatedAt, filter.After.ID)
+	}
+
+	var transactions []domain.Transaction
+	err := q.Order("created_at DESC, id DESC").Limit(filter.Limit).Find(&transactions).Error
+	return transactions, err
 }
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+
+	"digital-wallet/internal/domain"
+	"digital-wallet/pkg/postgres"
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// dbFor returns the transaction carried by ctx, or db when ctx carries none.
+func dbFor(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// readerFor is dbFor for reads that tolerate replication lag. Outside a
+// transaction, and unless ctx asks for primary reads, the query may be served
+// by a replica set up with postgres.UseReplicas.
+func readerFor(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok || domain.PrimaryReads(ctx) {
+		return dbFor(ctx, db)
+	}
+	return postgres.ReplicaRead(db.WithContext(ctx))
+}
+
+// withTx runs fn in a transaction on db carried by the context passed to fn.
+// If ctx already carries a transaction, fn joins it.
+func withTx(ctx context.Context, db *gorm.DB, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 
 	"digital-wallet/internal/domain"
 	"github.com/google/uuid"
@@ -18,39 +19,268 @@ func NewWalletRepository(db *gorm.DB) domain.WalletRepository {
 }
 
 func (r *walletRepository) Create(ctx context.Context, wallet *domain.Wallet) error {
-	return r.db.WithContext(ctx).Create(wallet).Error
+	return dbFor(ctx, r.db).Create(wallet).Error
 }
 
 func (r *walletRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Wallet, error) {
 	var wallet domain.Wallet
-	if err := r.db.WithContext(ctx).First(&wallet, "id = ?", id).Error; err != nil {
+	err := readerFor(ctx, r.db).First(&wallet, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrWalletNotFound
+	}
+	if err != nil {
 		return nil, err
 	}
 	return &wallet, nil
 }
 
-func (r *walletRepository) GetByIDWithLock(ctx context.Context, tx *gorm.DB, id uuid.UUID) (*domain.Wallet, error) {
+func (r *walletRepository) GetByIDWithLock(ctx context.Context, id uuid.UUID) (*domain.Wallet, error) {
 	var wallet domain.Wallet
-	conn := r.db
-	if tx != nil {
-		conn = tx
+	conn := dbFor(ctx, r.db)
+	err := conn.Clauses(clause.Locking{Strength: "UPDATE"}).First(&wallet, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, domain.ErrWalletNotFound
 	}
-	if err := conn.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&wallet, "id = ?", id).Error; err != nil {
+	if err != nil {
 		return nil, err
 	}
 	return &wallet, nil
 }
 
-func (r *walletRepository) UpdateBalance(ctx context.Context, tx *gorm.DB, id uuid.UUID, newBalance int64) error {
-	conn := r.db
-	if tx != nil {
-		conn = tx
+func (r *walletRepository) AdjustBalance(ctx context.Context, id uuid.UUID, delta int64) error {
+	conn := dbFor(ctx, r.db)
+	result := conn.Model(&domain.Wallet{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"balance": gorm.Expr("balance + ?", delta),
+		"version": gorm.Expr("version + 1"),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrWalletNotFound
 	}
-	// GORM updates fields with non-zero values by default, but 0 balance is valid.
-	// So we specifically select the column or use map.
-	return conn.WithContext(ctx).Model(&domain.Wallet{}).Where("id = ?", id).Update("balance", newBalance).Error
+	return nil
 }
 
-func (r *walletRepository) WithTx(ctx context.Context, fn func(tx *gorm.DB) error) error {
-	return r.db.WithContext(ctx).Transaction(fn)
+func (r *walletRepository) AdjustHeld(ctx context.Context, id uuid.UUID, delta int64) error {
+	conn := dbFor(ctx, r.db)
+	result := conn.Model(&domain.Wallet{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"held_balance": gorm.Expr("held_balance + ?", delta),
+		"version":      gorm.Expr("version + 1"),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrWalletNotFound
+	}
+	return nil
+}
+
+// transferBalanceSQL locks both wallets in ID order, the order the service
+// uses for locking reads, then applies the guarded debit and the credit. The
+// credit only runs if the debit matched a row. It returns the wallets as
+// locked, each with the number of rows credited.
+const transferBalanceSQL = `
+WITH locked AS (
+	SELECT * FROM wallets WHERE id IN (@sender, @receiver) ORDER BY id FOR UPDATE
+), debit AS (
+	UPDATE wallets SET balance = balance - @amount, version = version + 1, updated_at = now()
+	WHERE id = @sender
+		AND balance - held_balance >= @amount
+		AND (SELECT count(*) FROM locked WHERE currency = @currency) = 2
+	RETURNING id
+), credit AS (
+	UPDATE wallets SET balance = balance + @amount, version = version + 1, updated_at = now()
+	WHERE id = @receiver AND EXISTS (SELECT 1 FROM debit)
+	RETURNING id
+)
+SELECT locked.*, (SELECT count(*) FROM credit) AS moved FROM locked`
+
+func (r *walletRepository) TransferBalance(ctx context.Context, senderID, receiverID uuid.UUID, amount domain.Money) (sender, receiver *domain.Wallet, err error) {
+	conn := dbFor(ctx, r.db)
+	var rows []struct {
+		domain.Wallet `gorm:"embedded"`
+		Moved         int
+	}
+	err = conn.Raw(transferBalanceSQL, map[string]interface{}{
+		"sender":   senderID,
+		"receiver": receiverID,
+		"amount":   amount.Amount,
+		"currency": amount.Currency,
+	}).Scan(&rows).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rows) < 2 {
+		return nil, nil, domain.ErrWalletNotFound
+	}
+
+	for i := range rows {
+		if rows[i].Currency != amount.Currency {
+			return nil, nil, domain.ErrCurrencyMismatch
+		}
+		if rows[i].ID == senderID {
+			sender = &rows[i].Wallet
+		} else {
+			receiver = &rows[i].Wallet
+		}
+	}
+	if rows[0].Moved == 0 {
+		return nil, nil, domain.ErrInsufficientFunds
+	}
+	return sender, receiver, nil
+}
+
+func (r *walletRepository) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return withTx(ctx, r.db, fn)
+}
+
+func (r *walletRepository) SetStripes(ctx context.Context, id uuid.UUID, stripes int) error {
+	conn := dbFor(ctx, r.db)
+	rows := make([]domain.WalletStripe, stripes)
+	for i := range rows {
+		rows[i] = domain.WalletStripe{WalletID: id, Stripe: i}
+	}
+	if err := conn.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+		return err
+	}
+	result := conn.Model(&domain.Wallet{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"stripes": stripes,
+		"version": gorm.Expr("version + 1"),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrWalletNotFound
+	}
+	return nil
+}
+
+func (r *walletRepository) CreditStripe(ctx context.Context, id uuid.UUID, stripe int, amount int64) error {
+	conn := dbFor(ctx, r.db)
+	result := conn.Model(&domain.WalletStripe{}).
+		Where("wallet_id = ? AND stripe = ?", id, stripe).
+		Updates(map[string]interface{}{
+			"balance": gorm.Expr("balance + ?", amount),
+			"version": gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrWalletNotFound
+	}
+	return nil
+}
+
+func (r *walletRepository) BorrowFromStripes(ctx context.Context, id uuid.UUID, amount int64) (int64, error) {
+	conn := dbFor(ctx, r.db)
+
+	// Stripes are locked in index order so concurrent borrowers cannot deadlock.
+	var stripes []domain.WalletStripe
+	err := conn.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("wallet_id = ? AND balance > 0", id).
+		Order("stripe").
+		Find(&stripes).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var moved int64
+	for _, stripe := range stripes {
+		if moved == amount {
+			break
+		}
+		take := min(stripe.Balance, amount-moved)
+		err := conn.Model(&domain.WalletStripe{}).
+			Where("wallet_id = ? AND stripe = ?", id, stripe.Stripe).
+			Updates(map[string]interface{}{
+				"balance": gorm.Expr("balance - ?", take),
+				"version": gorm.Expr("version + 1"),
+			}).Error
+		if err != nil {
+			return 0, err
+		}
+		moved += take
+	}
+
+	if moved > 0 {
+		if err := r.AdjustBalance(ctx, id, moved); err != nil {
+			return 0, err
+		}
+	}
+	return moved, nil
+}
+
+func (r *walletRepository) CollectStripes(ctx context.Context, id uuid.UUID) (int64, error) {
+	conn := dbFor(ctx, r.db)
+
+	var stripes []domain.WalletStripe
+	err := conn.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("wallet_id = ?", id).
+		Order("stripe").
+		Find(&stripes).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var moved int64
+	for _, stripe := range stripes {
+		moved += stripe.Balance
+	}
+	if moved == 0 {
+		return 0, nil
+	}
+
+	err = conn.Model(&domain.WalletStripe{}).
+		Where("wallet_id = ? AND balance > 0", id).
+		Updates(map[string]interface{}{
+			"balance": 0,
+			"version": gorm.Expr("version + 1"),
+		}).Error
+	if err != nil {
+		return 0, err
+	}
+	if err := r.AdjustBalance(ctx, id, moved); err != nil {
+		return 0, err
+	}
+	return moved, nil
+}
+
+func (r *walletRepository) SumStripes(ctx context.Context, id uuid.UUID) (balance, version int64, err error) {
+	var sum struct {
+		Balance int64
+		Version int64
+	}
+	err = dbFor(ctx, r.db).Model(&domain.WalletStripe{}).
+		Where("wallet_id = ?", id).
+		Select("COALESCE(SUM(balance), 0) AS balance, COALESCE(SUM(version), 0) AS version").
+		Scan(&sum).Error
+	return sum.Balance, sum.Version, err
+}
+
+func (r *walletRepository) ListByUser(ctx context.Context, userID uuid.UUID) ([]domain.Wallet, error) {
+	var wallets []domain.Wallet
+	err := readerFor(ctx, r.db).
+		Where("user_id = ?", userID).
+		Order("created_at, id").
+		Find(&wallets).Error
+	return wallets, err
+}
+
+func (r *walletRepository) SetStatus(ctx context.Context, id uuid.UUID, status domain.WalletStatus) error {
+	conn := dbFor(ctx, r.db)
+	result := conn.Model(&domain.Wallet{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":  status,
+		"version": gorm.Expr("version + 1"),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrWalletNotFound
+	}
+	return nil
 }
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+// Page sizes of the admin ledger and audit log listings.
+const (
+	DefaultAdminListLimit = 50
+	MaxAdminListLimit     = 500
+)
+
+// WithAdmin enables the staff operations below, recording each of them in
+// audit. They act for staff, not for the wallet's owner, so they never check
+// wallet ownership; the caller must have checked the actor's role.
+func WithAdmin(audit domain.AdminAuditRepository) Option {
+	return func(s *WalletService) {
+		s.auditRepo = audit
+	}
+}
+
+var errAdminDisabled = fmt.Errorf("%w: admin API", domain.ErrFeatureDisabled)
+
+// audit appends entry to the audit log on behalf of actor.
+func (s *WalletService) audit(ctx context.Context, actor domain.AdminActor, entry domain.AdminAuditEntry) error {
+	entry.Actor = actor.ID
+	entry.Role = actor.Role
+	return s.auditRepo.Append(ctx, &entry)
+}
+
+func adminLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultAdminListLimit
+	}
+	return min(limit, MaxAdminListLimit)
+}
+
+// AdminListWallets returns the wallets of a user, oldest first.
+func (s *WalletService) AdminListWallets(ctx context.Context, actor domain.AdminActor, userID uuid.UUID) ([]domain.Wallet, error) {
+	if s.auditRepo == nil {
+		return nil, errAdminDisabled
+	}
+
+	wallets, err := s.walletRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range wallets {
+		if err := s.foldStripes(ctx, &wallets[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.audit(ctx, actor, domain.AdminAuditEntry{Action: domain.AdminActionListWallets, UserID: &userID})
+	if err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
+// AdminLedger returns the latest limit ledger entries of a wallet, newest first.
+func (s *WalletService) AdminLedger(ctx context.Context, actor domain.AdminActor, walletID uuid.UUID, limit int) ([]domain.LedgerEntry, error) {
+	if s.auditRepo == nil {
+		return nil, errAdminDisabled
+	}
+
+	if _, err := s.walletRepo.GetByID(ctx, walletID); err != nil {
+		return nil, err
+	}
+	entries, err := s.ledgerRepo.ListByAccount(ctx, domain.WalletAccount(walletID), adminLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.audit(ctx, actor, domain.AdminAuditEntry{Action: domain.AdminActionViewLedger, WalletID: &walletID})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// FreezeWallet stops money from leaving a wallet until it is unfrozen.
+// Credits still land unless the service runs with FreezeAll, holds can be
+// voided but not captured, and finance staff can still post adjustments.
+func (s *WalletService) FreezeWallet(ctx context.Context, actor domain.AdminActor, walletID uuid.UUID, note string) (*domain.Wallet, error) {
+	return s.setWalletStatus(ctx, actor, walletID, domain.WalletStatusFrozen, domain.AdminActionFreeze, note)
+}
+
+// UnfreezeWallet makes a frozen wallet active again.
+func (s *WalletService) UnfreezeWallet(ctx context.Context, actor domain.AdminActor, walletID uuid.UUID, note string) (*domain.Wallet, error) {
+	return s.setWalletStatus(ctx, actor, walletID, domain.WalletStatusActive, domain.AdminActionUnfreeze, note)
+}
+
+func (s *WalletService) setWalletStatus(
+	ctx context.Context,
+	actor domain.AdminActor,
+	walletID uuid.UUID,
+	status domain.WalletStatus,
+	action, note string,
+) (*domain.Wallet, error) {
+	if s.auditRepo == nil {
+		return nil, errAdminDisabled
+	}
+
+	var wallet *domain.Wallet
+	var event domain.WalletStatusEvent
+
+	err := s.walletRepo.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		wallet, err = s.walletRepo.GetByIDWithLock(ctx, walletID)
+		if err != nil {
+			return err
+		}
+		if err := s.checkVersion(ctx, wallet); err != nil {
+			return err
+		}
+		if wallet.Status == status {
+			return domain.ErrStatusUnchanged
+		}
+		if !wallet.Status.CanTransitionTo(status) {
+			return domain.ErrInvalidTransition
+		}
+		if err := s.walletRepo.SetStatus(ctx, walletID, status); err != nil {
+			return err
+		}
+		if err := s.audit(ctx, actor, domain.AdminAuditEntry{Action: action, WalletID: &walletID, Note: note}); err != nil {
+			return err
+		}
+		event = statusEvent(wallet, status, actor.ID)
+		return s.recordStatusEvent(ctx, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidateWallets(ctx, wallet)
+	s.publishStatusEvent(ctx, event)
+
+	return s.adminWallet(ctx, walletID)
+}
+
+// AdminCloseWallet closes a wallet like CloseWallet, for any owner and from
+// the frozen status too, so that staff can sweep the balance of a
+// compromised wallet to a safe one.
+func (s *WalletService) AdminCloseWallet(
+	ctx context.Context,
+	actor domain.AdminActor,
+	walletID uuid.UUID,
+	sweepTo *uuid.UUID,
+	note string,
+) (*domain.Wallet, *domain.Transaction, error) {
+	if s.auditRepo == nil {
+		return nil, nil, errAdminDisabled
+	}
+	return s.closeWallet(ctx, walletID, sweepTo, &actor, note)
+}
+
+// PostAdjustment corrects a wallet's balance by amount, which is signed:
+// positive amounts credit the wallet, negative ones debit it. The other leg
+// posts to HouseAccountAdjustments. Debits need the available balance but
+// are allowed on frozen wallets; closed wallets take no adjustments.
+func (s *WalletService) PostAdjustment(
+	ctx context.Context,
+	actor domain.AdminActor,
+	walletID uuid.UUID,
+	amount domain.Money,
+	reason domain.AdjustmentReason,
+	note string,
+) (*domain.Transaction, error) {
+	if s.auditRepo == nil {
+		return nil, errAdminDisabled
+	}
+	if amount.Amount == 0 {
+		return nil, fmt.Errorf("%w: an adjustment cannot be zero", domain.ErrInvalidAmount)
+	}
+	if _, err := domain.ParseAdjustmentReason(string(reason)); err != nil {
+		return nil, err
+	}
+
+	var transaction *domain.Transaction
+	var wallet *domain.Wallet
+
+	err := s.walletRepo.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		wallet, err = s.walletRepo.GetByIDWithLock(ctx, walletID)
+		if err != nil {
+			return fmt.Errorf("failed to lock wallet %s: %w", walletID, err)
+		}
+		if err := s.checkVersion(ctx, wallet); err != nil {
+			return err
+		}
+		if wallet.Currency != amount.Currency {
+			return domain.ErrCurrencyMismatch
+		}
+		if wallet.Status == domain.WalletStatusClosed {
+			return domain.ErrWalletClosed
+		}
+
+		transaction = &domain.Transaction{
+			Currency: amount.Currency,
+			Type:     domain.TransactionTypeAdjustment,
+		}
+		var entries []domain.LedgerEntry
+		if amount.Amount > 0 {
+			transaction.ReceiverID = &wallet.ID
+			transaction.Amount = amount.Amount
+			entries = []domain.LedgerEntry{
+				domain.Debit(domain.HouseAccountAdjustments, amount),
+				domain.Credit(domain.WalletAccount(wallet.ID), amount),
+			}
+		} else {
+			debit := domain.NewMoney(-amount.Amount, amount.Currency)
+			if err := s.checkAvailable(ctx, wallet, debit.Amount); err != nil {
+				return err
+			}
+			transaction.SenderID = &wallet.ID
+			transaction.Amount = debit.Amount
+			entries = []domain.LedgerEntry{
+				domain.Debit(domain.WalletAccount(wallet.ID), debit),
+				domain.Credit(domain.HouseAccountAdjustments, debit),
+			}
+		}
+		if err := s.postTransaction(ctx, transaction, entries...); err != nil {
+			return err
+		}
+
+		return s.audit(ctx, actor, domain.AdminAuditEntry{
+			Action:        domain.AdminActionAdjust,
+			WalletID:      &walletID,
+			TransactionID: &transaction.ID,
+			ReasonCode:    reason,
+			Note:          note,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.afterCommit(ctx, transaction, wallet)
+
+	return transaction, nil
+}
+
+// AdminAuditLog returns entries of the audit log, newest first. Reading the
+// log is itself recorded.
+func (s *WalletService) AdminAuditLog(ctx context.Context, actor domain.AdminActor, filter domain.AdminAuditFilter) ([]domain.AdminAuditEntry, error) {
+	if s.auditRepo == nil {
+		return nil, errAdminDisabled
+	}
+
+	filter.Limit = adminLimit(filter.Limit)
+	entries, err := s.auditRepo.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.audit(ctx, actor, domain.AdminAuditEntry{Action: domain.AdminActionViewAuditLog, WalletID: filter.WalletID})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// adminWallet reads a wallet just changed by staff from the primary, with
+// its stripes folded in.
+func (s *WalletService) adminWallet(ctx context.Context, walletID uuid.UUID) (*domain.Wallet, error) {
+	wallet, err := s.walletRepo.GetByID(domain.WithPrimaryReads(ctx), walletID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.foldStripes(ctx, wallet); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+// DefaultAPIKeyRotationOverlap is how long a rotated key keeps working next
+// to its replacement when no overlap is requested.
+const DefaultAPIKeyRotationOverlap = 24 * time.Hour
+
+const (
+	apiKeyTag        = "wk_"              // Starts every key, so leaked keys are easy to scan for
+	signingSecretTag = "wss_"             // Starts every signing secret
+	apiKeySecretLen  = 32                 // Random bytes in a key and in a signing secret
+	apiKeyPrefixLen  = len(apiKeyTag) + 8 // Characters of the key kept as APIKey.Prefix
+)
+
+// WithAPIKeys enables API keys for server-to-server calls.
+func WithAPIKeys(repo domain.APIKeyRepository) Option {
+	return func(s *WalletService) {
+		s.apiKeyRepo = repo
+	}
+}
+
+var errAPIKeysDisabled = fmt.Errorf("%w: API keys", domain.ErrFeatureDisabled)
+
+// hashAPIKey returns the stored hash of key. Keys are random, so a fast hash
+// is enough to make a leaked table useless.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueAPIKey creates a key with the name, scopes, owner, admin role, rate
+// limit and expiry of key and returns the key itself, which is not stored and
+// cannot be shown again. key is filled in as stored, including a new
+// SigningSecret. Only keys with the admin scope may carry an admin role.
+func (s *WalletService) IssueAPIKey(ctx context.Context, key *domain.APIKey) (string, error) {
+	if s.apiKeyRepo == nil {
+		return "", errAPIKeysDisabled
+	}
+	if len(key.Scopes) == 0 {
+		return "", domain.ErrInvalidScope
+	}
+	for _, scope := range key.Scopes {
+		if _, err := domain.ParseAPIScope(string(scope)); err != nil {
+			return "", err
+		}
+	}
+	if key.AdminRole != "" {
+		if _, err := domain.ParseAdminRole(string(key.AdminRole)); err != nil {
+			return "", err
+		}
+		if !key.HasScope(domain.ScopeAdmin) {
+			return "", fmt.Errorf("%w: an admin role needs the %s scope", domain.ErrInvalidScope, domain.ScopeAdmin)
+		}
+	}
+	return s.createAPIKey(ctx, key)
+}
+
+// randomSecret returns tag followed by apiKeySecretLen random bytes.
+func randomSecret(tag string) (string, error) {
+	secret := make([]byte, apiKeySecretLen)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return tag + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func (s *WalletService) createAPIKey(ctx context.Context, key *domain.APIKey) (string, error) {
+	plain, err := randomSecret(apiKeyTag)
+	if err != nil {
+		return "", err
+	}
+	signingSecret, err := randomSecret(signingSecretTag)
+	if err != nil {
+		return "", err
+	}
+
+	key.ID = uuid.Nil
+	key.Prefix = plain[:apiKeyPrefixLen]
+	key.KeyHash = hashAPIKey(plain)
+	key.SigningSecret = signingSecret
+	key.RevokedAt = nil
+	if err := s.apiKeyRepo.Create(ctx, key); err != nil {
+		return "", err
+	}
+	return plain, nil
+}
+
+// RotateAPIKey issues a replacement for the key with the same name, scopes,
+// owner, admin role and rate limit, and a lifetime as long as the old key's if
+// it had one. The replacement has a signing secret of its own. The old key
+// keeps working, with its old secret, for overlap, so clients can switch
+// over, and then expires.
+func (s *WalletService) RotateAPIKey(ctx context.Context, id uuid.UUID, overlap time.Duration) (*domain.APIKey, string, error) {
+	if s.apiKeyRepo == nil {
+		return nil, "", errAPIKeysDisabled
+	}
+	if overlap < 0 {
+		overlap = 0
+	}
+
+	var replacement *domain.APIKey
+	var plain string
+	err := s.walletRepo.WithTx(ctx, func(ctx context.Context) error {
+		old, err := s.apiKeyRepo.GetByIDWithLock(ctx, id)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if !old.Active(now) {
+			return domain.ErrAPIKeyNotActive
+		}
+
+		replacement = &domain.APIKey{
+			Name:        old.Name,
+			Scopes:      old.Scopes,
+			OwnerID:     old.OwnerID,
+			AdminRole:   old.AdminRole,
+			RateLimit:   old.RateLimit,
+			RotatedFrom: &old.ID,
+		}
+		if old.ExpiresAt != nil {
+			expiresAt := now.Add(old.ExpiresAt.Sub(old.CreatedAt))
+			replacement.ExpiresAt = &expiresAt
+		}
+		if plain, err = s.createAPIKey(ctx, replacement); err != nil {
+			return err
+		}
+
+		if cutoff := now.Add(overlap); old.ExpiresAt == nil || cutoff.Before(*old.ExpiresAt) {
+			old.ExpiresAt = &cutoff
+		}
+		return s.apiKeyRepo.Update(ctx, old)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return replacement, plain, nil
+}
+
+// RevokeAPIKey stops the key from working at once. Revoking a revoked key
+// returns it unchanged.
+func (s *WalletService) RevokeAPIKey(ctx context.Context, id uuid.UUID) (*domain.APIKey, error) {
+	if s.apiKeyRepo == nil {
+		return nil, errAPIKeysDisabled
+	}
+
+	var key *domain.APIKey
+	err := s.walletRepo.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		key, err = s.apiKeyRepo.GetByIDWithLock(ctx, id)
+		if err != nil || key.RevokedAt != nil {
+			return err
+		}
+		now := time.Now()
+		key.RevokedAt = &now
+		return s.apiKeyRepo.Update(ctx, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (s *WalletService) ListAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	if s.apiKeyRepo == nil {
+		return nil, errAPIKeysDisabled
+	}
+	return s.apiKeyRepo.List(ctx)
+}
+
+// AuthenticateAPIKey returns the stored key for plain, or ErrInvalidAPIKey
+// if there is none or it has expired or been revoked.
+func (s *WalletService) AuthenticateAPIKey(ctx context.Context, plain string) (*domain.APIKey, error) {
+	if s.apiKeyRepo == nil {
+		return nil, errAPIKeysDisabled
+	}
+	key, err := s.apiKeyRepo.GetByHash(ctx, hashAPIKey(plain))
+	if errors.Is(err, domain.ErrAPIKeyNotFound) {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !key.Active(time.Now()) {
+		return nil, domain.ErrInvalidAPIKey
+	}
+	return key, nil
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+// BalanceStrategy selects how TransferMoney updates the two wallets.
+type BalanceStrategy string
+
+const (
+	// BalanceStrategyPessimistic locks both wallets with SELECT ... FOR UPDATE,
+	// checks the sender's balance and then updates each wallet.
+	BalanceStrategyPessimistic BalanceStrategy = "pessimistic"
+	// BalanceStrategyConditional moves the money with one guarded statement,
+	// see WalletRepository.TransferBalance.
+	BalanceStrategyConditional BalanceStrategy = "conditional"
+)
+
+// ParseBalanceStrategy validates a strategy name.
+func ParseBalanceStrategy(name string) (BalanceStrategy, error) {
+	switch strategy := BalanceStrategy(name); strategy {
+	case BalanceStrategyPessimistic, BalanceStrategyConditional:
+		return strategy, nil
+	}
+	return "", fmt.Errorf("unknown balance strategy %q", name)
+}
+
+// WithBalanceStrategy selects the balance update strategy of transfers
+// between two wallets. The default is BalanceStrategyPessimistic.
+func WithBalanceStrategy(strategy BalanceStrategy) Option {
+	return func(s *WalletService) {
+		s.balanceStrategy = strategy
+	}
+}
+
+// transferConditional is transfer for BalanceStrategyConditional: the balance
+// check and both balance updates are a single round trip.
+func (s *WalletService) transferConditional(ctx context.Context, senderID, receiverID uuid.UUID, amount domain.Money) (*domain.Transaction, *domain.Wallet, *domain.Wallet, error) {
+	sender, receiver, err := s.walletRepo.TransferBalance(ctx, senderID, receiverID, amount)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// The statement does not look at statuses. Failing here rolls the move
+	// back with the rest of the transaction.
+	if err := checkDebitable(sender); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := s.checkCreditable(receiver); err != nil {
+		return nil, nil, nil, err
+	}
+
+	transaction := &domain.Transaction{
+		SenderID:   &senderID,
+		ReceiverID: &receiverID,
+		Amount:     amount.Amount,
+		Currency:   amount.Currency,
+		Type:       domain.TransactionTypeTransfer,
+	}
+	err = s.recordPosting(ctx, transaction, []domain.LedgerEntry{
+		domain.Debit(domain.WalletAccount(senderID), amount),
+		domain.Credit(domain.WalletAccount(receiverID), amount),
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return transaction, sender, receiver, nil
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+// MaxBatchLegs is the largest number of legs accepted by TransferBatch.
+const MaxBatchLegs = 5000
+
+// TransferBatch performs many transfers in one database transaction. Every
+// wallet involved is locked once, in the same order lockPair uses, so a batch
+// paying out from one wallet holds its row lock for the whole batch instead
+// of taking it once per transfer.
+//
+// In BatchModeAtomic the first failing leg aborts the batch and its error is
+// returned. In BatchModePerLeg legs that fail validation or balance checks are
+// reported in their result and the others commit; database errors still abort
+// the whole batch.
+func (s *WalletService) TransferBatch(ctx context.Context, legs []domain.TransferLeg, mode domain.BatchMode) ([]domain.BatchLegResult, error) {
+	if mode != domain.BatchModeAtomic && mode != domain.BatchModePerLeg {
+		return nil, fmt.Errorf("%w: unknown mode %q", domain.ErrInvalidBatch, mode)
+	}
+	if len(legs) == 0 || len(legs) > MaxBatchLegs {
+		return nil, fmt.Errorf("%w: between 1 and %d legs are required", domain.ErrInvalidBatch, MaxBatchLegs)
+	}
+
+	results := make([]domain.BatchLegResult, len(legs))
+	for i, leg := range legs {
+		results[i].Index = i
+		results[i].Err = validateTransfer(leg.SenderID, leg.ReceiverID, leg.Amount)
+		if results[i].Err != nil && mode == domain.BatchModeAtomic {
+			return nil, fmt.Errorf("leg %d: %w", i, results[i].Err)
+		}
+	}
+
+	var walletIDs []uuid.UUID
+	for i, leg := range legs {
+		if results[i].Err == nil {
+			walletIDs = append(walletIDs, leg.SenderID, leg.ReceiverID)
+		}
+	}
+
+	var wallets map[uuid.UUID]*domain.Wallet
+
+	err := s.walletRepo.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		wallets, err = s.lockWallets(ctx, walletIDs)
+		if err != nil {
+			return err
+		}
+
+		for i, leg := range legs {
+			if results[i].Err != nil {
+				continue
+			}
+
+			if err := s.checkBatchLeg(ctx, wallets, leg); err != nil {
+				if mode == domain.BatchModeAtomic {
+					return fmt.Errorf("leg %d: %w", i, err)
+				}
+				results[i].Err = err
+				continue
+			}
+
+			sender, receiver := wallets[leg.SenderID], wallets[leg.ReceiverID]
+			transaction := &domain.Transaction{
+				SenderID:   &sender.ID,
+				ReceiverID: &receiver.ID,
+				Amount:     leg.Amount.Amount,
+				Currency:   leg.Amount.Currency,
+				Type:       domain.TransactionTypeTransfer,
+			}
+			err := s.postTransaction(ctx, transaction,
+				domain.Debit(domain.WalletAccount(sender.ID), leg.Amount),
+				domain.Credit(domain.WalletAccount(receiver.ID), leg.Amount),
+			)
+			if err != nil {
+				return fmt.Errorf("leg %d: %w", i, err)
+			}
+
+			// Later legs are checked against the balances left by earlier ones.
+			sender.Balance -= leg.Amount.Amount
+			receiver.Balance += leg.Amount.Amount
+			results[i].Transaction = transaction
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, wallet := range wallets {
+		s.invalidateWallets(ctx, wallet)
+	}
+	for _, result := range results {
+		if result.Transaction != nil {
+			s.afterCommit(ctx, result.Transaction)
+		}
+	}
+
+	return results, nil
+}
+
+// lockWallets locks each distinct wallet once, in the order used by lockPair
+// so that batches and single transfers cannot deadlock each other, and checks
+// ExpectVersion preconditions on them. Wallets that do not exist are left out
+// of the result.
+func (s *WalletService) lockWallets(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*domain.Wallet, error) {
+	wallets := make(map[uuid.UUID]*domain.Wallet, len(ids))
+	var order []uuid.UUID
+	for _, id := range ids {
+		if _, ok := wallets[id]; !ok {
+			wallets[id] = nil
+			order = append(order, id)
+		}
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i].String() < order[j].String() })
+
+	for _, id := range order {
+		wallet, err := s.walletRepo.GetByIDWithLock(ctx, id)
+		if errors.Is(err, domain.ErrWalletNotFound) {
+			delete(wallets, id)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock wallet %s: %w", id, err)
+		}
+		if err := s.checkVersion(ctx, wallet); err != nil {
+			return nil, err
+		}
+		wallets[id] = wallet
+	}
+	return wallets, nil
+}
+
+// checkBatchLeg applies the checks transfer makes against locked wallets.
+func (s *WalletService) checkBatchLeg(ctx context.Context, wallets map[uuid.UUID]*domain.Wallet, leg domain.TransferLeg) error {
+	sender, receiver := wallets[leg.SenderID], wallets[leg.ReceiverID]
+	if sender == nil || receiver == nil {
+		return domain.ErrWalletNotFound
+	}
+	if err := authorizeOwner(ctx, sender); err != nil {
+		return err
+	}
+	if sender.Currency != leg.Amount.Currency || receiver.Currency != leg.Amount.Currency {
+		return domain.ErrCurrencyMismatch
+	}
+	if err := checkDebitable(sender); err != nil {
+		return err
+	}
+	if err := s.checkCreditable(receiver); err != nil {
+		return err
+	}
+	return s.checkAvailable(ctx, sender, leg.Amount.Amount)
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+// DefaultQuoteTTL is how long a quote is valid when FXConfig.QuoteTTL is unset.
+const DefaultQuoteTTL = 30 * time.Second
+
+// FXConfig controls cross-currency pricing.
+type FXConfig struct {
+	FeeBasisPoints int64         // Fee charged on the source amount, in hundredths of a percent
+	QuoteTTL       time.Duration // How long a quote can be used
+}
+
+// WithFX enables quotes and cross-currency transfers.
+func WithFX(quotes domain.QuoteRepository, rates domain.RateProvider, cfg FXConfig) Option {
+	return func(s *WalletService) {
+		if cfg.QuoteTTL <= 0 {
+			cfg.QuoteTTL = DefaultQuoteTTL
+		}
+		s.quoteRepo = quotes
+		s.rateProvider = rates
+		s.fx = cfg
+	}
+}
+
+var errFXDisabled = fmt.Errorf("%w: cross-currency transfers", domain.ErrFeatureDisabled)
+
+// CreateQuote prices sending amount out of wallet senderID into target
+// currency and locks the price until the quote expires. Only transfers out of
+// senderID can use the quote.
+func (s *WalletService) CreateQuote(ctx context.Context, senderID uuid.UUID, amount domain.Money, target domain.Currency) (*domain.FXQuote, error) {
+	if s.quoteRepo == nil {
+		return nil, errFXDisabled
+	}
+	if amount.Amount <= 0 {
+		return nil, domain.ErrInvalidAmount
+	}
+	if _, err := domain.ParseCurrency(string(amount.Currency)); err != nil {
+		return nil, err
+	}
+	if _, err := domain.ParseCurrency(string(target)); err != nil {
+		return nil, err
+	}
+	if amount.Currency == target {
+		return nil, fmt.Errorf("%w: source and target currency are both %s", domain.ErrCurrencyMismatch, target)
+	}
+	if err := s.authorizeWallet(ctx, senderID); err != nil {
+		return nil, err
+	}
+
+	rate, err := s.rateProvider.Rate(ctx, amount.Currency, target)
+	if err != nil {
+		return nil, err
+	}
+
+	fee, err := amount.BasisPoints(s.fx.FeeBasisPoints)
+	if err != nil {
+		return nil, err
+	}
+	converted, err := domain.NewMoney(amount.Amount-fee.Amount, amount.Currency).Convert(target, rate)
+	if err != nil {
+		return nil, err
+	}
+	if converted.Amount <= 0 {
+		return nil, fmt.Errorf("%w: amount is too small to convert", domain.ErrInvalidAmount)
+	}
+
+	quote := &domain.FXQuote{
+		SenderID:       senderID,
+		SourceCurrency: amount.Currency,
+		TargetCurrency: target,
+		SourceAmount:   amount.Amount,
+		Fee:            fee.Amount,
+		Rate:           rate.FloatString(12),
+		TargetAmount:   converted.Amount,
+		ExpiresAt:      time.Now().Add(s.fx.QuoteTTL),
+	}
+	if err := s.quoteRepo.Create(ctx, quote); err != nil {
+		return nil, err
+	}
+	return quote, nil
+}
+
+// TransferWithQuote moves money between wallets of different currencies at
+// the quote's locked price. The source currency flows into the house FX
+// account for that currency and the target currency flows out of the house
+// FX account for the target, so the ledger balances in each currency.
+func (s *WalletService) TransferWithQuote(ctx context.Context, senderID, receiverID, quoteID uuid.UUID) (*domain.Transaction, error) {
+	if s.quoteRepo == nil {
+		return nil, errFXDisabled
+	}
+	if senderID == receiverID {
+		return nil, domain.ErrSelfTransfer
+	}
+	if err := s.authorizeWallet(ctx, senderID); err != nil {
+		return nil, err
+	}
+
+	var transaction *domain.Transaction
+	var sender, receiver *domain.Wallet
+
+	err := s.walletRepo.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		sender, receiver, err = s.lockPair(ctx, senderID, receiverID)
+		if err != nil {
+			return err
+		}
+
+		quote, err := s.quoteRepo.GetByIDWithLock(ctx, quoteID)
+		if err != nil {
+			return err
+		}
+		// Someone else's quote is not disclosed, let alone used.
+		if quote.SenderID != sender.ID {
+			return domain.ErrQuoteNotFound
+		}
+		if quote.TransactionID != nil {
+			return domain.ErrQuoteUsed
+		}
+		if time.Now().After(quote.ExpiresAt) {
+			return domain.ErrQuoteExpired
+		}
+		if sender.Currency != quote.SourceCurrency || receiver.Currency != quote.TargetCurrency {
+			return domain.ErrCurrencyMismatch
+		}
+		if err := checkDebitable(sender); err != nil {
+			return err
+		}
+		if err := s.checkCreditable(receiver); err != nil {
+			return err
+		}
+		if err := s.checkAvailable(ctx, sender, quote.SourceAmount); err != nil {
+			return err
+		}
+
+		source := domain.NewMoney(quote.SourceAmount, quote.SourceCurrency)
+		fee := domain.NewMoney(quote.Fee, quote.SourceCurrency)
+		exchanged := domain.NewMoney(quote.SourceAmount-quote.Fee, quote.SourceCurrency)
+		target := domain.NewMoney(quote.TargetAmount, quote.TargetCurrency)
+
+		entries := []domain.LedgerEntry{
+			domain.Debit(domain.WalletAccount(sender.ID), source),
+			domain.Credit(domain.HouseFXAccount(source.Currency), exchanged),
+			domain.Debit(domain.HouseFXAccount(target.Currency), target),
+			domain.Credit(domain.WalletAccount(receiver.ID), target),
+		}
+		if fee.Amount > 0 {
+			entries = append(entries, domain.Credit(domain.HouseAccountFees, fee))
+		}
+
+		transaction = &domain.Transaction{
+			SenderID:   &sender.ID,
+			ReceiverID: &receiver.ID,
+			Amount:     source.Amount,
+			Currency:   source.Currency,
+			Type:       domain.TransactionTypeFXTransfer,
+			QuoteID:    &quote.ID,
+		}
+		if err := s.postTransaction(ctx, transaction, entries...); err != nil {
+			return err
+		}
+
+		return s.quoteRepo.MarkUsed(ctx, quote.ID, transaction.ID)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.afterCommit(ctx, transaction, sender, receiver)
+
+	return transaction, nil
+}
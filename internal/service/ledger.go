@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"math/rand"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+// postTransaction records transaction as the journal header of entries and
+// applies every wallet leg to that wallet's balance projection. The wallets
+// involved must already be locked in the transaction.
+func (s *WalletService) postTransaction(ctx context.Context, transaction *domain.Transaction, entries ...domain.LedgerEntry) error {
+	return s.postTransactionStriped(ctx, transaction, nil, entries...)
+}
+
+// postTransactionStriped is postTransaction where credits to the wallets in
+// striped, which maps wallet IDs to their stripe count, land on a random
+// stripe. Those wallets need not be locked.
+func (s *WalletService) postTransactionStriped(ctx context.Context, transaction *domain.Transaction, striped map[uuid.UUID]int, entries ...domain.LedgerEntry) error {
+	if err := s.recordPosting(ctx, transaction, entries); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		walletID, ok := entry.WalletID()
+		if !ok {
+			continue
+		}
+		if stripes := striped[walletID]; stripes > 0 && entry.Amount > 0 {
+			if err := s.walletRepo.CreditStripe(ctx, walletID, rand.Intn(stripes), entry.Amount); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := s.walletRepo.AdjustBalance(ctx, walletID, entry.Amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordPosting records transaction and its ledger entries without touching
+// the balance projections.
+func (s *WalletService) recordPosting(ctx context.Context, transaction *domain.Transaction, entries []domain.LedgerEntry) error {
+	if err := domain.ValidatePosting(entries); err != nil {
+		return err
+	}
+
+	if err := s.recordTransaction(ctx, transaction); err != nil {
+		return err
+	}
+
+	for i := range entries {
+		entries[i].TransactionID = transaction.ID
+	}
+	return s.ledgerRepo.CreateEntries(ctx, entries)
+}
+
+// VerifyWalletBalance recomputes a wallet's balance from the ledger and
+// compares it with the stored projection, including any stripes.
+func (s *WalletService) VerifyWalletBalance(ctx context.Context, walletID uuid.UUID) (*domain.BalanceCheck, error) {
+	// The ledger sum is read from the primary, so the wallet must be too.
+	ctx = domain.WithPrimaryReads(ctx)
+	wallet, err := s.walletRepo.GetByID(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeOwner(ctx, wallet); err != nil {
+		return nil, err
+	}
+	if err := s.foldStripes(ctx, wallet); err != nil {
+		return nil, err
+	}
+
+	ledgerBalance, err := s.ledgerRepo.SumByAccount(ctx, domain.WalletAccount(walletID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.BalanceCheck{
+		WalletID:      walletID,
+		Currency:      wallet.Currency,
+		Balance:       wallet.Balance,
+		LedgerBalance: ledgerBalance,
+		Consistent:    wallet.Balance == ledgerBalance,
+	}, nil
+}
+
+// GetTransactionEntries returns the ledger legs of a transaction.
+func (s *WalletService) GetTransactionEntries(ctx context.Context, transactionID uuid.UUID) ([]domain.LedgerEntry, error) {
+	if restricted(ctx) {
+		transaction, err := s.transRepo.GetByID(domain.WithPrimaryReads(ctx), transactionID)
+		if err != nil {
+			return nil, err
+		}
+		var parties []uuid.UUID
+		for _, id := range []*uuid.UUID{transaction.SenderID, transaction.ReceiverID} {
+			if id != nil {
+				parties = append(parties, *id)
+			}
+		}
+		if err := s.authorizeParty(ctx, parties...); err != nil {
+			return nil, err
+		}
+	}
+	return s.ledgerRepo.ListByTransaction(ctx, transactionID)
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"digital-wallet/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// restricted reports whether ctx is limited to some users' wallets: those of
+// its caller or of its API key. A ctx with neither is trusted, as for
+// internal jobs.
+func restricted(ctx context.Context) bool {
+	if key := domain.APIKeyFromContext(ctx); key != nil {
+		return !key.HasScope(domain.ScopeAllWallets)
+	}
+	_, ok := domain.CallerFromContext(ctx)
+	return ok
+}
+
+// mayActFor reports whether ctx may use the wallets of userID. An API key
+// takes precedence over a caller, as requests carrying one need no token.
+func mayActFor(ctx context.Context, userID uuid.UUID) bool {
+	if key := domain.APIKeyFromContext(ctx); key != nil {
+		return key.MayActFor(userID)
+	}
+	if caller, ok := domain.CallerFromContext(ctx); ok {
+		return caller == userID
+	}
+	return true
+}
+
+// authorizeOwner returns ErrForbidden if ctx may not act for the owner of
+// wallet.
+func authorizeOwner(ctx context.Context, wallet *domain.Wallet) error {
+	if !mayActFor(ctx, wallet.UserID) {
+		return domain.ErrForbidden
+	}
+	return nil
+}
+
+// authorizeWallet is authorizeOwner for a wallet that is not read otherwise,
+// checked before a transaction starts. A wallet's owner never changes, so a
+// replica is good enough unless it does not have the wallet yet.
+func (s *WalletService) authorizeWallet(ctx context.Context, walletID uuid.UUID) error {
+	if !restricted(ctx) {
+		return nil
+	}
+	wallet, err := s.walletRepo.GetByID(ctx, walletID)
+	if errors.Is(err, domain.ErrWalletNotFound) {
+		wallet, err = s.walletRepo.GetByID(domain.WithPrimaryReads(ctx), walletID)
+	}
+	if err != nil {
+		return err
+	}
+	return authorizeOwner(ctx, wallet)
+}
+
+// authorizeParty lets the caller through if they own any of walletIDs, for
+// records such as a transfer that both of its parties may read.
+func (s *WalletService) authorizeParty(ctx context.Context, walletIDs ...uuid.UUID) error {
+	if !restricted(ctx) {
+		return nil
+	}
+	for _, id := range walletIDs {
+		err := s.authorizeWallet(ctx, id)
+		if !errors.Is(err, domain.ErrForbidden) {
+			return err
+		}
+	}
+	return domain.ErrForbidden
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+// Pending transfer defaults.
+const (
+	DefaultHoldTTL       = 7 * 24 * time.Hour
+	expiredHoldsPerSweep = 100
+)
+
+// WithPendingTransfers enables authorize/capture/void. Holds that are not
+// captured or voided expire after ttl unless a shorter expiry is requested.
+func WithPendingTransfers(repo domain.PendingTransferRepository, ttl time.Duration) Option {
+	return func(s *WalletService) {
+		if ttl <= 0 {
+			ttl = DefaultHoldTTL
+		}
+		s.pendingRepo = repo
+		s.holdTTL = ttl
+	}
+}
+
+var errPendingDisabled = fmt.Errorf("%w: pending transfers", domain.ErrFeatureDisabled)
+
+// AuthorizeTransfer reserves amount on the sender's wallet for a later
+// capture. The reserved funds stay in the ledger balance but are no longer
+// available to other transfers or withdrawals.
+func (s *WalletService) AuthorizeTransfer(ctx context.Context, senderID, receiverID uuid.UUID, amount domain.Money, expiresIn time.Duration) (*domain.PendingTransfer, error) {
+	if s.pendingRepo == nil {
+		return nil, errPendingDisabled
+	}
+	if err := validateTransfer(senderID, receiverID, amount); err != nil {
+		return nil, err
+	}
+	if err := s.authorizeWallet(ctx, senderID); err != nil {
+		return nil, err
+	}
+	if expiresIn <= 0 || expiresIn > s.holdTTL {
+		expiresIn = s.holdTTL
+	}
+
+	var pending *domain.PendingTransfer
+	var sender *domain.Wallet
+
+	err := s.walletRepo.WithTx(ctx, func(ctx context.Context) error {
+		var receiver *domain.Wallet
+		var err error
+		sender, receiver, err = s.lockPair(ctx, senderID, receiverID)
+		if err != nil {
+			return err
+		}
+
+		if sender.Currency != amount.Currency || receiver.Currency != amount.Currency {
+			return domain.ErrCurrencyMismatch
+		}
+		if err := checkDebitable(sender); err != nil {
+			return err
+		}
+		if err := s.checkCreditable(receiver); err != nil {
+			return err
+		}
+		if err := s.checkAvailable(ctx, sender, amount.Amount); err != nil {
+			return err
+		}
+
+		if err := s.walletRepo.AdjustHeld(ctx, sender.ID, amount.Amount); err != nil {
+			return err
+		}
+
+		pending = &domain.PendingTransfer{
+			SenderID:   sender.ID,
+			ReceiverID: receiver.ID,
+			Amount:     amount.Amount,
+			Currency:   amount.Currency,
+			Status:     domain.PendingStatusPending,
+			ExpiresAt:  time.Now().Add(expiresIn),
+		}
+		return s.pendingRepo.Create(ctx, pending)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidateWallets(ctx, sender)
+
+	return pending, nil
+}
+
+func (s *WalletService) GetPendingTransfer(ctx context.Context, id uuid.UUID) (*domain.PendingTransfer, error) {
+	if s.pendingRepo == nil {
+		return nil, errPendingDisabled
+	}
+	pending, err := s.pendingRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeParty(ctx, pending.SenderID, pending.ReceiverID); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// CapturePendingTransfer moves amount from the hold to the receiver and
+// releases the rest of the hold. An amount of zero captures the full hold.
+// Only the receiver, who was promised the funds, may capture them.
+func (s *WalletService) CapturePendingTransfer(ctx context.Context, id uuid.UUID, amount int64) (*domain.PendingTransfer, *domain.Transaction, error) {
+	if s.pendingRepo == nil {
+		return nil, nil, errPendingDisabled
+	}
+	if amount < 0 {
+		return nil, nil, domain.ErrInvalidAmount
+	}
+
+	var transaction *domain.Transaction
+
+	authorize := func(ctx context.Context, pending *domain.PendingTransfer) error {
+		return s.authorizeWallet(ctx, pending.ReceiverID)
+	}
+	pending, sender, receiver, err := s.settlePending(ctx, id, authorize, func(ctx context.Context, pending *domain.PendingTransfer, sender, receiver *domain.Wallet) error {
+		if pending.ExpiresAt.Before(time.Now()) {
+			return domain.ErrPendingNotActive
+		}
+		// The hold does not outlast a freeze; it can still be voided.
+		if err := checkDebitable(sender); err != nil {
+			return err
+		}
+		if err := s.checkCreditable(receiver); err != nil {
+			return err
+		}
+		captured := amount
+		if captured == 0 {
+			captured = pending.Amount
+		}
+		if captured > pending.Amount {
+			return domain.ErrCaptureExceedsHold
+		}
+
+		money := domain.NewMoney(captured, pending.Currency)
+		transaction = &domain.Transaction{
+			SenderID:   &sender.ID,
+			ReceiverID: &receiver.ID,
+			Amount:     money.Amount,
+			Currency:   money.Currency,
+			Type:       domain.TransactionTypeTransfer,
+		}
+		err := s.postTransaction(ctx, transaction,
+			domain.Debit(domain.WalletAccount(sender.ID), money),
+			domain.Credit(domain.WalletAccount(receiver.ID), money),
+		)
+		if err != nil {
+			return err
+		}
+
+		pending.Status = domain.PendingStatusCaptured
+		pending.CapturedAmount = captured
+		pending.TransactionID = &transaction.ID
+		return nil
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.afterCommit(ctx, transaction, sender, receiver)
+
+	return pending, transaction, nil
+}
+
+// VoidPendingTransfer cancels a hold and releases the reserved funds. Either
+// party may void it: the sender to take back the reservation, the receiver to
+// decline it.
+func (s *WalletService) VoidPendingTransfer(ctx context.Context, id uuid.UUID) (*domain.PendingTransfer, error) {
+	if s.pendingRepo == nil {
+		return nil, errPendingDisabled
+	}
+	return s.releasePending(ctx, id, domain.PendingStatusVoided)
+}
+
+// ExpireStaleHolds releases holds that passed their expiry without being
+// captured or voided, and returns how many were expired.
+func (s *WalletService) ExpireStaleHolds(ctx context.Context) (int, error) {
+	if s.pendingRepo == nil {
+		return 0, nil
+	}
+
+	stale, err := s.pendingRepo.ListExpired(ctx, time.Now(), expiredHoldsPerSweep)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, p := range stale {
+		_, err := s.releasePending(ctx, p.ID, domain.PendingStatusExpired)
+		if errors.Is(err, domain.ErrPendingNotActive) {
+			continue // Captured or voided since it was listed
+		}
+		if err != nil {
+			log.Printf("failed to expire pending transfer %s: %v", p.ID, err)
+			continue
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+func (s *WalletService) releasePending(ctx context.Context, id uuid.UUID, status string) (*domain.PendingTransfer, error) {
+	authorize := func(ctx context.Context, pending *domain.PendingTransfer) error {
+		return s.authorizeParty(ctx, pending.SenderID, pending.ReceiverID)
+	}
+	pending, sender, _, err := s.settlePending(ctx, id, authorize, func(_ context.Context, pending *domain.PendingTransfer, _, _ *domain.Wallet) error {
+		pending.Status = status
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidateWallets(ctx, sender)
+
+	return pending, nil
+}
+
+// settlePending locks a pending transfer's wallets (in the same order as
+// TransferMoney) and then the pending transfer itself, releases the full
+// hold and lets fn settle it. authorize checks the caller against the
+// pending transfer before anything is locked. The wallets are returned as
+// they were locked.
+func (s *WalletService) settlePending(
+	ctx context.Context,
+	id uuid.UUID,
+	authorize func(ctx context.Context, pending *domain.PendingTransfer) error,
+	fn func(ctx context.Context, pending *domain.PendingTransfer, sender, receiver *domain.Wallet) error,
+) (pending *domain.PendingTransfer, sender, receiver *domain.Wallet, err error) {
+	// Read the wallet IDs first so the wallets can be locked before the row.
+	unlocked, err := s.pendingRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ctx = expectPayer(ctx, unlocked.SenderID)
+	if err := authorize(ctx, unlocked); err != nil {
+		return nil, nil, nil, err
+	}
+
+	err = s.walletRepo.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		sender, receiver, err = s.lockPair(ctx, unlocked.SenderID, unlocked.ReceiverID)
+		if err != nil {
+			return err
+		}
+
+		pending, err = s.pendingRepo.GetByIDWithLock(ctx, id)
+		if err != nil {
+			return err
+		}
+		if pending.Status != domain.PendingStatusPending {
+			return domain.ErrPendingNotActive
+		}
+
+		// Release the hold before fn debits the sender, so the balance
+		// never drops below the held amount.
+		if err := s.walletRepo.AdjustHeld(ctx, sender.ID, -pending.Amount); err != nil {
+			return err
+		}
+
+		if err := fn(ctx, pending, sender, receiver); err != nil {
+			return err
+		}
+		return s.pendingRepo.Update(ctx, pending)
+	})
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return pending, sender, receiver, nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+// RefundTransfer sends amount of a transfer back from its receiver to its
+// sender, as a REFUND transaction linked to the original. An amount of zero
+// refunds everything not refunded yet. The original transaction tracks the
+// refunded total, which can never exceed the transferred amount.
+func (s *WalletService) RefundTransfer(ctx context.Context, transactionID uuid.UUID, amount int64) (*domain.Transaction, error) {
+	if amount < 0 {
+		return nil, domain.ErrInvalidAmount
+	}
+
+	// Read the parties first so their wallets can be locked before the
+	// transaction row. A replica may not have the transfer yet.
+	original, err := s.transRepo.GetByID(domain.WithPrimaryReads(ctx), transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if original.Type != domain.TransactionTypeTransfer || original.SenderID == nil || original.ReceiverID == nil {
+		return nil, domain.ErrRefundNotAllowed
+	}
+	payerID, payeeID := *original.ReceiverID, *original.SenderID
+	ctx = expectPayer(ctx, payerID)
+
+	var refund *domain.Transaction
+	var payer, payee *domain.Wallet
+
+	err = s.walletRepo.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		payer, payee, err = s.lockPair(ctx, payerID, payeeID)
+		if err != nil {
+			return err
+		}
+		// Only the receiver of a transfer can refund it.
+		if err := authorizeOwner(ctx, payer); err != nil {
+			return err
+		}
+
+		original, err := s.transRepo.GetByIDWithLock(ctx, transactionID)
+		if err != nil {
+			return err
+		}
+
+		remaining := original.Amount - original.RefundedAmount
+		refundAmount := amount
+		if refundAmount == 0 {
+			refundAmount = remaining
+		}
+		if refundAmount == 0 || refundAmount > remaining {
+			return domain.ErrRefundExceedsAmount
+		}
+		if err := checkDebitable(payer); err != nil {
+			return err
+		}
+		if err := s.checkCreditable(payee); err != nil {
+			return err
+		}
+		if err := s.checkAvailable(ctx, payer, refundAmount); err != nil {
+			return err
+		}
+
+		money := domain.NewMoney(refundAmount, original.Currency)
+		refund = &domain.Transaction{
+			SenderID:              &payer.ID,
+			ReceiverID:            &payee.ID,
+			Amount:                money.Amount,
+			Currency:              money.Currency,
+			Type:                  domain.TransactionTypeRefund,
+			OriginalTransactionID: &original.ID,
+		}
+		err = s.postTransaction(ctx, refund,
+			domain.Debit(domain.WalletAccount(payer.ID), money),
+			domain.Credit(domain.WalletAccount(payee.ID), money),
+		)
+		if err != nil {
+			return err
+		}
+
+		return s.transRepo.AddRefundedAmount(ctx, original.ID, refundAmount)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.afterCommit(ctx, refund, payer, payee)
+
+	return refund, nil
+}
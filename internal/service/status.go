@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+// FreezePolicy selects which operations a frozen wallet rejects.
+type FreezePolicy string
+
+const (
+	// FreezeDebits stops money from leaving a frozen wallet; credits still land.
+	FreezeDebits FreezePolicy = "debits"
+	// FreezeAll stops money from leaving or reaching a frozen wallet.
+	FreezeAll FreezePolicy = "all"
+)
+
+// ParseFreezePolicy validates a freeze policy name.
+func ParseFreezePolicy(name string) (FreezePolicy, error) {
+	switch policy := FreezePolicy(name); policy {
+	case FreezeDebits, FreezeAll:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown freeze policy %q", name)
+}
+
+// WithFreezePolicy selects what a frozen wallet rejects. The default is
+// FreezeDebits.
+func WithFreezePolicy(policy FreezePolicy) Option {
+	return func(s *WalletService) {
+		s.freezePolicy = policy
+	}
+}
+
+// checkDebitable returns ErrWalletFrozen or ErrWalletClosed if money may not
+// leave wallet. Every debit made for a customer checks it; manual adjustments
+// do not, so finance staff can still recover funds from a frozen wallet.
+func checkDebitable(wallet *domain.Wallet) error {
+	switch wallet.Status {
+	case domain.WalletStatusClosed:
+		return domain.ErrWalletClosed
+	case domain.WalletStatusFrozen:
+		return domain.ErrWalletFrozen
+	}
+	return nil
+}
+
+// checkCreditable returns ErrWalletClosed if money may not reach wallet, or
+// ErrWalletFrozen if it is frozen under FreezeAll.
+func (s *WalletService) checkCreditable(wallet *domain.Wallet) error {
+	switch {
+	case wallet.Status == domain.WalletStatusClosed:
+		return domain.ErrWalletClosed
+	case wallet.Status == domain.WalletStatusFrozen && s.freezePolicy == FreezeAll:
+		return domain.ErrWalletFrozen
+	}
+	return nil
+}
+
+// recheckStripedCredit checks, after a credit landed on a stripe of receiver,
+// that receiver could still take it. The receiver was read before the
+// transaction; the stripe lock now held makes a concurrent CloseWallet wait,
+// and one that committed first is visible to this read.
+func (s *WalletService) recheckStripedCredit(ctx context.Context, receiverID uuid.UUID) error {
+	receiver, err := s.walletRepo.GetByID(ctx, receiverID)
+	if err != nil {
+		return err
+	}
+	return s.checkCreditable(receiver)
+}
+
+// statusEvent is the event of wallet changing to status.
+func statusEvent(wallet *domain.Wallet, to domain.WalletStatus, actor string) domain.WalletStatusEvent {
+	return domain.WalletStatusEvent{
+		WalletID:   wallet.ID,
+		UserID:     wallet.UserID,
+		From:       wallet.Status,
+		To:         to,
+		Actor:      actor,
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+// recordStatusEvent writes a status change's event to the outbox, when it is
+// enabled, in the transaction of the change.
+func (s *WalletService) recordStatusEvent(ctx context.Context, event domain.WalletStatusEvent) error {
+	if s.outboxRepo == nil {
+		return nil
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.outboxRepo.Enqueue(ctx, &domain.OutboxEvent{
+		EventType: domain.EventTypeWalletStatus,
+		SenderID:  &event.WalletID,
+		Payload:   payload,
+	})
+}
+
+// publishStatusEvent publishes a committed status change's event when there
+// is no outbox. Like afterCommit, it is best effort.
+func (s *WalletService) publishStatusEvent(ctx context.Context, event domain.WalletStatusEvent) {
+	if s.outboxRepo != nil {
+		return
+	}
+	if err := s.eventProducer.PublishWalletStatusEvent(ctx, event); err != nil {
+		log.Printf("failed to publish wallet status event for %s: %v", event.WalletID, err)
+	}
+}
+
+// CloseWallet closes one of the caller's wallets for good. A wallet that
+// still holds money must name sweepTo, an open wallet of the same currency
+// that receives the balance in a SWEEP transaction; holds must be captured or
+// voided first. Frozen wallets can only be closed by staff, see
+// AdminCloseWallet. The sweep transaction is nil if nothing was swept.
+func (s *WalletService) CloseWallet(ctx context.Context, walletID uuid.UUID, sweepTo *uuid.UUID) (*domain.Wallet, *domain.Transaction, error) {
+	return s.closeWallet(ctx, walletID, sweepTo, nil, "")
+}
+
+// closeWallet is CloseWallet for the wallet's owner if actor is nil, and
+// AdminCloseWallet otherwise.
+func (s *WalletService) closeWallet(
+	ctx context.Context,
+	walletID uuid.UUID,
+	sweepTo *uuid.UUID,
+	actor *domain.AdminActor,
+	note string,
+) (*domain.Wallet, *domain.Transaction, error) {
+	if sweepTo != nil && *sweepTo == walletID {
+		return nil, nil, domain.ErrSelfTransfer
+	}
+
+	var wallet, target *domain.Wallet
+	var sweep *domain.Transaction
+	var event domain.WalletStatusEvent
+
+	err := s.walletRepo.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		if sweepTo != nil {
+			wallet, target, err = s.lockPair(ctx, walletID, *sweepTo)
+		} else {
+			wallet, err = s.walletRepo.GetByIDWithLock(ctx, walletID)
+			if err == nil {
+				err = s.checkVersion(ctx, wallet)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if actor == nil {
+			if err := authorizeOwner(ctx, wallet); err != nil {
+				return err
+			}
+		}
+
+		if wallet.Status == domain.WalletStatusClosed {
+			return domain.ErrStatusUnchanged
+		}
+		if !wallet.Status.CanTransitionTo(domain.WalletStatusClosed) {
+			return domain.ErrInvalidTransition
+		}
+		if actor == nil && wallet.Status == domain.WalletStatusFrozen {
+			return domain.ErrWalletFrozen
+		}
+		if wallet.Held > 0 {
+			return fmt.Errorf("%w: capture or void its pending transfers first", domain.ErrWalletNotEmpty)
+		}
+		if target != nil {
+			if target.Currency != wallet.Currency {
+				return domain.ErrCurrencyMismatch
+			}
+			if err := s.checkCreditable(target); err != nil {
+				return fmt.Errorf("sweep to wallet %s: %w", target.ID, err)
+			}
+		}
+
+		// The stripes stay locked, so no credit reaches them between here
+		// and the status change.
+		if wallet.Stripes > 0 {
+			moved, err := s.walletRepo.CollectStripes(ctx, wallet.ID)
+			if err != nil {
+				return fmt.Errorf("failed to collect stripes of wallet %s: %w", wallet.ID, err)
+			}
+			wallet.Balance += moved
+		}
+
+		if wallet.Balance > 0 {
+			if target == nil {
+				return fmt.Errorf("%w: sweep its balance to another wallet", domain.ErrWalletNotEmpty)
+			}
+			balance := domain.NewMoney(wallet.Balance, wallet.Currency)
+			sweep = &domain.Transaction{
+				SenderID:   &wallet.ID,
+				ReceiverID: &target.ID,
+				Amount:     balance.Amount,
+				Currency:   balance.Currency,
+				Type:       domain.TransactionTypeSweep,
+			}
+			err := s.postTransaction(ctx, sweep,
+				domain.Debit(domain.WalletAccount(wallet.ID), balance),
+				domain.Credit(domain.WalletAccount(target.ID), balance),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := s.walletRepo.SetStatus(ctx, wallet.ID, domain.WalletStatusClosed); err != nil {
+			return err
+		}
+
+		event = statusEvent(wallet, domain.WalletStatusClosed, "")
+		if sweep != nil {
+			event.SweepTransactionID = &sweep.ID
+		}
+		if actor != nil {
+			event.Actor = actor.ID
+			err := s.audit(ctx, *actor, domain.AdminAuditEntry{
+				Action:        domain.AdminActionClose,
+				WalletID:      &walletID,
+				TransactionID: event.SweepTransactionID,
+				Note:          note,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return s.recordStatusEvent(ctx, event)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if sweep != nil {
+		s.afterCommit(ctx, sweep, wallet, target)
+	} else {
+		s.invalidateWallets(ctx, wallet)
+	}
+	s.publishStatusEvent(ctx, event)
+
+	closed, err := s.adminWallet(ctx, walletID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return closed, sweep, nil
+}
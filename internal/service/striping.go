@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+// MaxWalletStripes is the largest stripe count a wallet can have.
+const MaxWalletStripes = 64
+
+// WithWalletStriping enables striped wallets. Transfers to a striped wallet
+// credit one of its stripes and lock only the sender, at the cost of an extra
+// read of the receiver before every transfer.
+func WithWalletStriping() Option {
+	return func(s *WalletService) {
+		s.striping = true
+	}
+}
+
+var errStripingDisabled = fmt.Errorf("%w: wallet striping", domain.ErrFeatureDisabled)
+
+// SetWalletStripes splits a wallet's incoming credits across stripes
+// sub-balances. The stripe count can only be raised.
+func (s *WalletService) SetWalletStripes(ctx context.Context, walletID uuid.UUID, stripes int) (*domain.Wallet, error) {
+	if !s.striping {
+		return nil, errStripingDisabled
+	}
+	if stripes < 1 || stripes > MaxWalletStripes {
+		return nil, domain.ErrInvalidStripeCount
+	}
+
+	var wallet *domain.Wallet
+
+	err := s.walletRepo.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		wallet, err = s.walletRepo.GetByIDWithLock(ctx, walletID)
+		if err != nil {
+			return err
+		}
+		if err := authorizeOwner(ctx, wallet); err != nil {
+			return err
+		}
+		if err := s.checkVersion(ctx, wallet); err != nil {
+			return err
+		}
+		if stripes < wallet.Stripes {
+			return domain.ErrInvalidStripeCount
+		}
+		return s.walletRepo.SetStripes(ctx, walletID, stripes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidateWallets(ctx, wallet)
+
+	return s.GetBalance(ctx, walletID)
+}
+
+// stripedReceiver returns the receiver of a transfer if striping is enabled
+// and the receiver is striped, and nil otherwise. It is read before the
+// transfer's database transaction: the stripe count only grows and the
+// currency never changes, so the read cannot go stale in a harmful way.
+func (s *WalletService) stripedReceiver(ctx context.Context, receiverID uuid.UUID) (*domain.Wallet, error) {
+	if !s.striping {
+		return nil, nil
+	}
+	receiver, err := s.walletRepo.GetByID(ctx, receiverID)
+	if errors.Is(err, domain.ErrWalletNotFound) {
+		// Leave the error to the locking path.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if receiver.Stripes == 0 {
+		return nil, nil
+	}
+	return receiver, nil
+}
+
+// checkAvailable returns ErrInsufficientFunds unless wallet, locked in the transaction,
+// can spend amount. A striped wallet first borrows the shortfall from its
+// stripes into the wallet row.
+func (s *WalletService) checkAvailable(ctx context.Context, wallet *domain.Wallet, amount int64) error {
+	short := amount - wallet.AvailableBalance()
+	if short <= 0 {
+		return nil
+	}
+	if wallet.Stripes == 0 {
+		return domain.ErrInsufficientFunds
+	}
+
+	moved, err := s.walletRepo.BorrowFromStripes(ctx, wallet.ID, short)
+	if err != nil {
+		return fmt.Errorf("failed to borrow from stripes of wallet %s: %w", wallet.ID, err)
+	}
+	wallet.Balance += moved
+	if moved < short {
+		return domain.ErrInsufficientFunds
+	}
+	return nil
+}
+
+// foldStripes adds the stripes of a striped wallet to its Balance and Version.
+func (s *WalletService) foldStripes(ctx context.Context, wallet *domain.Wallet) error {
+	if wallet.Stripes == 0 {
+		return nil
+	}
+	balance, version, err := s.walletRepo.SumStripes(ctx, wallet.ID)
+	if err != nil {
+		return err
+	}
+	wallet.Balance += balance
+	wallet.Version += version
+	return nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+type expectedVersionsKey struct{}
+
+// ExpectVersion returns a context under which operations that change
+// walletID fail with ErrVersionMismatch unless the wallet is at version when
+// it is locked. It carries an If-Match precondition from the handler to the
+// point where it can be checked without racing other writers.
+func ExpectVersion(ctx context.Context, walletID uuid.UUID, version int64) context.Context {
+	expected := map[uuid.UUID]int64{walletID: version}
+	for id, v := range expectedVersions(ctx) {
+		if id != walletID {
+			expected[id] = v
+		}
+	}
+	return context.WithValue(ctx, expectedVersionsKey{}, expected)
+}
+
+type payerVersionKey struct{}
+
+// ExpectPayerVersion is ExpectVersion for requests that name the wallet they
+// change only indirectly: the precondition applies to the wallet a pending
+// transfer holds funds in, or a refund pays out of, once the operation has
+// looked that wallet up.
+func ExpectPayerVersion(ctx context.Context, version int64) context.Context {
+	return context.WithValue(ctx, payerVersionKey{}, version)
+}
+
+// expectPayer turns an ExpectPayerVersion precondition into an ExpectVersion
+// precondition on payerID.
+func expectPayer(ctx context.Context, payerID uuid.UUID) context.Context {
+	if version, ok := ctx.Value(payerVersionKey{}).(int64); ok {
+		return ExpectVersion(ctx, payerID, version)
+	}
+	return ctx
+}
+
+func expectedVersions(ctx context.Context) map[uuid.UUID]int64 {
+	expected, _ := ctx.Value(expectedVersionsKey{}).(map[uuid.UUID]int64)
+	return expected
+}
+
+func hasExpectedVersions(ctx context.Context) bool {
+	return len(expectedVersions(ctx)) > 0
+}
+
+// checkVersion enforces an ExpectVersion precondition on a locked wallet. A
+// striped wallet's version includes its stripes, which are not locked.
+func (s *WalletService) checkVersion(ctx context.Context, wallet *domain.Wallet) error {
+	expected, ok := expectedVersions(ctx)[wallet.ID]
+	if !ok {
+		return nil
+	}
+
+	version := wallet.Version
+	if wallet.Stripes > 0 {
+		_, stripeVersion, err := s.walletRepo.SumStripes(ctx, wallet.ID)
+		if err != nil {
+			return err
+		}
+		version += stripeVersion
+	}
+	if version != expected {
+		return domain.ErrVersionMismatch
+	}
+	return nil
+}
+
+// invalidateWallets drops the cached copies of wallets changed by a committed
+// transaction. Each wallet is passed as it was locked, so any copy at its
+// version or older is stale.
+func (s *WalletService) invalidateWallets(ctx context.Context, wallets ...*domain.Wallet) {
+	for _, wallet := range wallets {
+		_ = s.cacheRepo.InvalidateWallet(ctx, wallet.ID, wallet.Version+1)
+	}
+}
@@ -2,40 +2,104 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"digital-wallet/internal/domain"
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
+// DefaultIdempotencyRetention is how long idempotency keys are kept when
+// WithIdempotency is given a non-positive retention.
+const DefaultIdempotencyRetention = 24 * time.Hour
+
 type WalletService struct {
-	walletRepo      domain.WalletRepository
-	transRepo       domain.TransactionRepository
-	cacheRepo       domain.CacheRepository
-	eventProducer   domain.EventProducer
+	walletRepo    domain.WalletRepository
+	transRepo     domain.TransactionRepository
+	ledgerRepo    domain.LedgerRepository
+	cacheRepo     domain.CacheRepository
+	eventProducer domain.EventProducer
+
+	idempotencyRepo      domain.IdempotencyRepository
+	idempotencyRetention time.Duration
+
+	outboxRepo domain.OutboxRepository
+
+	quoteRepo    domain.QuoteRepository
+	rateProvider domain.RateProvider
+	fx           FXConfig
+
+	pendingRepo domain.PendingTransferRepository
+	holdTTL     time.Duration
+
+	apiKeyRepo domain.APIKeyRepository
+
+	auditRepo    domain.AdminAuditRepository
+	freezePolicy FreezePolicy
+
+	striping        bool
+	balanceStrategy BalanceStrategy
+}
+
+// Option configures optional WalletService features.
+type Option func(*WalletService)
+
+// WithIdempotency enables TransferMoneyIdempotent, keeping keys for retention.
+func WithIdempotency(repo domain.IdempotencyRepository, retention time.Duration) Option {
+	return func(s *WalletService) {
+		if retention <= 0 {
+			retention = DefaultIdempotencyRetention
+		}
+		s.idempotencyRepo = repo
+		s.idempotencyRetention = retention
+	}
+}
+
+// WithOutbox writes events to the outbox inside each database transaction
+// instead of publishing them after commit. A worker.OutboxRelay must be
+// running to deliver them.
+func WithOutbox(repo domain.OutboxRepository) Option {
+	return func(s *WalletService) {
+		s.outboxRepo = repo
+	}
 }
 
 func NewWalletService(
 	wRepo domain.WalletRepository,
 	tRepo domain.TransactionRepository,
+	lRepo domain.LedgerRepository,
 	cRepo domain.CacheRepository,
 	evt domain.EventProducer,
+	opts ...Option,
 ) *WalletService {
-	return &WalletService{
+	s := &WalletService{
 		walletRepo:    wRepo,
 		transRepo:     tRepo,
+		ledgerRepo:    lRepo,
 		cacheRepo:     cRepo,
 		eventProducer: evt,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-func (s *WalletService) CreateWallet(ctx context.Context, userID uuid.UUID) (*domain.Wallet, error) {
+func (s *WalletService) CreateWallet(ctx context.Context, userID uuid.UUID, currency domain.Currency) (*domain.Wallet, error) {
+	if _, err := domain.ParseCurrency(string(currency)); err != nil {
+		return nil, err
+	}
+	if !mayActFor(ctx, userID) {
+		return nil, domain.ErrForbidden
+	}
 	wallet := &domain.Wallet{
-		UserID:  userID,
-		Balance: 0,
+		UserID:   userID,
+		Balance:  0,
+		Currency: currency,
+		Status:   domain.WalletStatusActive,
 	}
 	if err := s.walletRepo.Create(ctx, wallet); err != nil {
 		return nil, err
@@ -47,14 +111,33 @@ func (s *WalletService) GetBalance(ctx context.Context, walletID uuid.UUID) (*do
 	// 1. Check Cache
 	cached, err := s.cacheRepo.GetWallet(ctx, walletID)
 	if err == nil && cached != nil {
+		if err := authorizeOwner(ctx, cached); err != nil {
+			return nil, err
+		}
 		return cached, nil
 	}
-	
-	// 2. Fetch from DB
+
+	// 2. Fetch from DB, possibly a replica. A stale copy is harmless: the
+	// cache refuses versions older than the last invalidation.
 	wallet, err := s.walletRepo.GetByID(ctx, walletID)
 	if err != nil {
 		return nil, err
 	}
+	if err := authorizeOwner(ctx, wallet); err != nil {
+		return nil, err
+	}
+	if wallet.Stripes > 0 {
+		// Striped wallets change too often to be worth caching. The wallet
+		// row is read again from the primary so that it matches the stripes.
+		wallet, err = s.walletRepo.GetByID(domain.WithPrimaryReads(ctx), walletID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.foldStripes(ctx, wallet); err != nil {
+			return nil, err
+		}
+		return wallet, nil
+	}
 
 	// 3. Set Cache
 	if err := s.cacheRepo.SetWallet(ctx, wallet); err != nil {
@@ -64,96 +147,508 @@ func (s *WalletService) GetBalance(ctx context.Context, walletID uuid.UUID) (*do
 	return wallet, nil
 }
 
-func (s *WalletService) TransferMoney(ctx context.Context, senderID, receiverID uuid.UUID, amount int64) (*domain.Transaction, error) {
-	if amount <= 0 {
-		return nil, errors.New("amount must be positive")
+// Transaction history page sizes.
+const (
+	DefaultHistoryLimit = 20
+	MaxHistoryLimit     = 100
+)
+
+// GetTransactionHistory returns one page of a wallet's transactions, newest first.
+func (s *WalletService) GetTransactionHistory(ctx context.Context, filter domain.TransactionFilter) (*domain.TransactionPage, error) {
+	switch filter.Direction {
+	case "", domain.DirectionIncoming, domain.DirectionOutgoing:
+	default:
+		return nil, fmt.Errorf("%w: unknown direction %q", domain.ErrInvalidFilter, filter.Direction)
 	}
-	if senderID == receiverID {
-		return nil, errors.New("cannot transfer to self")
+	if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MinAmount > *filter.MaxAmount {
+		return nil, fmt.Errorf("%w: min_amount is greater than max_amount", domain.ErrInvalidFilter)
+	}
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return nil, fmt.Errorf("%w: from must be before to", domain.ErrInvalidFilter)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultHistoryLimit
+	}
+	if filter.Limit > MaxHistoryLimit {
+		filter.Limit = MaxHistoryLimit
+	}
+
+	wallet, err := s.walletRepo.GetByID(ctx, filter.WalletID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeOwner(ctx, wallet); err != nil {
+		return nil, err
+	}
+
+	// Fetch one extra row to learn whether another page exists.
+	pageSize := filter.Limit
+	filter.Limit++
+	transactions, err := s.transRepo.ListByWallet(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.TransactionPage{Transactions: transactions}
+	if len(transactions) > pageSize {
+		page.Transactions = transactions[:pageSize]
+		last := page.Transactions[pageSize-1]
+		page.NextCursor = &domain.TransactionCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+	return page, nil
+}
+
+func (s *WalletService) TransferMoney(ctx context.Context, senderID, receiverID uuid.UUID, amount domain.Money) (*domain.Transaction, error) {
+	if err := validateTransfer(senderID, receiverID, amount); err != nil {
+		return nil, err
+	}
+	if err := s.authorizeWallet(ctx, senderID); err != nil {
+		return nil, err
+	}
+
+	stripedReceiver, err := s.stripedReceiver(ctx, receiverID)
+	if err != nil {
+		return nil, err
 	}
 
 	var transaction *domain.Transaction
+	var sender, receiver *domain.Wallet
 
 	// Transactional Block
-	err := s.walletRepo.WithTx(ctx, func(tx *gorm.DB) error {
-		// Deadlock Prevention: Sort Locks
-		firstID, secondID := senderID, receiverID
-		if firstID.String() > secondID.String() {
-			firstID, secondID = receiverID, senderID
-		}
+	err = s.walletRepo.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		transaction, sender, receiver, err = s.transfer(ctx, senderID, receiverID, amount, stripedReceiver)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Post-Transaction Actions (Best Effort)
+	s.afterCommit(ctx, transaction, sender, receiver)
+
+	return transaction, nil
+}
 
-		// Lock First Wallet
-		w1, err := s.walletRepo.GetByIDWithLock(ctx, tx, firstID)
+// TransferMoneyIdempotent performs TransferMoney at most once per key and
+// caller.
+// render builds the response that is stored with the key inside the same
+// database transaction as the transfer; a retry with the same key and
+// requestHash gets the stored record back with replayed set to true.
+func (s *WalletService) TransferMoneyIdempotent(
+	ctx context.Context,
+	key, requestHash string,
+	senderID, receiverID uuid.UUID,
+	amount domain.Money,
+	render func(*domain.Transaction) (int, []byte, error),
+) (record *domain.IdempotencyKey, replayed bool, err error) {
+	if s.idempotencyRepo == nil {
+		return nil, false, fmt.Errorf("%w: idempotency keys", domain.ErrFeatureDisabled)
+	}
+
+	// Checked first so that another user cannot replay the stored response.
+	if err := s.authorizeWallet(ctx, senderID); err != nil {
+		return nil, false, err
+	}
+	scope := idempotencyScope(ctx)
+	if record, err := s.lookupIdempotencyKey(ctx, scope, key, requestHash); err != nil || record != nil {
+		return record, record != nil, err
+	}
+
+	if err := validateTransfer(senderID, receiverID, amount); err != nil {
+		return nil, false, err
+	}
+
+	stripedReceiver, err := s.stripedReceiver(ctx, receiverID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var transaction *domain.Transaction
+	var sender, receiver *domain.Wallet
+
+	err = s.walletRepo.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		transaction, sender, receiver, err = s.transfer(ctx, senderID, receiverID, amount, stripedReceiver)
 		if err != nil {
-			return fmt.Errorf("failed to lock wallet %s: %w", firstID, err)
+			return err
 		}
 
-		// Lock Second Wallet
-		w2, err := s.walletRepo.GetByIDWithLock(ctx, tx, secondID)
+		status, body, err := render(transaction)
 		if err != nil {
-			return fmt.Errorf("failed to lock wallet %s: %w", secondID, err)
+			return err
+		}
+
+		record = &domain.IdempotencyKey{
+			Scope:          scope,
+			Key:            key,
+			RequestHash:    requestHash,
+			TransactionID:  transaction.ID,
+			ResponseStatus: status,
+			ResponseBody:   body,
+			ExpiresAt:      time.Now().Add(s.idempotencyRetention),
+		}
+		return s.idempotencyRepo.Create(ctx, record)
+	})
+
+	if errors.Is(err, domain.ErrIdempotencyKeyInUse) {
+		// A concurrent request with the same key committed first.
+		existing, lookupErr := s.lookupIdempotencyKey(ctx, scope, key, requestHash)
+		if lookupErr != nil || existing == nil {
+			return nil, false, err
+		}
+		return existing, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	s.afterCommit(ctx, transaction, sender, receiver)
+
+	return record, false, nil
+}
+
+// PurgeExpiredIdempotencyKeys deletes keys past their retention window.
+func (s *WalletService) PurgeExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	if s.idempotencyRepo == nil {
+		return 0, nil
+	}
+	return s.idempotencyRepo.DeleteExpired(ctx, time.Now())
+}
+
+// idempotencyScope names the caller an idempotency key belongs to, the API
+// key or else the user, so that two callers picking the same key do not see
+// each other's responses. Unauthenticated requests share one scope.
+func idempotencyScope(ctx context.Context) string {
+	if key := domain.APIKeyFromContext(ctx); key != nil {
+		return "api_key:" + key.ID.String()
+	}
+	if caller, ok := domain.CallerFromContext(ctx); ok {
+		return "user:" + caller.String()
+	}
+	return ""
+}
+
+func (s *WalletService) lookupIdempotencyKey(ctx context.Context, scope, key, requestHash string) (*domain.IdempotencyKey, error) {
+	record, err := s.idempotencyRepo.Get(ctx, scope, key)
+	if err != nil || record == nil {
+		return nil, err
+	}
+	if record.RequestHash != requestHash {
+		return nil, domain.ErrIdempotencyKeyReused
+	}
+	return record, nil
+}
+
+func validateTransfer(senderID, receiverID uuid.UUID, amount domain.Money) error {
+	if amount.Amount <= 0 {
+		return domain.ErrInvalidAmount
+	}
+	if _, err := domain.ParseCurrency(string(amount.Currency)); err != nil {
+		return err
+	}
+	if senderID == receiverID {
+		return domain.ErrSelfTransfer
+	}
+	return nil
+}
+
+// lockPair locks two distinct wallets in deterministic order and returns
+// them as sender and receiver.
+func (s *WalletService) lockPair(ctx context.Context, senderID, receiverID uuid.UUID) (sender, receiver *domain.Wallet, err error) {
+	// Deadlock Prevention: Sort Locks
+	firstID, secondID := senderID, receiverID
+	if firstID.String() > secondID.String() {
+		firstID, secondID = receiverID, senderID
+	}
+
+	// Lock First Wallet
+	w1, err := s.walletRepo.GetByIDWithLock(ctx, firstID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lock wallet %s: %w", firstID, err)
+	}
+
+	// Lock Second Wallet
+	w2, err := s.walletRepo.GetByIDWithLock(ctx, secondID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lock wallet %s: %w", secondID, err)
+	}
+
+	if err := s.checkVersion(ctx, w1); err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkVersion(ctx, w2); err != nil {
+		return nil, nil, err
+	}
+
+	// Identify Sender and Receiver
+	if w1.ID == senderID {
+		return w1, w2, nil
+	}
+	return w2, w1, nil
+}
+
+// transfer moves amount between two wallets of the same currency inside the
+// transaction carried by ctx and returns the wallets as they were locked.
+// stripedReceiver is the receiver as returned by s.stripedReceiver.
+func (s *WalletService) transfer(ctx context.Context, senderID, receiverID uuid.UUID, amount domain.Money, stripedReceiver *domain.Wallet) (transaction *domain.Transaction, sender, receiver *domain.Wallet, err error) {
+	// Version preconditions are checked on the locking path.
+	if s.balanceStrategy == BalanceStrategyConditional && stripedReceiver == nil && !hasExpectedVersions(ctx) {
+		transaction, sender, receiver, err = s.transferConditional(ctx, senderID, receiverID, amount)
+		// Nothing was written if the guard failed, so a striped sender can
+		// still borrow from its stripes on the locking path below.
+		if !s.striping || !errors.Is(err, domain.ErrInsufficientFunds) {
+			return transaction, sender, receiver, err
 		}
+	}
 
-		// Identify Sender and Receiver
-		var sender, receiver *domain.Wallet
-		if w1.ID == senderID {
-			sender = w1
-			receiver = w2
-		} else {
-			sender = w2
-			receiver = w1
+	var striped map[uuid.UUID]int
+	if stripedReceiver != nil {
+		// The credit lands on a stripe, so only the sender is locked.
+		receiver = stripedReceiver
+		striped = map[uuid.UUID]int{receiver.ID: receiver.Stripes}
+		sender, err = s.walletRepo.GetByIDWithLock(ctx, senderID)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to lock wallet %s: %w", senderID, err)
+		}
+		if err := s.checkVersion(ctx, sender); err != nil {
+			return nil, nil, nil, err
+		}
+	} else {
+		sender, receiver, err = s.lockPair(ctx, senderID, receiverID)
+		if err != nil {
+			return nil, nil, nil, err
 		}
+	}
 
-		// Logic Check
-		if sender.Balance < amount {
-			return errors.New("insufficient funds")
+	// Logic Check
+	if sender.Currency != amount.Currency || receiver.Currency != amount.Currency {
+		return nil, nil, nil, domain.ErrCurrencyMismatch
+	}
+	if err := checkDebitable(sender); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := s.checkCreditable(receiver); err != nil {
+		return nil, nil, nil, err
+	}
+	if err := s.checkAvailable(ctx, sender, amount.Amount); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Post Transaction & Update Balances
+	transaction = &domain.Transaction{
+		SenderID:   &sender.ID,
+		ReceiverID: &receiver.ID,
+		Amount:     amount.Amount,
+		Currency:   amount.Currency,
+		Type:       domain.TransactionTypeTransfer,
+	}
+	err = s.postTransactionStriped(ctx, transaction, striped,
+		domain.Debit(domain.WalletAccount(sender.ID), amount),
+		domain.Credit(domain.WalletAccount(receiver.ID), amount),
+	)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if stripedReceiver != nil {
+		if err := s.recheckStripedCredit(ctx, receiver.ID); err != nil {
+			return nil, nil, nil, err
 		}
+	}
+
+	return transaction, sender, receiver, nil
+}
 
-		// Update Balances
-		newSenderBal := sender.Balance - amount
-		newReceiverBal := receiver.Balance + amount
+func (s *WalletService) Deposit(ctx context.Context, walletID uuid.UUID, amount domain.Money) (*domain.Transaction, error) {
+	if amount.Amount <= 0 {
+		return nil, domain.ErrInvalidAmount
+	}
+
+	var transaction *domain.Transaction
+	var wallet *domain.Wallet
 
-		if err := s.walletRepo.UpdateBalance(ctx, tx, sender.ID, newSenderBal); err != nil {
+	err := s.walletRepo.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		wallet, err = s.walletRepo.GetByIDWithLock(ctx, walletID)
+		if err != nil {
+			return fmt.Errorf("failed to lock wallet %s: %w", walletID, err)
+		}
+		if err := authorizeOwner(ctx, wallet); err != nil {
 			return err
 		}
-		if err := s.walletRepo.UpdateBalance(ctx, tx, receiver.ID, newReceiverBal); err != nil {
+		if err := s.checkVersion(ctx, wallet); err != nil {
+			return err
+		}
+
+		if wallet.Currency != amount.Currency {
+			return domain.ErrCurrencyMismatch
+		}
+		if err := s.checkCreditable(wallet); err != nil {
 			return err
 		}
 
-		// Create Transaction Log
 		transaction = &domain.Transaction{
-			SenderID:   &sender.ID,
-			ReceiverID: &receiver.ID,
-			Amount:     amount,
-			Type:       "TRANSFER",
+			ReceiverID: &wallet.ID,
+			Amount:     amount.Amount,
+			Currency:   amount.Currency,
+			Type:       domain.TransactionTypeDeposit,
+		}
+		return s.postTransaction(ctx, transaction,
+			domain.Debit(domain.HouseAccountCash, amount),
+			domain.Credit(domain.WalletAccount(wallet.ID), amount),
+		)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.afterCommit(ctx, transaction, wallet)
+
+	return transaction, nil
+}
+
+func (s *WalletService) Withdraw(ctx context.Context, walletID uuid.UUID, amount domain.Money) (*domain.Transaction, error) {
+	if amount.Amount <= 0 {
+		return nil, domain.ErrInvalidAmount
+	}
+
+	var transaction *domain.Transaction
+	var wallet *domain.Wallet
+
+	err := s.walletRepo.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		wallet, err = s.walletRepo.GetByIDWithLock(ctx, walletID)
+		if err != nil {
+			return fmt.Errorf("failed to lock wallet %s: %w", walletID, err)
+		}
+		if err := authorizeOwner(ctx, wallet); err != nil {
+			return err
 		}
-		if err := s.transRepo.Create(ctx, tx, transaction); err != nil {
+		if err := s.checkVersion(ctx, wallet); err != nil {
 			return err
 		}
 
-		return nil
+		if wallet.Currency != amount.Currency {
+			return domain.ErrCurrencyMismatch
+		}
+		if err := checkDebitable(wallet); err != nil {
+			return err
+		}
+		if err := s.checkAvailable(ctx, wallet, amount.Amount); err != nil {
+			return err
+		}
+
+		transaction = &domain.Transaction{
+			SenderID: &wallet.ID,
+			Amount:   amount.Amount,
+			Currency: amount.Currency,
+			Type:     domain.TransactionTypeWithdrawal,
+		}
+		return s.postTransaction(ctx, transaction,
+			domain.Debit(domain.WalletAccount(wallet.ID), amount),
+			domain.Credit(domain.HouseAccountCash, amount),
+		)
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Post-Transaction Actions (Best Effort)
-	
+	s.afterCommit(ctx, transaction, wallet)
+
+	return transaction, nil
+}
+
+// recordTransaction writes the ledger row and, when the outbox is enabled,
+// its event in the same database transaction.
+func (s *WalletService) recordTransaction(ctx context.Context, transaction *domain.Transaction) error {
+	if err := s.transRepo.Create(ctx, transaction); err != nil {
+		return err
+	}
+	if s.outboxRepo == nil {
+		return nil
+	}
+
+	eventType, event := transactionEvent(transaction)
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.outboxRepo.Enqueue(ctx, &domain.OutboxEvent{
+		EventType:  eventType,
+		SenderID:   transaction.SenderID,
+		ReceiverID: transaction.ReceiverID,
+		Payload:    payload,
+	})
+}
+
+// afterCommit invalidates the cached wallets touched by a committed
+// transaction, passed as they were locked. Without an outbox it also
+// publishes the event directly, which is best effort: the event is lost if
+// the broker is unavailable.
+func (s *WalletService) afterCommit(ctx context.Context, transaction *domain.Transaction, wallets ...*domain.Wallet) {
 	// Invalidate Cache
-	_ = s.cacheRepo.InvalidateWallet(ctx, senderID)
-	_ = s.cacheRepo.InvalidateWallet(ctx, receiverID)
+	s.invalidateWallets(ctx, wallets...)
+
+	if s.outboxRepo != nil {
+		return
+	}
 
 	// Publish Event
+	var err error
+	_, event := transactionEvent(transaction)
+	switch event := event.(type) {
+	case domain.RefundEvent:
+		err = s.eventProducer.PublishRefundEvent(ctx, event)
+	case domain.TransferEvent:
+		err = s.eventProducer.PublishTransferEvent(ctx, event)
+	}
+	if err != nil {
+		log.Printf("failed to publish %s event: %v", transaction.Type, err)
+	}
+}
+
+// transactionEvent returns the event type and payload published for a
+// committed transaction: a RefundEvent for refunds, a TransferEvent otherwise.
+func transactionEvent(transaction *domain.Transaction) (string, interface{}) {
+	if transaction.Type == domain.TransactionTypeRefund {
+		return domain.EventTypeRefund, refundEvent(transaction)
+	}
+	return domain.EventTypeTransfer, transferEvent(transaction)
+}
+
+func refundEvent(transaction *domain.Transaction) domain.RefundEvent {
+	event := domain.RefundEvent{
+		RefundID: transaction.ID,
+		Amount:   transaction.Amount,
+		Currency: transaction.Currency,
+	}
+	if transaction.OriginalTransactionID != nil {
+		event.OriginalTransactionID = *transaction.OriginalTransactionID
+	}
+	if transaction.SenderID != nil {
+		event.SenderID = *transaction.SenderID
+	}
+	if transaction.ReceiverID != nil {
+		event.ReceiverID = *transaction.ReceiverID
+	}
+	return event
+}
+
+func transferEvent(transaction *domain.Transaction) domain.TransferEvent {
 	event := domain.TransferEvent{
 		TransactionID: transaction.ID,
-		SenderID:      senderID,
-		ReceiverID:    receiverID,
-		Amount:        amount,
+		Type:          transaction.Type,
+		Amount:        transaction.Amount,
+		Currency:      transaction.Currency,
 	}
-	if err := s.eventProducer.PublishTransferEvent(ctx, event); err != nil {
-		log.Printf("failed to publish transfer event: %v", err)
+	if transaction.SenderID != nil {
+		event.SenderID = *transaction.SenderID
 	}
-
-	return transaction, nil
+	if transaction.ReceiverID != nil {
+		event.ReceiverID = *transaction.ReceiverID
+	}
+	return event
 }
@@ -0,0 +1,136 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultOutboxBatchSize = 100
+	outboxPublishTimeout   = 5 * time.Second
+	outboxMaxBackoff       = 5 * time.Minute
+)
+
+// OutboxRelay publishes committed outbox events to the EventProducer.
+//
+// Events are published in ID order. When an event for a wallet fails or is
+// waiting for its retry, later events touching the same wallet are held back
+// so consumers always see a wallet's events in commit order. An event is
+// marked as dispatched only after the broker confirms it; a crash in between
+// publishes it again, so consumers should de-duplicate on the
+// transaction or refund ID.
+type OutboxRelay struct {
+	repo      domain.OutboxRepository
+	producer  domain.EventProducer
+	batchSize int
+}
+
+func NewOutboxRelay(repo domain.OutboxRepository, producer domain.EventProducer) *OutboxRelay {
+	return &OutboxRelay{repo: repo, producer: producer, batchSize: defaultOutboxBatchSize}
+}
+
+// Start drains the outbox every interval until ctx is cancelled.
+func (r *OutboxRelay) Start(ctx context.Context, interval time.Duration) {
+	RunPeriodic(ctx, "Outbox relay", interval, func(ctx context.Context) error {
+		_, err := r.Dispatch(ctx)
+		return err
+	})
+}
+
+// Dispatch publishes one batch of due events and returns how many were
+// delivered. It does nothing if another relay currently holds the outbox.
+func (r *OutboxRelay) Dispatch(ctx context.Context) (int, error) {
+	dispatched := 0
+
+	_, err := r.repo.WithRelayLock(ctx, func(ctx context.Context) error {
+		events, err := r.repo.ListPending(ctx, r.batchSize)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		blocked := make(map[uuid.UUID]bool)
+		block := func(e *domain.OutboxEvent) {
+			for _, id := range e.WalletIDs() {
+				blocked[id] = true
+			}
+		}
+
+		for i := range events {
+			event := &events[i]
+
+			isBlocked := false
+			for _, id := range event.WalletIDs() {
+				isBlocked = isBlocked || blocked[id]
+			}
+			if isBlocked || event.NextAttemptAt.After(now) {
+				block(event)
+				continue
+			}
+
+			if err := r.publish(ctx, event); err != nil {
+				block(event)
+				next := time.Now().Add(outboxBackoff(event.Attempts + 1))
+				log.Printf("Outbox event %d failed (attempt %d), retrying at %s: %v", event.ID, event.Attempts+1, next.Format(time.RFC3339), err)
+				if err := r.repo.MarkFailed(ctx, event.ID, err.Error(), next); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := r.repo.MarkDispatched(ctx, event.ID, time.Now()); err != nil {
+				return err
+			}
+			dispatched++
+		}
+		return nil
+	})
+
+	return dispatched, err
+}
+
+func (r *OutboxRelay) publish(ctx context.Context, event *domain.OutboxEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, outboxPublishTimeout)
+	defer cancel()
+
+	switch event.EventType {
+	case domain.EventTypeTransfer:
+		var payload domain.TransferEvent
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("decode payload: %w", err)
+		}
+		return r.producer.PublishTransferEvent(ctx, payload)
+	case domain.EventTypeRefund:
+		var payload domain.RefundEvent
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("decode payload: %w", err)
+		}
+		return r.producer.PublishRefundEvent(ctx, payload)
+	case domain.EventTypeWalletStatus:
+		var payload domain.WalletStatusEvent
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("decode payload: %w", err)
+		}
+		return r.producer.PublishWalletStatusEvent(ctx, payload)
+	default:
+		return fmt.Errorf("unknown event type %q", event.EventType)
+	}
+}
+
+// outboxBackoff doubles the retry delay per attempt, starting at one second.
+func outboxBackoff(attempts int) time.Duration {
+	if attempts > 9 {
+		return outboxMaxBackoff
+	}
+	d := time.Second << attempts
+	if d > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+	return d
+}
@@ -0,0 +1,29 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunPeriodic calls fn every interval until ctx is cancelled. Errors are
+// logged and do not stop the loop.
+func RunPeriodic(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				log.Printf("%s stopped", name)
+				return
+			case <-ticker.C:
+				if err := fn(ctx); err != nil {
+					log.Printf("%s failed: %v", name, err)
+				}
+			}
+		}
+	}()
+	log.Printf("%s started (every %s)", name, interval)
+}
@@ -25,14 +25,32 @@ func (w *Worker) Start() {
 
 	go func() {
 		for d := range msgs {
-			var event domain.TransferEvent
-			if err := json.Unmarshal(d.Body, &event); err != nil {
-				log.Printf("Error decoding event: %v", err)
-				d.Nack(false, false) // discard
-				continue
+			switch d.Type {
+			case domain.EventTypeRefund:
+				var event domain.RefundEvent
+				if err := json.Unmarshal(d.Body, &event); err != nil {
+					log.Printf("Error decoding refund event: %v", err)
+					d.Nack(false, false) // discard
+					continue
+				}
+				w.processRefund(event)
+			case domain.EventTypeWalletStatus:
+				var event domain.WalletStatusEvent
+				if err := json.Unmarshal(d.Body, &event); err != nil {
+					log.Printf("Error decoding wallet status event: %v", err)
+					d.Nack(false, false) // discard
+					continue
+				}
+				w.processWalletStatus(event)
+			default: // Transfer events, including those published before message types existed
+				var event domain.TransferEvent
+				if err := json.Unmarshal(d.Body, &event); err != nil {
+					log.Printf("Error decoding event: %v", err)
+					d.Nack(false, false) // discard
+					continue
+				}
+				w.processTransfer(event)
 			}
-
-			w.processTransfer(event)
 			d.Ack(false)
 		}
 	}()
@@ -45,3 +63,17 @@ func (w *Worker) processTransfer(event domain.TransferEvent) {
 	time.Sleep(2 * time.Second)
 	log.Printf("Email Sent for transaction %s", event.TransactionID)
 }
+
+func (w *Worker) processRefund(event domain.RefundEvent) {
+	// Simulate Email Sending
+	log.Printf("Processing refund event: %s for transaction %s. Sending email...", event.RefundID, event.OriginalTransactionID)
+	time.Sleep(2 * time.Second)
+	log.Printf("Email Sent for refund %s", event.RefundID)
+}
+
+func (w *Worker) processWalletStatus(event domain.WalletStatusEvent) {
+	// Simulate Email Sending
+	log.Printf("Processing wallet status event: wallet %s is now %s. Sending email...", event.WalletID, event.To)
+	time.Sleep(2 * time.Second)
+	log.Printf("Email Sent for wallet %s", event.WalletID)
+}
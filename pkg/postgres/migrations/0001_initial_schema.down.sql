@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS outbox_events;
+DROP TABLE IF EXISTS idempotency_keys;
+DROP TABLE IF EXISTS fx_quotes;
+DROP TABLE IF EXISTS pending_transfers;
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS wallet_stripes;
+DROP TABLE IF EXISTS wallets;
@@ -0,0 +1,117 @@
+-- The schema previously created by GORM's AutoMigrate. Every statement is
+-- idempotent so that databases created by AutoMigrate can adopt migrations.
+
+CREATE TABLE IF NOT EXISTS wallets (
+	id           uuid        NOT NULL DEFAULT gen_random_uuid(),
+	user_id      uuid        NOT NULL,
+	balance      bigint      NOT NULL DEFAULT 0,
+	held_balance bigint      NOT NULL DEFAULT 0,
+	currency     char(3)     NOT NULL DEFAULT 'USD',
+	stripes      bigint      NOT NULL DEFAULT 0,
+	version      bigint      NOT NULL DEFAULT 0,
+	created_at   timestamptz,
+	updated_at   timestamptz,
+	PRIMARY KEY (id),
+	CONSTRAINT chk_wallets_balance CHECK (balance >= 0),
+	CONSTRAINT chk_wallets_held_balance CHECK (held_balance >= 0 AND held_balance <= balance)
+);
+
+CREATE TABLE IF NOT EXISTS wallet_stripes (
+	wallet_id uuid   NOT NULL,
+	stripe    bigint NOT NULL,
+	balance   bigint NOT NULL DEFAULT 0,
+	version   bigint NOT NULL DEFAULT 0,
+	PRIMARY KEY (wallet_id, stripe),
+	CONSTRAINT chk_wallet_stripes_balance CHECK (balance >= 0)
+);
+
+CREATE TABLE IF NOT EXISTS transactions (
+	id                      uuid        NOT NULL DEFAULT gen_random_uuid(),
+	sender_id               uuid,
+	receiver_id             uuid,
+	amount                  bigint      NOT NULL,
+	currency                char(3)     NOT NULL DEFAULT 'USD',
+	type                    text        NOT NULL,
+	quote_id                uuid,
+	refunded_amount         bigint      NOT NULL DEFAULT 0,
+	original_transaction_id uuid,
+	created_at              timestamptz,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_transactions_sender_created ON transactions (sender_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_transactions_receiver_created ON transactions (receiver_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_transactions_original_transaction_id ON transactions (original_transaction_id);
+
+CREATE TABLE IF NOT EXISTS ledger_entries (
+	id             uuid        NOT NULL DEFAULT gen_random_uuid(),
+	transaction_id uuid        NOT NULL,
+	account        text        NOT NULL,
+	amount         bigint      NOT NULL,
+	currency       char(3)     NOT NULL,
+	created_at     timestamptz,
+	PRIMARY KEY (id),
+	CONSTRAINT chk_ledger_entries_amount CHECK (amount <> 0)
+);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_transaction_id ON ledger_entries (transaction_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account ON ledger_entries (account);
+
+CREATE TABLE IF NOT EXISTS pending_transfers (
+	id              uuid        NOT NULL DEFAULT gen_random_uuid(),
+	sender_id       uuid        NOT NULL,
+	receiver_id     uuid        NOT NULL,
+	amount          bigint      NOT NULL,
+	currency        char(3)     NOT NULL,
+	captured_amount bigint      NOT NULL DEFAULT 0,
+	status          text        NOT NULL,
+	transaction_id  uuid,
+	expires_at      timestamptz NOT NULL,
+	created_at      timestamptz,
+	updated_at      timestamptz,
+	PRIMARY KEY (id),
+	CONSTRAINT chk_pending_transfers_amount CHECK (amount > 0)
+);
+CREATE INDEX IF NOT EXISTS idx_pending_transfers_sender_id ON pending_transfers (sender_id);
+CREATE INDEX IF NOT EXISTS idx_pending_transfers_status_expires ON pending_transfers (status, expires_at);
+
+CREATE TABLE IF NOT EXISTS fx_quotes (
+	id              uuid           NOT NULL DEFAULT gen_random_uuid(),
+	sender_id       uuid           NOT NULL,
+	source_currency char(3)        NOT NULL,
+	target_currency char(3)        NOT NULL,
+	source_amount   bigint         NOT NULL,
+	fee             bigint         NOT NULL,
+	rate            numeric(30,12) NOT NULL,
+	target_amount   bigint         NOT NULL,
+	expires_at      timestamptz    NOT NULL,
+	transaction_id  uuid,
+	created_at      timestamptz,
+	PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	scope           text        NOT NULL,
+	key             text        NOT NULL,
+	request_hash    text        NOT NULL,
+	transaction_id  uuid        NOT NULL,
+	response_status bigint      NOT NULL,
+	response_body   bytea       NOT NULL,
+	created_at      timestamptz,
+	expires_at      timestamptz NOT NULL,
+	PRIMARY KEY (scope, key)
+);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+	id              bigserial   NOT NULL,
+	event_type      text        NOT NULL,
+	sender_id       uuid,
+	receiver_id     uuid,
+	payload         jsonb       NOT NULL,
+	attempts        bigint      NOT NULL DEFAULT 0,
+	last_error      text,
+	next_attempt_at timestamptz NOT NULL,
+	dispatched_at   timestamptz,
+	created_at      timestamptz,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events (id) WHERE dispatched_at IS NULL;
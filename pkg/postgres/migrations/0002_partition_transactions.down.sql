@@ -0,0 +1,31 @@
+-- Folds the attached partitions back into a plain table. Detached and
+-- archived partitions are not restored.
+
+ALTER TABLE transactions RENAME TO transactions_partitioned;
+ALTER INDEX transactions_pkey RENAME TO transactions_partitioned_pkey;
+DROP INDEX IF EXISTS idx_transactions_sender_created;
+DROP INDEX IF EXISTS idx_transactions_receiver_created;
+DROP INDEX IF EXISTS idx_transactions_original_transaction_id;
+
+CREATE TABLE transactions (
+	id                      uuid        NOT NULL DEFAULT gen_random_uuid(),
+	sender_id               uuid,
+	receiver_id             uuid,
+	amount                  bigint      NOT NULL,
+	currency                char(3)     NOT NULL DEFAULT 'USD',
+	type                    text        NOT NULL,
+	quote_id                uuid,
+	refunded_amount         bigint      NOT NULL DEFAULT 0,
+	original_transaction_id uuid,
+	created_at              timestamptz,
+	PRIMARY KEY (id)
+);
+CREATE INDEX idx_transactions_sender_created ON transactions (sender_id, created_at);
+CREATE INDEX idx_transactions_receiver_created ON transactions (receiver_id, created_at);
+CREATE INDEX idx_transactions_original_transaction_id ON transactions (original_transaction_id);
+
+-- Columns are named: tables created by AutoMigrate order them differently.
+INSERT INTO transactions (id, sender_id, receiver_id, amount, currency, type, quote_id, refunded_amount, original_transaction_id, created_at)
+SELECT id, sender_id, receiver_id, amount, currency, type, quote_id, refunded_amount, original_transaction_id, created_at
+FROM transactions_partitioned;
+DROP TABLE transactions_partitioned;
@@ -0,0 +1,50 @@
+-- Range-partition transactions by month of created_at. The primary key has
+-- to include the partition key, so it becomes (id, created_at). Partitions
+-- covering the existing rows and the next three months are created here;
+-- PartitionManager keeps creating them ahead of time from then on. Rows
+-- outside every partition land in transactions_default.
+
+ALTER TABLE transactions RENAME TO transactions_unpartitioned;
+ALTER TABLE transactions_unpartitioned RENAME CONSTRAINT transactions_pkey TO transactions_unpartitioned_pkey;
+DROP INDEX IF EXISTS idx_transactions_sender_created;
+DROP INDEX IF EXISTS idx_transactions_receiver_created;
+DROP INDEX IF EXISTS idx_transactions_original_transaction_id;
+
+UPDATE transactions_unpartitioned SET created_at = now() WHERE created_at IS NULL;
+
+CREATE TABLE transactions (
+	id                      uuid        NOT NULL DEFAULT gen_random_uuid(),
+	sender_id               uuid,
+	receiver_id             uuid,
+	amount                  bigint      NOT NULL,
+	currency                char(3)     NOT NULL DEFAULT 'USD',
+	type                    text        NOT NULL,
+	quote_id                uuid,
+	refunded_amount         bigint      NOT NULL DEFAULT 0,
+	original_transaction_id uuid,
+	created_at              timestamptz NOT NULL DEFAULT now(),
+	PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+CREATE INDEX idx_transactions_sender_created ON transactions (sender_id, created_at);
+CREATE INDEX idx_transactions_receiver_created ON transactions (receiver_id, created_at);
+CREATE INDEX idx_transactions_original_transaction_id ON transactions (original_transaction_id);
+
+CREATE TABLE transactions_default PARTITION OF transactions DEFAULT;
+
+DO $$
+DECLARE
+	lower_bound timestamptz := date_trunc('month', COALESCE((SELECT min(created_at) FROM transactions_unpartitioned), now()), 'UTC');
+	last_bound  timestamptz := date_trunc('month', now(), 'UTC') + interval '3 months';
+BEGIN
+	WHILE lower_bound <= last_bound LOOP
+		EXECUTE format('CREATE TABLE %I PARTITION OF transactions FOR VALUES FROM (%L) TO (%L)',
+			'transactions_p' || to_char(lower_bound AT TIME ZONE 'UTC', 'YYYY_MM'), lower_bound, lower_bound + interval '1 month');
+		lower_bound := lower_bound + interval '1 month';
+	END LOOP;
+END $$;
+
+-- Columns are named: tables created by AutoMigrate order them differently.
+INSERT INTO transactions (id, sender_id, receiver_id, amount, currency, type, quote_id, refunded_amount, original_transaction_id, created_at)
+SELECT id, sender_id, receiver_id, amount, currency, type, quote_id, refunded_amount, original_transaction_id, created_at
+FROM transactions_unpartitioned;
+DROP TABLE transactions_unpartitioned;
@@ -0,0 +1,18 @@
+-- API keys for server-to-server calls, see domain.APIKey. Only the SHA-256
+-- hash of each key is stored.
+
+CREATE TABLE api_keys (
+	id           uuid        NOT NULL DEFAULT gen_random_uuid(),
+	name         text        NOT NULL,
+	prefix       text        NOT NULL,
+	key_hash     text        NOT NULL,
+	scopes       jsonb       NOT NULL,
+	owner_id     uuid,
+	rate_limit   bigint      NOT NULL DEFAULT 0,
+	expires_at   timestamptz,
+	revoked_at   timestamptz,
+	rotated_from uuid,
+	created_at   timestamptz,
+	PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_api_keys_key_hash ON api_keys (key_hash);
@@ -0,0 +1,5 @@
+-- Every API key gets its own request signing secret, see
+-- handler.SignatureVerifier. Keys issued before have none and must be
+-- rotated to sign requests.
+
+ALTER TABLE api_keys ADD COLUMN signing_secret text NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS admin_audit_entries;
+DROP FUNCTION IF EXISTS admin_audit_entries_append_only();
+ALTER TABLE api_keys DROP COLUMN IF EXISTS admin_role;
+DROP INDEX IF EXISTS idx_wallets_user_id;
+ALTER TABLE wallets DROP COLUMN IF EXISTS status;
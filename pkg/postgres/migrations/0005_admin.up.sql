@@ -0,0 +1,38 @@
+-- Wallet status, the staff role of admin API keys and the admin audit log,
+-- see domain.AdminAuditEntry. The audit log is append-only: a trigger rejects
+-- every update, delete and truncate, whichever role issues it. Existing API
+-- keys get no admin role and lose the /admin wallet routes until they are
+-- reissued with one.
+
+ALTER TABLE wallets ADD COLUMN status text NOT NULL DEFAULT 'active';
+CREATE INDEX idx_wallets_user_id ON wallets (user_id);
+ALTER TABLE api_keys ADD COLUMN admin_role text NOT NULL DEFAULT '';
+
+CREATE TABLE admin_audit_entries (
+	id             bigserial   NOT NULL,
+	actor          text        NOT NULL,
+	role           text        NOT NULL,
+	action         text        NOT NULL,
+	wallet_id      uuid,
+	user_id        uuid,
+	transaction_id uuid,
+	reason_code    text        NOT NULL DEFAULT '',
+	note           text        NOT NULL DEFAULT '',
+	created_at     timestamptz,
+	PRIMARY KEY (id)
+);
+CREATE INDEX idx_admin_audit_entries_wallet_id ON admin_audit_entries (wallet_id);
+
+CREATE FUNCTION admin_audit_entries_append_only() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+	RAISE EXCEPTION 'admin_audit_entries is append-only';
+END
+$$;
+
+CREATE TRIGGER admin_audit_entries_no_update
+	BEFORE UPDATE OR DELETE ON admin_audit_entries
+	FOR EACH ROW EXECUTE FUNCTION admin_audit_entries_append_only();
+CREATE TRIGGER admin_audit_entries_no_truncate
+	BEFORE TRUNCATE ON admin_audit_entries
+	FOR EACH STATEMENT EXECUTE FUNCTION admin_audit_entries_append_only();
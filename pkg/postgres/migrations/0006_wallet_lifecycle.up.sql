@@ -0,0 +1,5 @@
+-- Wallet lifecycle: statuses are limited to those of domain.WalletStatus.
+-- Closed is terminal; the service enforces the allowed transitions.
+
+ALTER TABLE wallets ADD CONSTRAINT chk_wallets_status
+	CHECK (status IN ('active', 'frozen', 'closed'));
@@ -0,0 +1,367 @@
+package postgres
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// partitionLockKey is the advisory lock held during partition maintenance so
+// that only one instance changes partitions at a time.
+const partitionLockKey = 7_340_003
+
+const (
+	partitionedTable = "transactions"
+	defaultPartition = "transactions_default"
+	partitionPrefix  = "transactions_p"
+	partitionLayout  = "2006_01" // Month suffix of a partition name
+)
+
+// Default PartitionConfig values.
+const (
+	DefaultPartitionMonthsAhead = 3
+	DefaultArchiveDir           = "archive"
+	DefaultPartitionLockTimeout = 5 * time.Second
+)
+
+// PartitionConfig controls PartitionManager.
+type PartitionConfig struct {
+	MonthsAhead     int           // Future monthly partitions kept ready
+	RetentionMonths int           // Past months kept attached besides the current one, 0 keeps everything
+	ArchiveDir      string        // Where detached partitions are exported
+	LockTimeout     time.Duration // How long attaching or detaching a partition waits for its locks
+}
+
+// PartitionManager maintains the monthly partitions of the transactions table
+// created by migration 0002. Partitions are named transactions_pYYYY_MM and
+// cover one calendar month in UTC.
+//
+// Detached partitions drop out of every query, so history older than the
+// retention and refunds of archived transactions are no longer served.
+//
+// Attaching and detaching a partition lock the transactions table; see
+// detachPartition. Exporting and dropping a detached partition do not.
+type PartitionManager struct {
+	db  *sql.DB
+	cfg PartitionConfig
+}
+
+// NewPartitionManager returns a PartitionManager, filling in defaults for
+// unset fields of cfg.
+func NewPartitionManager(db *sql.DB, cfg PartitionConfig) *PartitionManager {
+	if cfg.MonthsAhead <= 0 {
+		cfg.MonthsAhead = DefaultPartitionMonthsAhead
+	}
+	if cfg.ArchiveDir == "" {
+		cfg.ArchiveDir = DefaultArchiveDir
+	}
+	if cfg.LockTimeout < time.Millisecond {
+		cfg.LockTimeout = DefaultPartitionLockTimeout
+	}
+	return &PartitionManager{db: db, cfg: cfg}
+}
+
+// Maintain creates the partitions for the current month and the MonthsAhead
+// following it, then detaches the partitions that fell out of the retention.
+// It returns the names of the partitions it created and detached, and does
+// nothing if another instance is maintaining partitions.
+func (m *PartitionManager) Maintain(ctx context.Context, now time.Time) (created, detached []string, err error) {
+	err = m.locked(ctx, func(conn *sql.Conn) error {
+		attached, err := attachedPartitions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		current := monthStart(now)
+		for i := 0; i <= m.cfg.MonthsAhead; i++ {
+			month := current.AddDate(0, i, 0)
+			if _, ok := attached[month]; ok {
+				continue
+			}
+			name, err := createPartition(ctx, conn, month, m.cfg.LockTimeout)
+			if err != nil {
+				return fmt.Errorf("failed to create partition %s: %w", name, err)
+			}
+			created = append(created, name)
+		}
+
+		if m.cfg.RetentionMonths <= 0 {
+			return nil
+		}
+		cutoff := current.AddDate(0, -m.cfg.RetentionMonths, 0)
+		for _, month := range sortedMonths(attached) {
+			if !month.Before(cutoff) {
+				break
+			}
+			name := attached[month]
+			if err := detachPartition(ctx, conn, name, m.cfg.LockTimeout); err != nil {
+				return fmt.Errorf("failed to detach partition %s: %w", name, err)
+			}
+			detached = append(detached, name)
+		}
+		return nil
+	})
+	return created, detached, err
+}
+
+// Archive exports every detached partition to a gzipped CSV file named after
+// it in ArchiveDir and then drops it. A partition is only dropped once its
+// file has been synced to disk. It returns the paths of the files written,
+// and does nothing if another instance is maintaining partitions.
+func (m *PartitionManager) Archive(ctx context.Context) ([]string, error) {
+	var files []string
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		names, err := detachedPartitions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			return nil
+		}
+		if err := os.MkdirAll(m.cfg.ArchiveDir, 0o755); err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			path := filepath.Join(m.cfg.ArchiveDir, name+".csv.gz")
+			if err := exportTable(ctx, conn, name, path); err != nil {
+				return fmt.Errorf("failed to archive partition %s: %w", name, err)
+			}
+			if _, err := conn.ExecContext(ctx, `DROP TABLE `+ident(name)); err != nil {
+				return fmt.Errorf("failed to drop archived partition %s: %w", name, err)
+			}
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// locked runs fn on a single connection holding the partition advisory lock,
+// or skips it if the lock is taken.
+func (m *PartitionManager) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, partitionLockKey).Scan(&acquired); err != nil {
+		return err
+	}
+	if !acquired {
+		return nil
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, partitionLockKey)
+
+	return fn(conn)
+}
+
+// createPartition creates and attaches the partition for month. Rows of that
+// month already in the default partition are moved into it first, as Postgres
+// refuses to attach a partition whose range the default partition holds rows
+// for. Attaching locks the default partition exclusively, waiting at most
+// lockTimeout for it.
+func createPartition(ctx context.Context, conn *sql.Conn, month time.Time, lockTimeout time.Duration) (string, error) {
+	name := partitionName(month)
+	from, to := month, month.AddDate(0, 1, 0)
+
+	tx, err := beginWithLockTimeout(ctx, conn, lockTimeout)
+	if err != nil {
+		return name, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`,
+		ident(name), ident(partitionedTable)))
+	if err != nil {
+		return name, err
+	}
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`WITH moved AS (
+		DELETE FROM %s WHERE created_at >= $1 AND created_at < $2 RETURNING *
+	) INSERT INTO %s SELECT * FROM moved`, ident(defaultPartition), ident(name)), from, to)
+	if err != nil {
+		return name, err
+	}
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM (%s) TO (%s)`,
+		ident(partitionedTable), ident(name), timeLiteral(from), timeLiteral(to)))
+	if err != nil {
+		return name, err
+	}
+	return name, tx.Commit()
+}
+
+// detachPartition detaches the partition name from the transactions table.
+//
+// DETACH PARTITION holds an ACCESS EXCLUSIVE lock on the transactions table
+// until it commits. DETACH PARTITION CONCURRENTLY, which would not, is refused
+// while the table has a default partition, and transactions_default has to
+// stay to catch rows outside the monthly partitions. Detaching changes only
+// the catalog, so the lock is held briefly, but while it waits for running
+// queries on transactions every new one queues behind it. The wait is
+// therefore capped at lockTimeout: if it runs out the partition stays
+// attached, Maintain fails, and the next run tries again.
+func detachPartition(ctx context.Context, conn *sql.Conn, name string, lockTimeout time.Duration) error {
+	tx, err := beginWithLockTimeout(ctx, conn, lockTimeout)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s DETACH PARTITION %s`, ident(partitionedTable), ident(name))); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// beginWithLockTimeout starts a transaction whose statements fail once they
+// have waited lockTimeout for a lock.
+func beginWithLockTimeout(ctx context.Context, conn *sql.Conn, lockTimeout time.Duration) (*sql.Tx, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	// SET does not take parameters.
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`SET LOCAL lock_timeout = %d`, lockTimeout.Milliseconds())); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
+
+// exportTable writes the rows of table as gzipped CSV with a header to path.
+// The file is written under a temporary name and renamed once complete.
+func exportTable(ctx context.Context, conn *sql.Conn, table, path string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	err = conn.Raw(func(driverConn any) error {
+		pc, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("archiving needs the pgx driver, got %T", driverConn)
+		}
+		_, err := pc.Conn().PgConn().CopyTo(ctx, zw, fmt.Sprintf(`COPY %s TO STDOUT WITH (FORMAT csv, HEADER)`, ident(table)))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// attachedPartitions returns the monthly partitions of the transactions table
+// by month. The default partition is left out.
+func attachedPartitions(ctx context.Context, conn *sql.Conn) (map[time.Time]string, error) {
+	names, err := queryNames(ctx, conn, `
+		SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = $1::regclass`, partitionedTable)
+	if err != nil {
+		return nil, err
+	}
+	partitions := make(map[time.Time]string, len(names))
+	for _, name := range names {
+		if month, ok := partitionMonth(name); ok {
+			partitions[month] = name
+		}
+	}
+	return partitions, nil
+}
+
+// detachedPartitions returns the names of monthly partitions that have been
+// detached but not yet archived, oldest first.
+func detachedPartitions(ctx context.Context, conn *sql.Conn) ([]string, error) {
+	names, err := queryNames(ctx, conn, `
+		SELECT c.relname FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = current_schema() AND c.relkind = 'r' AND NOT c.relispartition AND starts_with(c.relname, $1)`,
+		partitionPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var detached []string
+	for _, name := range names {
+		if _, ok := partitionMonth(name); ok {
+			detached = append(detached, name)
+		}
+	}
+	sort.Strings(detached)
+	return detached, nil
+}
+
+func queryNames(ctx context.Context, conn *sql.Conn, query string, args ...any) ([]string, error) {
+	rows, err := conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func partitionName(month time.Time) string {
+	return partitionPrefix + month.Format(partitionLayout)
+}
+
+func partitionMonth(name string) (time.Time, bool) {
+	suffix, ok := strings.CutPrefix(name, partitionPrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	month, err := time.Parse(partitionLayout, suffix)
+	return month, err == nil
+}
+
+func sortedMonths(partitions map[time.Time]string) []time.Time {
+	months := make([]time.Time, 0, len(partitions))
+	for month := range partitions {
+		months = append(months, month)
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].Before(months[j]) })
+	return months
+}
+
+func ident(name string) string {
+	return pgx.Identifier{name}.Sanitize()
+}
+
+// timeLiteral formats t for partition bounds, which cannot be parameters.
+func timeLiteral(t time.Time) string {
+	return "'" + t.UTC().Format("2006-01-02 15:04:05Z07:00") + "'"
+}
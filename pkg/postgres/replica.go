@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+// replicaReadKey marks a GORM statement as safe to serve from a replica.
+const replicaReadKey = "postgres:replica_read"
+
+// Default ReplicaConfig values.
+const (
+	DefaultReplicaMaxLag        = 5 * time.Second
+	DefaultReplicaCheckInterval = 5 * time.Second
+)
+
+// ReplicaConfig lists the read replicas of the primary database.
+type ReplicaConfig struct {
+	DSNs          []string
+	MaxLag        time.Duration // Replicas further behind are skipped
+	CheckInterval time.Duration // How often health and lag are measured
+}
+
+// ReplicaSet routes reads marked with ReplicaRead to healthy replicas whose
+// replay lag is within MaxLag, round robin, and to the primary when there is
+// none. Statements in a transaction and locking reads always run on the
+// primary.
+type ReplicaSet struct {
+	replicas []*replica
+	cfg      ReplicaConfig
+	next     atomic.Uint64
+}
+
+type replica struct {
+	name    string // Host and port, safe to log
+	db      *sql.DB
+	healthy atomic.Bool
+	lag     atomic.Int64 // Replay lag in nanoseconds
+}
+
+// UseReplicas opens the replicas in cfg and installs the read routing on
+// primary. Replicas start out unhealthy; call Start to begin checking them.
+func UseReplicas(primary *gorm.DB, cfg ReplicaConfig) (*ReplicaSet, error) {
+	if cfg.MaxLag <= 0 {
+		cfg.MaxLag = DefaultReplicaMaxLag
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = DefaultReplicaCheckInterval
+	}
+
+	s := &ReplicaSet{cfg: cfg}
+	for i, dsn := range cfg.DSNs {
+		config, err := pgx.ParseConfig(dsn)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("invalid replica DSN #%d: %w", i+1, err)
+		}
+		s.replicas = append(s.replicas, &replica{
+			name: fmt.Sprintf("%s:%d", config.Host, config.Port),
+			db:   stdlib.OpenDB(*config),
+		})
+	}
+
+	err := primary.Callback().Query().Before("gorm:query").Register("postgres:replica", s.route)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// ReplicaRead marks a query as tolerating replication lag. It only has an
+// effect on connections set up with UseReplicas.
+func ReplicaRead(db *gorm.DB) *gorm.DB {
+	return db.Set(replicaReadKey, true)
+}
+
+// Start checks the replicas now and then every CheckInterval until ctx is
+// done.
+func (s *ReplicaSet) Start(ctx context.Context) {
+	s.check(ctx)
+	go func() {
+		ticker := time.NewTicker(s.cfg.CheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.check(ctx)
+			}
+		}
+	}()
+}
+
+// Close closes the replica connections. Reads fall back to the primary.
+func (s *ReplicaSet) Close() {
+	for _, r := range s.replicas {
+		r.healthy.Store(false)
+		r.db.Close()
+	}
+}
+
+func (s *ReplicaSet) route(db *gorm.DB) {
+	if ok, _ := db.Get(replicaReadKey); ok != true {
+		return
+	}
+	if _, inTx := db.Statement.ConnPool.(gorm.TxCommitter); inTx {
+		return
+	}
+	if _, locking := db.Statement.Clauses["FOR"]; locking {
+		return
+	}
+	if r := s.pick(); r != nil {
+		db.Statement.ConnPool = r.db
+	}
+}
+
+// pick returns the next usable replica, or nil if none is.
+func (s *ReplicaSet) pick() *replica {
+	n := uint64(len(s.replicas))
+	start := s.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		r := s.replicas[(start+i)%n]
+		if r.healthy.Load() && time.Duration(r.lag.Load()) <= s.cfg.MaxLag {
+			return r
+		}
+	}
+	return nil
+}
+
+// check measures every replica, logging when one changes between usable
+// and unusable.
+func (s *ReplicaSet) check(ctx context.Context) {
+	for _, r := range s.replicas {
+		wasUsable := r.healthy.Load() && time.Duration(r.lag.Load()) <= s.cfg.MaxLag
+
+		lag, err := r.measureLag(ctx, s.cfg.CheckInterval)
+		r.healthy.Store(err == nil)
+		if err == nil {
+			r.lag.Store(int64(lag))
+		}
+
+		usable := err == nil && lag <= s.cfg.MaxLag
+		switch {
+		case usable && !wasUsable:
+			log.Printf("Replica %s is serving reads (lag %s)", r.name, lag)
+		case !usable && wasUsable && err != nil:
+			log.Printf("Replica %s is unhealthy, reading from the primary: %v", r.name, err)
+		case !usable && wasUsable:
+			log.Printf("Replica %s lags by %s, reading from the primary", r.name, lag)
+		}
+	}
+}
+
+// measureLag returns how far the replica's replay is behind. A replica that
+// is streaming from the primary and has replayed everything it received
+// counts as current even if the primary has been idle, and a server that is
+// not in recovery has no lag. A replica whose WAL receiver is not streaming
+// cannot tell how far behind it is and is reported as an error; reading
+// pg_stat_wal_receiver needs the pg_read_all_stats role.
+func (r *replica) measureLag(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var inRecovery, streaming bool
+	var seconds float64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT pg_is_in_recovery(),
+			EXISTS (SELECT 1 FROM pg_stat_wal_receiver WHERE status = 'streaming'),
+			CASE
+				WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+				ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+			END::float8`).Scan(&inRecovery, &streaming, &seconds)
+	if err != nil {
+		return 0, err
+	}
+	if !inRecovery {
+		return 0, nil
+	}
+	if !streaming {
+		return 0, errors.New("WAL receiver is not streaming from the primary")
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -2,6 +2,7 @@ package rabbitmq
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 
@@ -27,6 +28,11 @@ func NewConnection(url string) (*RabbitMQ, error) {
 		return nil, fmt.Errorf("failed to open a channel: %w", err)
 	}
 
+	// Publisher confirms let Publish report whether the broker accepted a message.
+	if err := ch.Confirm(false); err != nil {
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
 	q, err := ch.QueueDeclare(
 		QueueName, // name
 		true,      // durable
@@ -43,16 +49,32 @@ func NewConnection(url string) (*RabbitMQ, error) {
 	return &RabbitMQ{conn: conn, channel: ch, queue: q}, nil
 }
 
-func (r *RabbitMQ) Publish(ctx context.Context, body []byte) error {
-	return r.channel.PublishWithContext(ctx,
+// Publish sends a persistent message of the given type and waits for the
+// broker to confirm it. Consumers read the type from Delivery.Type.
+func (r *RabbitMQ) Publish(ctx context.Context, msgType string, body []byte) error {
+	confirm, err := r.channel.PublishWithDeferredConfirmWithContext(ctx,
 		"",           // exchange
 		r.queue.Name, // routing key
 		false,        // mandatory
 		false,        // immediate
 		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
+			ContentType:  "application/json",
+			Type:         msgType,
+			DeliveryMode: amqp.Persistent,
+			Body:         body,
 		})
+	if err != nil {
+		return err
+	}
+
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return errors.New("message was rejected by the broker")
+	}
+	return nil
 }
 
 func (r *RabbitMQ) Consume() (<-chan amqp.Delivery, error) {
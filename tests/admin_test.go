@@ -0,0 +1,215 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"digital-wallet/internal/handler"
+	"digital-wallet/internal/repository/memory"
+	"digital-wallet/internal/service"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// signStaffToken is signToken for an HS256 token granting roles.
+func signStaffToken(t *testing.T, secret []byte, userID uuid.UUID, roles ...string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   userID.String(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": roles,
+	})
+	signed, err := token.SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestAdminAPI(t *testing.T) {
+	secret := []byte("test-secret")
+	auth, err := handler.NewAuthenticator(handler.AuthConfig{HMACSecret: secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, _ := newMemoryService()
+	router := handler.NewRouter(handler.NewHandler(svc),
+		handler.NewAPIKeyAuthenticator(svc, memory.NewRateLimiter(), nil).Middleware, auth.Middleware)
+
+	alice := uuid.New()
+	aliceToken := signToken(t, jwt.SigningMethodHS256, secret, "", alice)
+	viewer := signStaffToken(t, secret, uuid.New(), "viewer")
+	operator := signStaffToken(t, secret, uuid.New(), "operator")
+	financeID := uuid.New()
+	finance := signStaffToken(t, secret, financeID, "finance")
+
+	w := doAuthJSON(router, aliceToken, "POST", "/wallets", map[string]string{"user_id": alice.String()})
+	var wallet domain.Wallet
+	_ = json.Unmarshal(w.Body.Bytes(), &wallet)
+	if wallet.Status != domain.WalletStatusActive {
+		t.Fatalf("Expected a new wallet to be active, got %q", wallet.Status)
+	}
+	bob := uuid.New()
+	w = doAuthJSON(router, signToken(t, jwt.SigningMethodHS256, secret, "", bob), "POST", "/wallets", map[string]string{"user_id": bob.String()})
+	var bobWallet domain.Wallet
+	_ = json.Unmarshal(w.Body.Bytes(), &bobWallet)
+	walletPath := "/wallets/" + wallet.ID.String()
+	adminPath := "/admin" + walletPath
+	doAuthJSON(router, aliceToken, "POST", walletPath+"/deposits", map[string]int64{"amount": 1000})
+
+	// expect checks the status and, if code is set, the problem code of w.
+	expect := func(name string, w *httptest.ResponseRecorder, status int, code string) {
+		t.Helper()
+		var problem handler.Problem
+		_ = json.Unmarshal(w.Body.Bytes(), &problem)
+		if w.Code != status || (code != "" && problem.Code != code) {
+			t.Errorf("%s: expected %d %s, got %d %q. Body: %s", name, status, code, w.Code, problem.Code, w.Body.String())
+		}
+	}
+
+	// Roles.
+	expect("customer lookup", doAuthJSON(router, aliceToken, "GET", "/admin/users/"+alice.String()+"/wallets", nil), http.StatusForbidden, handler.CodeInsufficientRole)
+	expect("anonymous lookup", doAuthJSON(router, "", "GET", "/admin/users/"+alice.String()+"/wallets", nil), http.StatusUnauthorized, "")
+	expect("viewer freeze", doAuthJSON(router, viewer, "POST", adminPath+"/freeze", nil), http.StatusForbidden, handler.CodeInsufficientRole)
+	expect("operator adjustment", doAuthJSON(router, operator, "POST", adminPath+"/adjustments",
+		map[string]interface{}{"amount": 100, "reason_code": "goodwill"}), http.StatusForbidden, handler.CodeInsufficientRole)
+
+	w = doAuthJSON(router, viewer, "GET", "/admin/users/"+alice.String()+"/wallets", nil)
+	var listed struct {
+		Wallets []handler.WalletResponse `json:"wallets"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &listed)
+	if w.Code != http.StatusOK || len(listed.Wallets) != 1 || listed.Wallets[0].ID != wallet.ID {
+		t.Fatalf("Expected alice's wallet, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	// Freezing stops debits but not credits.
+	w = doAuthJSON(router, operator, "POST", adminPath+"/freeze", map[string]string{"note": "account takeover"})
+	var frozen domain.Wallet
+	_ = json.Unmarshal(w.Body.Bytes(), &frozen)
+	if w.Code != http.StatusOK || frozen.Status != domain.WalletStatusFrozen {
+		t.Fatalf("Expected the wallet to be frozen, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	expect("second freeze", doAuthJSON(router, operator, "POST", adminPath+"/freeze", nil), http.StatusConflict, "status_unchanged")
+	expect("withdrawal", doAuthJSON(router, aliceToken, "POST", walletPath+"/withdrawals", map[string]int64{"amount": 100}), http.StatusConflict, "wallet_frozen")
+	expect("transfer", doAuthJSON(router, aliceToken, "POST", "/transfers", map[string]interface{}{
+		"sender_id": wallet.ID.String(), "receiver_id": bobWallet.ID.String(), "amount": 100,
+	}), http.StatusConflict, "wallet_frozen")
+	expect("deposit", doAuthJSON(router, aliceToken, "POST", walletPath+"/deposits", map[string]int64{"amount": 100}), http.StatusCreated, "")
+
+	// Adjustments need a known reason and may debit a frozen wallet.
+	expect("no reason", doAuthJSON(router, finance, "POST", adminPath+"/adjustments", map[string]interface{}{"amount": -300}), http.StatusBadRequest, "")
+	expect("unknown reason", doAuthJSON(router, finance, "POST", adminPath+"/adjustments",
+		map[string]interface{}{"amount": -300, "reason_code": "because"}), http.StatusBadRequest, "invalid_reason_code")
+	expect("overdraft", doAuthJSON(router, finance, "POST", adminPath+"/adjustments",
+		map[string]interface{}{"amount": -5000, "reason_code": "fraud_recovery"}), http.StatusUnprocessableEntity, "insufficient_funds")
+	w = doAuthJSON(router, finance, "POST", adminPath+"/adjustments",
+		map[string]interface{}{"amount": -300, "reason_code": "fraud_recovery", "note": "case 42"})
+	var adjustment domain.Transaction
+	_ = json.Unmarshal(w.Body.Bytes(), &adjustment)
+	if w.Code != http.StatusCreated || adjustment.Type != domain.TransactionTypeAdjustment || *adjustment.SenderID != wallet.ID {
+		t.Fatalf("Expected a debit adjustment, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	w = doAuthJSON(router, viewer, "GET", adminPath+"/ledger?limit=1", nil)
+	var ledger struct {
+		Entries []domain.LedgerEntry `json:"entries"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &ledger)
+	if len(ledger.Entries) != 1 || ledger.Entries[0].TransactionID != adjustment.ID || ledger.Entries[0].Amount != -300 {
+		t.Errorf("Expected the adjustment as the latest entry, got %+v", ledger.Entries)
+	}
+
+	expect("unfreeze", doAuthJSON(router, operator, "POST", adminPath+"/unfreeze", nil), http.StatusOK, "")
+	expect("withdrawal after unfreeze", doAuthJSON(router, aliceToken, "POST", walletPath+"/withdrawals", map[string]int64{"amount": 100}), http.StatusCreated, "")
+
+	// Every action is in the audit log, newest first.
+	w = doAuthJSON(router, viewer, "GET", "/admin/audit-log?wallet_id="+wallet.ID.String(), nil)
+	var audit struct {
+		Entries []domain.AdminAuditEntry `json:"entries"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &audit)
+	var actions []string
+	for _, e := range audit.Entries {
+		actions = append(actions, e.Action)
+	}
+	want := []string{domain.AdminActionUnfreeze, domain.AdminActionViewLedger, domain.AdminActionAdjust, domain.AdminActionFreeze}
+	if len(actions) != len(want) {
+		t.Fatalf("Expected actions %v, got %v", want, actions)
+	}
+	for i := range want {
+		if actions[i] != want[i] {
+			t.Fatalf("Expected actions %v, got %v", want, actions)
+		}
+	}
+	adjusted := audit.Entries[2]
+	if adjusted.Actor != "user:"+financeID.String() || adjusted.Role != domain.AdminRoleFinance ||
+		adjusted.ReasonCode != domain.AdjustmentReasonFraudRecovery || adjusted.Note != "case 42" ||
+		adjusted.TransactionID == nil || *adjusted.TransactionID != adjustment.ID {
+		t.Errorf("Unexpected adjustment entry %+v", adjusted)
+	}
+
+	// API keys with the admin scope act with the admin role stored on them.
+	issue := func(role domain.AdminRole) string {
+		t.Helper()
+		key, err := svc.IssueAPIKey(context.Background(), &domain.APIKey{Name: "ops", Scopes: []domain.APIScope{domain.ScopeAdmin}, AdminRole: role})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	expect("API key without a role", doKeyJSON(router, issue(""), "GET", "/admin/users/"+alice.String()+"/wallets", nil), http.StatusForbidden, handler.CodeInsufficientRole)
+	viewerKey := issue(domain.AdminRoleViewer)
+	expect("viewer API key freeze", doKeyJSON(router, viewerKey, "POST", adminPath+"/freeze", nil), http.StatusForbidden, handler.CodeInsufficientRole)
+	expect("viewer API key lookup", doKeyJSON(router, viewerKey, "GET", "/admin/users/"+alice.String()+"/wallets", nil), http.StatusOK, "")
+	operatorKey := issue(domain.AdminRoleOperator)
+	expect("operator API key adjustment", doKeyJSON(router, operatorKey, "POST", adminPath+"/adjustments",
+		map[string]interface{}{"amount": 100, "reason_code": "goodwill"}), http.StatusForbidden, handler.CodeInsufficientRole)
+	expect("operator API key freeze", doKeyJSON(router, operatorKey, "POST", adminPath+"/freeze", nil), http.StatusOK, "")
+	entries, err := svc.AdminAuditLog(context.Background(), domain.AdminActor{ID: "test", Role: domain.AdminRoleViewer},
+		domain.AdminAuditFilter{WalletID: &wallet.ID, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Actor != "api_key:"+operatorKey[:11] || entries[0].Role != domain.AdminRoleOperator {
+		t.Errorf("Expected the API key's freeze to be audited with its role, got %+v", entries)
+	}
+
+	// Only admin keys carry a role.
+	_, err = svc.IssueAPIKey(context.Background(), &domain.APIKey{Name: "billing", Scopes: []domain.APIScope{domain.ScopeWalletsRead}, AdminRole: domain.AdminRoleViewer})
+	if !errors.Is(err, domain.ErrInvalidScope) {
+		t.Errorf("Expected ErrInvalidScope for a role on a key without the admin scope, got %v", err)
+	}
+}
+
+// TestFrozenWalletConditionalTransfer checks that the single-statement
+// transfer is rolled back when the sender turns out to be frozen.
+func TestFrozenWalletConditionalTransfer(t *testing.T) {
+	svc, walletRepo := newMemoryService(service.WithBalanceStrategy(service.BalanceStrategyConditional))
+	ctx := context.Background()
+	staff := domain.AdminActor{ID: "test", Role: domain.AdminRoleOperator}
+
+	sender, _ := svc.CreateWallet(ctx, uuid.New(), domain.DefaultCurrency)
+	receiver, _ := svc.CreateWallet(ctx, uuid.New(), domain.DefaultCurrency)
+	if _, err := svc.Deposit(ctx, sender.ID, domain.NewMoney(500, domain.DefaultCurrency)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.FreezeWallet(ctx, staff, sender.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := svc.TransferMoney(ctx, sender.ID, receiver.ID, domain.NewMoney(100, domain.DefaultCurrency))
+	if !errors.Is(err, domain.ErrWalletFrozen) {
+		t.Fatalf("Expected ErrWalletFrozen, got %v", err)
+	}
+	got, _ := walletRepo.GetByID(ctx, sender.ID)
+	if got.Balance != 500 {
+		t.Errorf("Expected the transfer to be rolled back, balance is %d", got.Balance)
+	}
+}
@@ -0,0 +1,330 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"digital-wallet/internal/handler"
+	"digital-wallet/internal/repository"
+	"digital-wallet/internal/repository/memory"
+	"digital-wallet/internal/repository/pgxrepo"
+	"digital-wallet/pkg/postgres"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+)
+
+// doKeyJSON is doJSON with an API key; an empty key sends none.
+func doKeyJSON(router http.Handler, key, method, path string, payload interface{}) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	if payload != nil {
+		_ = json.NewEncoder(&body).Encode(payload)
+	}
+	req, _ := http.NewRequest(method, path, &body)
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(handler.APIKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPIKeyScopesAndRotation(t *testing.T) {
+	svc, _ := newMemoryService()
+	router := handler.NewRouter(handler.NewHandler(svc), handler.NewAPIKeyAuthenticator(svc, memory.NewRateLimiter(), nil).Middleware)
+
+	adminKey, err := svc.IssueAPIKey(context.Background(), &domain.APIKey{Name: "ops", Scopes: []domain.APIScope{domain.ScopeAdmin}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issue := func(payload interface{}) handler.APIKeyResponse {
+		w := doKeyJSON(router, adminKey, "POST", "/admin/api-keys", payload)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d. Body: %s", w.Code, w.Body.String())
+		}
+		var resp handler.APIKeyResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		if resp.Key == "" || resp.SigningSecret == "" {
+			t.Fatalf("Expected the key and its signing secret, got %s", w.Body.String())
+		}
+		return resp
+	}
+	owner := uuid.New()
+	reader := issue(map[string]interface{}{"name": "reporting", "scopes": []string{"wallets:read"}, "owner_id": owner})
+
+	w := doKeyJSON(router, adminKey, "POST", "/wallets", map[string]string{"user_id": owner.String()})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 for the admin key, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var wallet domain.Wallet
+	_ = json.Unmarshal(w.Body.Bytes(), &wallet)
+	walletPath := "/wallets/" + wallet.ID.String()
+
+	if w := doKeyJSON(router, reader.Key, "GET", walletPath, nil); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 reading with wallets:read, got %d", w.Code)
+	}
+	w = doKeyJSON(router, reader.Key, "POST", walletPath+"/deposits", map[string]int64{"amount": 100})
+	var problem handler.Problem
+	_ = json.Unmarshal(w.Body.Bytes(), &problem)
+	if w.Code != http.StatusForbidden || problem.Code != handler.CodeInsufficientScope {
+		t.Errorf("Expected 403 insufficient_scope depositing with wallets:read, got %d %q", w.Code, problem.Code)
+	}
+	if w := doKeyJSON(router, reader.Key, "GET", "/admin/api-keys", nil); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 listing keys without admin, got %d", w.Code)
+	}
+	if w := doKeyJSON(router, "", "GET", "/admin/api-keys", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 listing keys without a key, got %d", w.Code)
+	}
+	if w := doKeyJSON(router, "wk_not-a-key", "GET", walletPath, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for an unknown key, got %d", w.Code)
+	}
+
+	// Both keys work during the overlap.
+	w = doKeyJSON(router, adminKey, "POST", "/admin/api-keys/"+reader.ID.String()+"/rotate", map[string]int64{"overlap_seconds": 60})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 rotating, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var rotated handler.APIKeyResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &rotated)
+	if rotated.RotatedFrom == nil || *rotated.RotatedFrom != reader.ID || rotated.Key == reader.Key || rotated.OwnerID == nil || *rotated.OwnerID != owner {
+		t.Fatalf("Expected a new key replacing %s, got %+v", reader.ID, rotated.APIKey)
+	}
+	for _, key := range []string{reader.Key, rotated.Key} {
+		if w := doKeyJSON(router, key, "GET", walletPath, nil); w.Code != http.StatusOK {
+			t.Errorf("Expected status 200 during the overlap, got %d", w.Code)
+		}
+	}
+
+	// Without an overlap the old key stops working at once.
+	w = doKeyJSON(router, adminKey, "POST", "/admin/api-keys/"+rotated.ID.String()+"/rotate", map[string]int64{"overlap_seconds": 0})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 rotating, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	if w := doKeyJSON(router, rotated.Key, "GET", walletPath, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a rotated key after the overlap, got %d", w.Code)
+	}
+
+	if w := doKeyJSON(router, adminKey, "DELETE", "/admin/api-keys/"+reader.ID.String(), nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 revoking, got %d", w.Code)
+	}
+	if w := doKeyJSON(router, reader.Key, "GET", walletPath, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a revoked key, got %d", w.Code)
+	}
+}
+
+func TestAPIKeyWalletOwnership(t *testing.T) {
+	svc, _ := newMemoryService()
+	router := handler.NewRouter(handler.NewHandler(svc), handler.NewAPIKeyAuthenticator(svc, memory.NewRateLimiter(), nil).Middleware)
+	ctx := context.Background()
+
+	alice, bob := uuid.New(), uuid.New()
+	aliceWallet, _ := svc.CreateWallet(ctx, alice, "USD")
+	bobWallet, _ := svc.CreateWallet(ctx, bob, "USD")
+	if _, err := svc.Deposit(ctx, bobWallet.ID, domain.NewMoney(1000, "USD")); err != nil {
+		t.Fatal(err)
+	}
+
+	issue := func(key *domain.APIKey) string {
+		plain, err := svc.IssueAPIKey(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return plain
+	}
+	writer := []domain.APIScope{domain.ScopeWalletsRead, domain.ScopeTransfersWrite}
+	aliceKey := issue(&domain.APIKey{Name: "alice-app", Scopes: writer, OwnerID: &alice})
+	ownerless := issue(&domain.APIKey{Name: "unbound", Scopes: writer})
+	allWallets := issue(&domain.APIKey{Name: "settlement", Scopes: append(writer, domain.ScopeAllWallets)})
+
+	bobPath := "/wallets/" + bobWallet.ID.String()
+	transfer := map[string]interface{}{"sender_id": bobWallet.ID, "receiver_id": aliceWallet.ID, "amount": 100}
+	for name, key := range map[string]string{"alice's key": aliceKey, "a key without owner": ownerless} {
+		if w := doKeyJSON(router, key, "GET", bobPath, nil); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 reading bob's wallet with %s, got %d", name, w.Code)
+		}
+		if w := doKeyJSON(router, key, "POST", bobPath+"/withdrawals", map[string]int64{"amount": 100}); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 withdrawing from bob's wallet with %s, got %d", name, w.Code)
+		}
+		if w := doKeyJSON(router, key, "POST", "/transfers", transfer); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 transferring from bob's wallet with %s, got %d", name, w.Code)
+		}
+		if w := doKeyJSON(router, key, "POST", "/wallets", map[string]string{"user_id": bob.String()}); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 creating a wallet for bob with %s, got %d", name, w.Code)
+		}
+	}
+
+	if w := doKeyJSON(router, aliceKey, "GET", "/wallets/"+aliceWallet.ID.String(), nil); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 reading alice's wallet with her key, got %d", w.Code)
+	}
+	if w := doKeyJSON(router, allWallets, "POST", "/transfers", transfer); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 transferring with wallets:all, got %d. Body: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestAPIKeyIdempotencyScope(t *testing.T) {
+	svc, _ := newMemoryService()
+	ctx := context.Background()
+	render := func(tx *domain.Transaction) (int, []byte, error) {
+		return http.StatusOK, []byte(tx.ID.String()), nil
+	}
+	from, _ := svc.CreateWallet(ctx, uuid.New(), "USD")
+	to, _ := svc.CreateWallet(ctx, uuid.New(), "USD")
+	if _, err := svc.Deposit(ctx, from.ID, domain.NewMoney(100, "USD")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two keys acting for the same wallet each get a transfer for the same key.
+	var first *domain.IdempotencyKey
+	for _, name := range []string{"billing", "payouts"} {
+		key := &domain.APIKey{Name: name, Scopes: []domain.APIScope{domain.ScopeTransfersWrite, domain.ScopeAllWallets}}
+		if _, err := svc.IssueAPIKey(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+		record, replayed, err := svc.TransferMoneyIdempotent(domain.WithAPIKey(ctx, key), "order-1", "hash", from.ID, to.ID, domain.NewMoney(10, "USD"), render)
+		if err != nil || replayed {
+			t.Fatalf("Expected a new transfer for %s, got replayed=%v err=%v", name, replayed, err)
+		}
+		if first != nil && record.TransactionID == first.TransactionID {
+			t.Errorf("Expected a transfer of its own for %s, got %s", name, first.TransactionID)
+		}
+		first = record
+	}
+}
+
+func TestAPIKeyRateLimit(t *testing.T) {
+	svc, _ := newMemoryService()
+	router := handler.NewRouter(handler.NewHandler(svc), handler.NewAPIKeyAuthenticator(svc, memory.NewRateLimiter(), nil).Middleware)
+
+	key := &domain.APIKey{Name: "batch", Scopes: []domain.APIScope{domain.ScopeWalletsRead}, RateLimit: 2}
+	plain, err := svc.IssueAPIKey(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := "/wallets/" + uuid.NewString()
+	for i := 0; i < 2; i++ {
+		if w := doKeyJSON(router, plain, "GET", path, nil); w.Code != http.StatusNotFound {
+			t.Fatalf("Expected status 404 within the limit, got %d", w.Code)
+		}
+	}
+	w := doKeyJSON(router, plain, "GET", path, nil)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429 over the limit, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header")
+	}
+}
+
+// failingRateLimiter is a rate limiter whose backing store is down.
+type failingRateLimiter struct{}
+
+func (failingRateLimiter) Allow(context.Context, string, int, time.Duration) (bool, time.Duration, error) {
+	return false, 0, errors.New("connection refused")
+}
+
+func TestAPIKeyRateLimitUnavailable(t *testing.T) {
+	svc, _ := newMemoryService()
+	limited, err := svc.IssueAPIKey(context.Background(), &domain.APIKey{Name: "batch", Scopes: []domain.APIScope{domain.ScopeWalletsRead}, RateLimit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlimited, err := svc.IssueAPIKey(context.Background(), &domain.APIKey{Name: "reports", Scopes: []domain.APIScope{domain.ScopeWalletsRead}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := "/wallets/" + uuid.NewString()
+
+	// Without a fallback, rate-limited keys are refused.
+	router := handler.NewRouter(handler.NewHandler(svc), handler.NewAPIKeyAuthenticator(svc, failingRateLimiter{}, nil).Middleware)
+	w := doKeyJSON(router, limited, "GET", path, nil)
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected status 503 with Retry-After, got %d", w.Code)
+	}
+	if w := doKeyJSON(router, unlimited, "GET", path, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a key without a rate limit, got %d", w.Code)
+	}
+
+	// The fallback keeps counting.
+	router = handler.NewRouter(handler.NewHandler(svc), handler.NewAPIKeyAuthenticator(svc, failingRateLimiter{}, memory.NewRateLimiter()).Middleware)
+	for i := 0; i < 2; i++ {
+		if w := doKeyJSON(router, limited, "GET", path, nil); w.Code != http.StatusNotFound {
+			t.Fatalf("Expected status 404 within the limit, got %d", w.Code)
+		}
+	}
+	if w := doKeyJSON(router, limited, "GET", path, nil); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 over the limit, got %d", w.Code)
+	}
+}
+
+// TestAPIKeyRepositories checks that both Postgres backends store scopes,
+// owners and revocation the same way.
+func TestAPIKeyRepositories(t *testing.T) {
+	_ = godotenv.Load("../.env")
+	pgDSN := os.Getenv("DATABASE_URL")
+	if pgDSN == "" {
+		pgDSN = "host=localhost user=postgres password=postgres dbname=wallet_db port=5432 sslmode=disable"
+	}
+	db, err := postgres.NewConnection(pgDSN)
+	if err != nil {
+		t.Skipf("Skipping API key repository test (DB not available): %v", err)
+	}
+	ctx := context.Background()
+	if err := postgres.Migrate(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	pool, err := postgres.NewPool(ctx, pgDSN, pgxrepo.PrepareStatements)
+	if err != nil {
+		t.Skipf("Skipping API key repository test (DB not available): %v", err)
+	}
+	defer pool.Close()
+
+	repos := map[string]domain.APIKeyRepository{
+		"gorm": repository.NewAPIKeyRepository(db),
+		"pgx":  pgxrepo.NewAPIKeyRepository(pool),
+	}
+	for name, repo := range repos {
+		t.Run(name, func(t *testing.T) {
+			owner := uuid.New()
+			key := &domain.APIKey{
+				Name:    "test",
+				Prefix:  "wk_test",
+				KeyHash: uuid.NewString(),
+				Scopes:  []domain.APIScope{domain.ScopeWalletsRead, domain.ScopeTransfersWrite},
+				OwnerID: &owner,
+			}
+			if err := repo.Create(ctx, key); err != nil {
+				t.Fatal(err)
+			}
+			got, err := repo.GetByHash(ctx, key.KeyHash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != key.ID || len(got.Scopes) != 2 || !got.HasScope(domain.ScopeTransfersWrite) || got.HasScope(domain.ScopeAdmin) || !got.MayActFor(owner) {
+				t.Errorf("Read back %+v, want %+v", got, key)
+			}
+
+			now := time.Now()
+			got.RevokedAt = &now
+			if err := repo.Update(ctx, got); err != nil {
+				t.Fatal(err)
+			}
+			if got, err = repo.GetByHash(ctx, key.KeyHash); err != nil || got.Active(time.Now()) {
+				t.Errorf("Expected a revoked key, got %+v, %v", got, err)
+			}
+			if _, err := repo.GetByHash(ctx, uuid.NewString()); err != domain.ErrAPIKeyNotFound {
+				t.Errorf("Expected ErrAPIKeyNotFound, got %v", err)
+			}
+		})
+	}
+}
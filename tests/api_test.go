@@ -2,11 +2,13 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"digital-wallet/internal/domain"
 	"digital-wallet/internal/handler"
@@ -41,27 +43,38 @@ func setupRouter(t *testing.T) http.Handler {
 	// Infrastructure
 	db, err := postgres.NewConnection(pgDSN)
 	if err != nil {
-		t.Skipf("Skipping API test (DB not available): %v", err)
+		t.Logf("DB not available, using in-memory repositories: %v", err)
+		return memoryRouter()
+	}
+	if err := postgres.Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
 	}
 
 	rdb, err := redis.NewClient(redisAddr, "", 0)
 	if err != nil {
-		t.Skipf("Skipping API test (Redis not available): %v", err)
+		t.Logf("Redis not available, using in-memory repositories: %v", err)
+		return memoryRouter()
 	}
 
 	mq, err := rabbitmq.NewConnection(rabbitURL)
 	if err != nil {
-		t.Skipf("Skipping API test (RabbitMQ not available): %v", err)
+		t.Logf("RabbitMQ not available, using in-memory repositories: %v", err)
+		return memoryRouter()
 	}
 
 	// Repositories
 	walletRepo := repository.NewWalletRepository(db)
 	transRepo := repository.NewTransactionRepository(db)
+	ledgerRepo := repository.NewLedgerRepository(db)
 	cacheRepo := repository.NewCacheRepository(rdb)
 	eventProducer := repository.NewEventProducer(mq)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
 
 	// Service
-	svc := service.NewWalletService(walletRepo, transRepo, cacheRepo, eventProducer)
+	svc := service.NewWalletService(walletRepo, transRepo, ledgerRepo, cacheRepo, eventProducer,
+		service.WithIdempotency(idempotencyRepo, time.Hour),
+		service.WithPendingTransfers(repository.NewPendingTransferRepository(db), time.Hour),
+	)
 
 	// Handler
 	h := handler.NewHandler(svc)
@@ -163,15 +176,505 @@ func TestTransferAPI(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	// Expect 400 Bad Request or 500 Internal Error (depending on implementation) due to Insufficient Funds
-	// Checking the actual handler implementation in previous turns implies it returns error.
+	// Insufficient funds is a problem response with a stable code
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != handler.ProblemContentType {
+		t.Errorf("Expected Content-Type %s, got %s", handler.ProblemContentType, ct)
+	}
+	var problem handler.Problem
+	_ = json.Unmarshal(w.Body.Bytes(), &problem)
+	if problem.Code != "insufficient_funds" || problem.Status != http.StatusUnprocessableEntity {
+		t.Errorf("Unexpected problem: %+v", problem)
+	}
+}
+
+func TestDepositWithdrawAPI(t *testing.T) {
+	router := setupRouter(t)
+
+	userID := uuid.New()
+	createBody, _ := json.Marshal(map[string]string{"user_id": userID.String()})
+	createReq, _ := http.NewRequest("POST", "/wallets", bytes.NewBuffer(createBody))
+	createReq.Header.Set("Content-Type", "application/json")
+	wCreate := httptest.NewRecorder()
+	router.ServeHTTP(wCreate, createReq)
+
+	var wallet domain.Wallet
+	_ = json.Unmarshal(wCreate.Body.Bytes(), &wallet)
+	walletID := wallet.ID.String()
+
+	post := func(path string, amount int64) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]int64{"amount": amount})
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Deposit
+	w := post("/wallets/"+walletID+"/deposits", 500)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var deposit domain.Transaction
+	_ = json.Unmarshal(w.Body.Bytes(), &deposit)
+	if deposit.Type != domain.TransactionTypeDeposit || deposit.ReceiverID == nil || *deposit.ReceiverID != wallet.ID {
+		t.Errorf("Unexpected deposit transaction: %+v", deposit)
+	}
+
+	// Withdraw more than the balance
+	w = post("/wallets/"+walletID+"/withdrawals", 501)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422, got %d", w.Code)
+	}
+
+	// Withdraw part of the balance
+	w = post("/wallets/"+walletID+"/withdrawals", 200)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	// Check Balance
+	req, _ := http.NewRequest("GET", "/wallets/"+walletID, nil)
+	wGet := httptest.NewRecorder()
+	router.ServeHTTP(wGet, req)
+
+	var final domain.Wallet
+	_ = json.Unmarshal(wGet.Body.Bytes(), &final)
+	if final.Balance != 300 {
+		t.Errorf("Expected balance 300, got %d", final.Balance)
+	}
+}
+
+func TestIdempotentTransferAPI(t *testing.T) {
+	router := setupRouter(t)
+
+	createWallet := func() *domain.Wallet {
+		body, _ := json.Marshal(map[string]string{"user_id": uuid.New().String()})
+		req, _ := http.NewRequest("POST", "/wallets", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var wallet domain.Wallet
+		_ = json.Unmarshal(w.Body.Bytes(), &wallet)
+		return &wallet
+	}
+
+	sender := createWallet()
+	receiver := createWallet()
 
-	if w.Code != http.StatusBadRequest && w.Code != http.StatusInternalServerError {
-		// It might be 400 or 500 depending on how the handler maps "insufficient funds" error
-		// Ideally we manually check "insufficient funds" string in body
+	depositBody, _ := json.Marshal(map[string]int64{"amount": 100})
+	depositReq, _ := http.NewRequest("POST", "/wallets/"+sender.ID.String()+"/deposits", bytes.NewBuffer(depositBody))
+	router.ServeHTTP(httptest.NewRecorder(), depositReq)
+
+	key := uuid.NewString()
+	transfer := func(amount int64) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{
+			"sender_id":   sender.ID.String(),
+			"receiver_id": receiver.ID.String(),
+			"amount":      amount,
+		})
+		req, _ := http.NewRequest("POST", "/transfers", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := transfer(30)
+	if first.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", first.Code, first.Body.String())
+	}
+
+	retry := transfer(30)
+	if retry.Code != http.StatusOK {
+		t.Fatalf("Expected replayed status 200, got %d", retry.Code)
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected Idempotent-Replayed header on retry")
+	}
+	if !bytes.Equal(first.Body.Bytes(), retry.Body.Bytes()) {
+		t.Errorf("Expected identical bodies, got %s and %s", first.Body.String(), retry.Body.String())
+	}
+
+	conflict := transfer(40)
+	if conflict.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422, got %d", conflict.Code)
+	}
+
+	req, _ := http.NewRequest("GET", "/wallets/"+sender.ID.String(), nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var final domain.Wallet
+	_ = json.Unmarshal(w.Body.Bytes(), &final)
+	if final.Balance != 70 {
+		t.Errorf("Expected sender balance 70, got %d", final.Balance)
+	}
+}
+
+// doJSON sends a JSON request to router and returns the recorded response.
+func doJSON(router http.Handler, method, path string, payload interface{}) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	if payload != nil {
+		_ = json.NewEncoder(&body).Encode(payload)
+	}
+	req, _ := http.NewRequest(method, path, &body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestTransactionHistoryAPI(t *testing.T) {
+	router := setupRouter(t)
+
+	var wallet domain.Wallet
+	w := doJSON(router, "POST", "/wallets", map[string]string{"user_id": uuid.NewString()})
+	_ = json.Unmarshal(w.Body.Bytes(), &wallet)
+	base := "/wallets/" + wallet.ID.String()
+
+	for _, amount := range []int64{100, 200, 300} {
+		doJSON(router, "POST", base+"/deposits", map[string]int64{"amount": amount})
+	}
+
+	type page struct {
+		Transactions []domain.Transaction `json:"transactions"`
+		NextCursor   string               `json:"next_cursor"`
+	}
+
+	var first page
+	w = doJSON(router, "GET", base+"/transactions?limit=2", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &first)
+	if len(first.Transactions) != 2 || first.NextCursor == "" {
+		t.Fatalf("Expected 2 transactions and a cursor, got %d and %q", len(first.Transactions), first.NextCursor)
+	}
+	if first.Transactions[0].Amount != 300 {
+		t.Errorf("Expected newest transaction first, got amount %d", first.Transactions[0].Amount)
+	}
+
+	var second page
+	w = doJSON(router, "GET", base+"/transactions?limit=2&cursor="+first.NextCursor, nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &second)
+	if len(second.Transactions) != 1 || second.NextCursor != "" {
+		t.Errorf("Expected last page with 1 transaction, got %d and cursor %q", len(second.Transactions), second.NextCursor)
+	}
+
+	var filtered page
+	w = doJSON(router, "GET", base+"/transactions?direction=incoming&min_amount=150&max_amount=250", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &filtered)
+	if len(filtered.Transactions) != 1 || filtered.Transactions[0].Amount != 200 {
+		t.Errorf("Expected only the 200 deposit, got %+v", filtered.Transactions)
+	}
+
+	w = doJSON(router, "GET", base+"/transactions?direction=sideways", nil)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for unknown direction, got %d", w.Code)
+	}
+}
+
+func TestPendingTransferAPI(t *testing.T) {
+	router := setupRouter(t)
+
+	createWallet := func() domain.Wallet {
+		var wallet domain.Wallet
+		w := doJSON(router, "POST", "/wallets", map[string]string{"user_id": uuid.NewString()})
+		_ = json.Unmarshal(w.Body.Bytes(), &wallet)
+		return wallet
+	}
+	getWallet := func(id uuid.UUID) domain.Wallet {
+		var wallet domain.Wallet
+		w := doJSON(router, "GET", "/wallets/"+id.String(), nil)
+		_ = json.Unmarshal(w.Body.Bytes(), &wallet)
+		return wallet
+	}
+
+	sender := createWallet()
+	receiver := createWallet()
+	doJSON(router, "POST", "/wallets/"+sender.ID.String()+"/deposits", map[string]int64{"amount": 1000})
+
+	// Authorize
+	w := doJSON(router, "POST", "/pending-transfers", map[string]interface{}{
+		"sender_id":   sender.ID.String(),
+		"receiver_id": receiver.ID.String(),
+		"amount":      600,
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var pending domain.PendingTransfer
+	_ = json.Unmarshal(w.Body.Bytes(), &pending)
+
+	if got := getWallet(sender.ID); got.Balance != 1000 || got.Held != 600 {
+		t.Errorf("Expected balance 1000 with 600 held, got %d and %d", got.Balance, got.Held)
+	}
+
+	// Held funds cannot be withdrawn
+	w = doJSON(router, "POST", "/wallets/"+sender.ID.String()+"/withdrawals", map[string]int64{"amount": 500})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 when withdrawing held funds, got %d", w.Code)
+	}
+
+	// Partial capture releases the remainder
+	w = doJSON(router, "POST", "/pending-transfers/"+pending.ID.String()+"/capture", map[string]int64{"amount": 250})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	if got := getWallet(sender.ID); got.Balance != 750 || got.Held != 0 {
+		t.Errorf("Expected sender balance 750 with nothing held, got %d and %d", got.Balance, got.Held)
+	}
+	if got := getWallet(receiver.ID); got.Balance != 250 {
+		t.Errorf("Expected receiver balance 250, got %d", got.Balance)
+	}
+
+	// A settled transfer cannot be voided
+	w = doJSON(router, "POST", "/pending-transfers/"+pending.ID.String()+"/void", nil)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", w.Code)
+	}
+}
+
+func TestRefundAPI(t *testing.T) {
+	router := setupRouter(t)
+
+	createWallet := func() domain.Wallet {
+		var wallet domain.Wallet
+		w := doJSON(router, "POST", "/wallets", map[string]string{"user_id": uuid.NewString()})
+		_ = json.Unmarshal(w.Body.Bytes(), &wallet)
+		return wallet
+	}
+	getWallet := func(id uuid.UUID) domain.Wallet {
+		var wallet domain.Wallet
+		w := doJSON(router, "GET", "/wallets/"+id.String(), nil)
+		_ = json.Unmarshal(w.Body.Bytes(), &wallet)
+		return wallet
+	}
+
+	sender := createWallet()
+	receiver := createWallet()
+	doJSON(router, "POST", "/wallets/"+sender.ID.String()+"/deposits", map[string]int64{"amount": 1000})
+
+	w := doJSON(router, "POST", "/transfers", map[string]interface{}{
+		"sender_id":   sender.ID.String(),
+		"receiver_id": receiver.ID.String(),
+		"amount":      500,
+	})
+	var transfer domain.Transaction
+	_ = json.Unmarshal(w.Body.Bytes(), &transfer)
+
+	// Partial refund
+	w = doJSON(router, "POST", "/transfers/"+transfer.ID.String()+"/refunds", map[string]int64{"amount": 200})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var refund domain.Transaction
+	_ = json.Unmarshal(w.Body.Bytes(), &refund)
+	if refund.Type != domain.TransactionTypeRefund || refund.OriginalTransactionID == nil || *refund.OriginalTransactionID != transfer.ID {
+		t.Errorf("Expected a REFUND linked to %s, got %+v", transfer.ID, refund)
+	}
+
+	// Over-refund is refused
+	w = doJSON(router, "POST", "/transfers/"+transfer.ID.String()+"/refunds", map[string]int64{"amount": 400})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422, got %d", w.Code)
+	}
+
+	// An empty body refunds the remainder
+	w = doJSON(router, "POST", "/transfers/"+transfer.ID.String()+"/refunds", nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	if got := getWallet(sender.ID); got.Balance != 1000 {
+		t.Errorf("Expected sender balance 1000, got %d", got.Balance)
+	}
+	if got := getWallet(receiver.ID); got.Balance != 0 {
+		t.Errorf("Expected receiver balance 0, got %d", got.Balance)
+	}
+}
+
+func TestBatchTransferAPI(t *testing.T) {
+	router := setupRouter(t)
+
+	createWallet := func() domain.Wallet {
+		var wallet domain.Wallet
+		w := doJSON(router, "POST", "/wallets", map[string]string{"user_id": uuid.NewString()})
+		_ = json.Unmarshal(w.Body.Bytes(), &wallet)
+		return wallet
+	}
+	getWallet := func(id uuid.UUID) domain.Wallet {
+		var wallet domain.Wallet
+		w := doJSON(router, "GET", "/wallets/"+id.String(), nil)
+		_ = json.Unmarshal(w.Body.Bytes(), &wallet)
+		return wallet
+	}
+
+	payer := createWallet()
+	first := createWallet()
+	second := createWallet()
+	doJSON(router, "POST", "/wallets/"+payer.ID.String()+"/deposits", map[string]int64{"amount": 1000})
+
+	legs := []map[string]interface{}{
+		{"sender_id": payer.ID.String(), "receiver_id": first.ID.String(), "amount": 700},
+		{"sender_id": payer.ID.String(), "receiver_id": second.ID.String(), "amount": 500},
+	}
+
+	// Atomic: the second leg overdraws, so nothing commits
+	w := doJSON(router, "POST", "/transfers/batch", map[string]interface{}{"legs": legs})
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	if got := getWallet(payer.ID); got.Balance != 1000 {
+		t.Errorf("Expected payer balance 1000 after a failed atomic batch, got %d", got.Balance)
+	}
+
+	// Per leg: the first leg commits and the second is reported
+	w = doJSON(router, "POST", "/transfers/batch", map[string]interface{}{"mode": "per_leg", "legs": legs})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Results []struct {
+			Index  int    `json:"index"`
+			Status string `json:"status"`
+		} `json:"results"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Results) != 2 || resp.Results[0].Status != "committed" || resp.Results[1].Status != "failed" {
+		t.Errorf("Expected one committed and one failed leg, got %+v", resp.Results)
+	}
+	if got := getWallet(payer.ID); got.Balance != 300 {
+		t.Errorf("Expected payer balance 300, got %d", got.Balance)
+	}
+	if got := getWallet(first.ID); got.Balance != 700 {
+		t.Errorf("Expected first receiver balance 700, got %d", got.Balance)
+	}
+}
+
+func TestWalletETagAPI(t *testing.T) {
+	router := setupRouter(t)
+
+	var wallet domain.Wallet
+	w := doJSON(router, "POST", "/wallets", map[string]string{"user_id": uuid.NewString()})
+	_ = json.Unmarshal(w.Body.Bytes(), &wallet)
+
+	w = doJSON(router, "GET", "/wallets/"+wallet.ID.String(), nil)
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("Expected an ETag header")
+	}
+
+	deposit := func(ifMatch string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]int64{"amount": 100})
+		req, _ := http.NewRequest("POST", "/wallets/"+wallet.ID.String()+"/deposits", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", ifMatch)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if w := deposit(etag); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 with a current ETag, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	// The deposit changed the version, so the old ETag no longer matches
+	if w := deposit(etag); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected status 412 with a stale ETag, got %d", w.Code)
+	}
+
+	w = doJSON(router, "GET", "/wallets/"+wallet.ID.String(), nil)
+	if got := w.Header().Get("ETag"); got == etag {
+		t.Errorf("Expected the ETag to change after a deposit, still %s", got)
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &wallet)
+	if wallet.Balance != 100 {
+		t.Errorf("Expected balance 100, got %d", wallet.Balance)
+	}
+}
+
+func TestIfMatchOnEveryDebitAPI(t *testing.T) {
+	router := setupRouter(t)
+
+	createWallet := func() domain.Wallet {
+		var wallet domain.Wallet
+		w := doJSON(router, "POST", "/wallets", map[string]string{"user_id": uuid.NewString()})
+		_ = json.Unmarshal(w.Body.Bytes(), &wallet)
+		return wallet
+	}
+	etagOf := func(id uuid.UUID) string {
+		return doJSON(router, "GET", "/wallets/"+id.String(), nil).Header().Get("ETag")
+	}
+	doIfMatch := func(method, path, ifMatch string, payload interface{}) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req, _ := http.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", ifMatch)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	sender := createWallet()
+	receiver := createWallet()
+	stale := etagOf(sender.ID)
+	doJSON(router, "POST", "/wallets/"+sender.ID.String()+"/deposits", map[string]int64{"amount": 1000})
+
+	// Holds are placed on the sender
+	hold := map[string]interface{}{"sender_id": sender.ID.String(), "receiver_id": receiver.ID.String(), "amount": 100}
+	if w := doIfMatch("POST", "/pending-transfers", stale, hold); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected status 412 placing a hold with a stale ETag, got %d", w.Code)
+	}
+	w := doIfMatch("POST", "/pending-transfers", etagOf(sender.ID), hold)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var pending domain.PendingTransfer
+	_ = json.Unmarshal(w.Body.Bytes(), &pending)
+
+	// Capture and void check the wallet the hold is on
+	capturePath := "/pending-transfers/" + pending.ID.String() + "/capture"
+	if w := doIfMatch("POST", capturePath, stale, nil); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected status 412 capturing with a stale ETag, got %d", w.Code)
+	}
+	w = doIfMatch("POST", capturePath, etagOf(sender.ID), nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 capturing with a current ETag, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var capture struct {
+		Transaction domain.Transaction `json:"transaction"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &capture)
+
+	// A refund is paid by the original receiver
+	refundPath := "/transfers/" + capture.Transaction.ID.String() + "/refunds"
+	if w := doIfMatch("POST", refundPath, etagOf(sender.ID), nil); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected status 412 refunding with the sender's ETag, got %d", w.Code)
+	}
+	if w := doIfMatch("POST", refundPath, etagOf(receiver.ID), nil); w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201 refunding with the receiver's ETag, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	// Batches check their one sender
+	leg := map[string]interface{}{"sender_id": sender.ID.String(), "receiver_id": receiver.ID.String(), "amount": 10}
+	if w := doIfMatch("POST", "/transfers/batch", stale, map[string]interface{}{"legs": []interface{}{leg}}); w.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected status 412 for a batch with a stale ETag, got %d", w.Code)
+	}
+	other := map[string]interface{}{"sender_id": receiver.ID.String(), "receiver_id": sender.ID.String(), "amount": 10}
+	if w := doIfMatch("POST", "/transfers/batch", etagOf(sender.ID), map[string]interface{}{"legs": []interface{}{leg, other}}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for If-Match on a batch with two senders, got %d", w.Code)
+	}
+	if w := doIfMatch("POST", "/transfers/batch", etagOf(sender.ID), map[string]interface{}{"legs": []interface{}{leg}}); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for a batch with a current ETag, got %d. Body: %s", w.Code, w.Body.String())
 	}
 
-	if !bytes.Contains(w.Body.Bytes(), []byte("insufficient funds")) {
-		// Just listing this check; if it fails we'll see why
+	// Creating a wallet has no version to check
+	if w := doIfMatch("POST", "/wallets", stale, map[string]string{"user_id": uuid.NewString()}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for If-Match on wallet creation, got %d", w.Code)
 	}
 }
@@ -0,0 +1,258 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"digital-wallet/internal/handler"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// authRouter returns a router over in-memory repositories that requires the
+// tokens described by cfg.
+func authRouter(t *testing.T, cfg handler.AuthConfig) http.Handler {
+	auth, err := handler.NewAuthenticator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, _ := newMemoryService()
+	return handler.NewRouter(handler.NewHandler(svc), auth.Middleware)
+}
+
+// doAuthJSON is doJSON with a bearer token; an empty token sends none.
+func doAuthJSON(router http.Handler, token, method, path string, payload interface{}) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	if payload != nil {
+		_ = json.NewEncoder(&body).Encode(payload)
+	}
+	req, _ := http.NewRequest(method, path, &body)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, userID uuid.UUID) string {
+	token := jwt.NewWithClaims(method, jwt.RegisteredClaims{
+		Subject:   userID.String(),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestJWTOwnershipAPI(t *testing.T) {
+	secret := []byte("test-secret")
+	router := authRouter(t, handler.AuthConfig{HMACSecret: secret})
+
+	alice, bob := uuid.New(), uuid.New()
+	aliceToken := signToken(t, jwt.SigningMethodHS256, secret, "", alice)
+	bobToken := signToken(t, jwt.SigningMethodHS256, secret, "", bob)
+
+	createWallet := func(token string, userID uuid.UUID) domain.Wallet {
+		w := doAuthJSON(router, token, "POST", "/wallets", map[string]string{"user_id": userID.String()})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d. Body: %s", w.Code, w.Body.String())
+		}
+		var wallet domain.Wallet
+		_ = json.Unmarshal(w.Body.Bytes(), &wallet)
+		return wallet
+	}
+	aliceWallet := createWallet(aliceToken, alice)
+	bobWallet := createWallet(bobToken, bob)
+	doAuthJSON(router, aliceToken, "POST", "/wallets/"+aliceWallet.ID.String()+"/deposits", map[string]int64{"amount": 1000})
+
+	if w := doAuthJSON(router, "", "GET", "/wallets/"+aliceWallet.ID.String(), nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without a token, got %d", w.Code)
+	}
+	expired := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   alice.String(),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Hour)),
+	})
+	expiredToken, _ := expired.SignedString(secret)
+	if w := doAuthJSON(router, expiredToken, "GET", "/wallets/"+aliceWallet.ID.String(), nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for an expired token, got %d", w.Code)
+	}
+
+	if w := doAuthJSON(router, aliceToken, "GET", "/wallets/"+aliceWallet.ID.String(), nil); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for the owner, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	w := doAuthJSON(router, bobToken, "GET", "/wallets/"+aliceWallet.ID.String(), nil)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for another user, got %d", w.Code)
+	}
+	var problem handler.Problem
+	_ = json.Unmarshal(w.Body.Bytes(), &problem)
+	if problem.Code != "forbidden" {
+		t.Errorf("Expected code forbidden, got %q", problem.Code)
+	}
+
+	transfer := map[string]interface{}{
+		"sender_id":   aliceWallet.ID.String(),
+		"receiver_id": bobWallet.ID.String(),
+		"amount":      100,
+	}
+	if w := doAuthJSON(router, bobToken, "POST", "/transfers", transfer); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a transfer out of another user's wallet, got %d", w.Code)
+	}
+	if w := doAuthJSON(router, aliceToken, "POST", "/transfers", transfer); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for the owner's transfer, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	if w := doAuthJSON(router, aliceToken, "POST", "/wallets", map[string]string{"user_id": bob.String()}); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 creating a wallet for another user, got %d", w.Code)
+	}
+}
+
+func TestJWTJWKSAPI(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks, _ := json.Marshal(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"kid": "test-key",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	router := authRouter(t, handler.AuthConfig{JWKSFile: path})
+
+	userID := uuid.New()
+	token := signToken(t, jwt.SigningMethodRS256, key, "test-key", userID)
+	if w := doAuthJSON(router, token, "POST", "/wallets", map[string]string{"user_id": userID.String()}); w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	// HS256 is not accepted when only a key set is configured.
+	hsToken := signToken(t, jwt.SigningMethodHS256, []byte("test-secret"), "", userID)
+	if w := doAuthJSON(router, hsToken, "POST", "/wallets", map[string]string{"user_id": userID.String()}); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for an HS256 token, got %d", w.Code)
+	}
+}
+
+func TestJWTPendingTransferParties(t *testing.T) {
+	secret := []byte("test-secret")
+	router := authRouter(t, handler.AuthConfig{HMACSecret: secret})
+
+	customer, merchant, stranger := uuid.New(), uuid.New(), uuid.New()
+	customerToken := signToken(t, jwt.SigningMethodHS256, secret, "", customer)
+	merchantToken := signToken(t, jwt.SigningMethodHS256, secret, "", merchant)
+	strangerToken := signToken(t, jwt.SigningMethodHS256, secret, "", stranger)
+
+	createWallet := func(token string, userID uuid.UUID) domain.Wallet {
+		w := doAuthJSON(router, token, "POST", "/wallets", map[string]string{"user_id": userID.String()})
+		var wallet domain.Wallet
+		_ = json.Unmarshal(w.Body.Bytes(), &wallet)
+		return wallet
+	}
+	customerWallet := createWallet(customerToken, customer)
+	merchantWallet := createWallet(merchantToken, merchant)
+	doAuthJSON(router, customerToken, "POST", "/wallets/"+customerWallet.ID.String()+"/deposits", map[string]int64{"amount": 1000})
+
+	authorize := func() domain.PendingTransfer {
+		w := doAuthJSON(router, customerToken, "POST", "/pending-transfers", map[string]interface{}{
+			"sender_id":   customerWallet.ID.String(),
+			"receiver_id": merchantWallet.ID.String(),
+			"amount":      300,
+		})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d. Body: %s", w.Code, w.Body.String())
+		}
+		var pending domain.PendingTransfer
+		_ = json.Unmarshal(w.Body.Bytes(), &pending)
+		return pending
+	}
+
+	// The merchant captures the hold the customer placed.
+	pending := authorize()
+	capture := "/pending-transfers/" + pending.ID.String() + "/capture"
+	for name, token := range map[string]string{"sender": customerToken, "stranger": strangerToken} {
+		if w := doAuthJSON(router, token, "POST", capture, nil); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 for a capture by the %s, got %d. Body: %s", name, w.Code, w.Body.String())
+		}
+	}
+	w := doAuthJSON(router, merchantToken, "POST", capture, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for a capture by the receiver, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	w = doAuthJSON(router, merchantToken, "GET", "/wallets/"+merchantWallet.ID.String(), nil)
+	var wallet domain.Wallet
+	_ = json.Unmarshal(w.Body.Bytes(), &wallet)
+	if wallet.Balance != 300 {
+		t.Errorf("Expected the receiver to hold 300, got %d", wallet.Balance)
+	}
+
+	// Either party voids; nobody else does.
+	for name, token := range map[string]string{"sender": customerToken, "receiver": merchantToken} {
+		void := "/pending-transfers/" + authorize().ID.String() + "/void"
+		if w := doAuthJSON(router, strangerToken, "POST", void, nil); w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403 for a void by a stranger, got %d", w.Code)
+		}
+		if w := doAuthJSON(router, token, "POST", void, nil); w.Code != http.StatusOK {
+			t.Errorf("Expected status 200 for a void by the %s, got %d. Body: %s", name, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestIdempotencyKeyPerCaller(t *testing.T) {
+	svc, _ := newMemoryService()
+	ctx := context.Background()
+	render := func(tx *domain.Transaction) (int, []byte, error) {
+		return http.StatusOK, []byte(tx.ID.String()), nil
+	}
+
+	// Two users pick the same key for the same request; each gets a transfer.
+	var first *domain.IdempotencyKey
+	for _, user := range []uuid.UUID{uuid.New(), uuid.New()} {
+		caller := domain.WithCaller(ctx, user)
+		from, err := svc.CreateWallet(caller, user, "USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		to, err := svc.CreateWallet(ctx, uuid.New(), "USD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := svc.Deposit(caller, from.ID, domain.NewMoney(100, "USD")); err != nil {
+			t.Fatal(err)
+		}
+
+		record, replayed, err := svc.TransferMoneyIdempotent(caller, "order-1", "hash", from.ID, to.ID, domain.NewMoney(10, "USD"), render)
+		if err != nil || replayed {
+			t.Fatalf("Expected a new transfer for %s, got replayed=%v err=%v", user, replayed, err)
+		}
+		if first != nil && record.TransactionID == first.TransactionID {
+			t.Errorf("Expected a transfer of its own for %s, got the other caller's %s", user, first.TransactionID)
+		}
+		first = record
+	}
+}
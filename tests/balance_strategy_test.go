@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+
+	"digital-wallet/internal/domain"
+	"digital-wallet/internal/repository"
+	"digital-wallet/internal/service"
+	"digital-wallet/pkg/postgres"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+)
+
+func setupStrategyService(tb testing.TB, strategy service.BalanceStrategy) (*service.WalletService, domain.WalletRepository) {
+	_ = godotenv.Load("../.env")
+	pgDSN := os.Getenv("DATABASE_URL")
+	if pgDSN == "" {
+		pgDSN = "host=localhost user=postgres password=postgres dbname=wallet_db port=5432 sslmode=disable"
+	}
+	db, err := postgres.NewConnection(pgDSN)
+	if err != nil {
+		tb.Logf("DB not available, using in-memory repositories: %v", err)
+		return newMemoryService(service.WithBalanceStrategy(strategy))
+	}
+	if err := postgres.Migrate(context.Background(), db); err != nil {
+		tb.Fatal(err)
+	}
+	walletRepo := repository.NewWalletRepository(db)
+	svc := service.NewWalletService(walletRepo, repository.NewTransactionRepository(db), repository.NewLedgerRepository(db),
+		noopCache{}, &recordingProducer{}, service.WithBalanceStrategy(strategy))
+	return svc, walletRepo
+}
+
+func createFundedWallets(tb testing.TB, svc *service.WalletService, repo domain.WalletRepository, n int, balance int64) []uuid.UUID {
+	ctx := context.Background()
+	ids := make([]uuid.UUID, n)
+	for i := range ids {
+		wallet := &domain.Wallet{ID: uuid.New(), UserID: uuid.New(), Currency: domain.DefaultCurrency}
+		if err := repo.Create(ctx, wallet); err != nil {
+			tb.Fatalf("Failed to create wallet: %v", err)
+		}
+		if _, err := svc.Deposit(ctx, wallet.ID, domain.NewMoney(balance, domain.DefaultCurrency)); err != nil {
+			tb.Fatalf("Failed to fund wallet: %v", err)
+		}
+		ids[i] = wallet.ID
+	}
+	return ids
+}
+
+// TestConditionalTransfers runs opposing transfers between two wallets, so
+// that a wrong lock order would deadlock, while overdrawing one of them.
+func TestConditionalTransfers(t *testing.T) {
+	svc, repo := setupStrategyService(t, service.BalanceStrategyConditional)
+	ctx := context.Background()
+
+	ids := createFundedWallets(t, svc, repo, 2, 20)
+	a, b := ids[0], ids[1]
+	one := domain.NewMoney(1, domain.DefaultCurrency)
+
+	// A sends 50 and B sends 10: A can cover at most 30 of its transfers.
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var fromA, fromB, refused int
+	for i := 0; i < 60; i++ {
+		sender, receiver := a, b
+		if i%6 == 0 {
+			sender, receiver = b, a
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.TransferMoney(ctx, sender, receiver, one)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil && sender == a:
+				fromA++
+			case err == nil:
+				fromB++
+			case errors.Is(err, domain.ErrInsufficientFunds):
+				refused++
+			default:
+				t.Errorf("Transfer failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if fromB != 10 || fromA+refused != 50 || refused < 20 {
+		t.Errorf("Expected 10 transfers from B and at least 20 of 50 from A refused, got %d from B, %d from A, %d refused", fromB, fromA, refused)
+	}
+
+	expected := map[uuid.UUID]int64{a: int64(20 - fromA + fromB), b: int64(20 + fromA - fromB)}
+	for id, balance := range expected {
+		check, err := svc.VerifyWalletBalance(ctx, id)
+		if err != nil {
+			t.Fatalf("Failed to verify wallet %s: %v", id, err)
+		}
+		if !check.Consistent || check.Balance != balance {
+			t.Errorf("Expected wallet %s balance %d matching the ledger, got %+v", id, balance, check)
+		}
+	}
+}
+
+// BenchmarkBalanceStrategies transfers between random pairs of a small pool
+// of wallets with each balance update strategy.
+func BenchmarkBalanceStrategies(b *testing.B) {
+	for _, strategy := range []service.BalanceStrategy{service.BalanceStrategyPessimistic, service.BalanceStrategyConditional} {
+		b.Run(string(strategy), func(b *testing.B) {
+			svc, repo := setupStrategyService(b, strategy)
+			ctx := context.Background()
+			ids := createFundedWallets(b, svc, repo, 32, int64(b.N)+1)
+			one := domain.NewMoney(1, domain.DefaultCurrency)
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					i := rand.Intn(len(ids))
+					j := (i + 1 + rand.Intn(len(ids)-1)) % len(ids)
+					if _, err := svc.TransferMoney(ctx, ids[i], ids[j], one); err != nil {
+						b.Errorf("Transfer failed: %v", err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
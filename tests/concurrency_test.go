@@ -42,6 +42,9 @@ func TestConcurrentTransfers(t *testing.T) {
 	if err != nil {
 		t.Skipf("Skipping integration test (DB not available): %v", err)
 	}
+	if err := postgres.Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
 	rdb, err := redis.NewClient(redisAddr, "", 0)
 	if err != nil {
 		t.Skipf("Skipping integration test (Redis not available): %v", err)
@@ -55,9 +58,10 @@ func TestConcurrentTransfers(t *testing.T) {
 	// Setup Components
 	walletRepo := repository.NewWalletRepository(db)
 	transRepo := repository.NewTransactionRepository(db)
+	ledgerRepo := repository.NewLedgerRepository(db)
 	cacheRepo := repository.NewCacheRepository(rdb)
 	eventProducer := repository.NewEventProducer(mq)
-	svc := service.NewWalletService(walletRepo, transRepo, cacheRepo, eventProducer)
+	svc := service.NewWalletService(walletRepo, transRepo, ledgerRepo, cacheRepo, eventProducer)
 
 	ctx := context.Background()
 
@@ -69,19 +73,20 @@ func TestConcurrentTransfers(t *testing.T) {
 	user1 := uuid.New()
 	user2 := uuid.New()
 
-	// Direct DB Insert to set initial balance
 	walletA := &domain.Wallet{
-		ID:      senderID,
-		UserID:  user1,
-		Balance: 1000, // 10.00
+		ID:       senderID,
+		UserID:   user1,
+		Currency: domain.DefaultCurrency,
 	}
 	walletB := &domain.Wallet{
-		ID:      receiverID,
-		UserID:  user2,
-		Balance: 0,
+		ID:       receiverID,
+		UserID:   user2,
+		Balance:  0,
+		Currency: domain.DefaultCurrency,
 	}
 
 	// Cleanup old data if any (optional, purely for local dev re-runs with same IDs)
+	db.Exec("DELETE FROM ledger_entries WHERE transaction_id IN (SELECT id FROM transactions WHERE sender_id = ? OR receiver_id = ?)", senderID, receiverID)
 	db.Exec("DELETE FROM transactions WHERE sender_id = ? OR receiver_id = ?", senderID, receiverID)
 	db.Delete(&domain.Wallet{}, "id = ?", senderID)
 	db.Delete(&domain.Wallet{}, "id = ?", receiverID)
@@ -93,10 +98,15 @@ func TestConcurrentTransfers(t *testing.T) {
 		t.Fatalf("Failed to create wallet B: %v", err)
 	}
 
+	// Fund wallet A through the ledger
+	if _, err := svc.Deposit(ctx, senderID, domain.NewMoney(1000, domain.DefaultCurrency)); err != nil { // 10.00
+		t.Fatalf("Failed to fund wallet A: %v", err)
+	}
+
 	// Run Concurrency Test
 	// Scenario: 50 concurrent transfers of 1 cent from A to B.
 	concurrentCount := 50
-	amount := int64(1)
+	amount := domain.NewMoney(1, domain.DefaultCurrency)
 
 	var wg sync.WaitGroup
 	wg.Add(concurrentCount)
@@ -146,5 +156,16 @@ func TestConcurrentTransfers(t *testing.T) {
 		t.Errorf("Expected Wallet B balance %d, got %d", expectedB, finalB.Balance)
 	}
 
+	// The balance projections must match the ledger
+	for _, id := range []uuid.UUID{senderID, receiverID} {
+		check, err := svc.VerifyWalletBalance(ctx, id)
+		if err != nil {
+			t.Fatalf("Failed to verify wallet %s: %v", id, err)
+		}
+		if !check.Consistent {
+			t.Errorf("Wallet %s balance %d does not match ledger balance %d", id, check.Balance, check.LedgerBalance)
+		}
+	}
+
 	fmt.Printf("Test Passed! Final Balances: A=%d, B=%d\n", finalA.Balance, finalB.Balance)
 }
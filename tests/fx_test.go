@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"testing"
+
+	"digital-wallet/internal/domain"
+	"digital-wallet/internal/repository"
+	"digital-wallet/internal/repository/memory"
+	"digital-wallet/internal/service"
+	"digital-wallet/pkg/postgres"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+)
+
+func TestStaticRateProviderAndConversion(t *testing.T) {
+	rates, err := repository.NewStaticRateProvider(map[string]string{
+		"USD/JPY": "150",
+		"USD/KWD": "0.3",
+	})
+	if err != nil {
+		t.Fatalf("Failed to build rate provider: %v", err)
+	}
+	ctx := context.Background()
+
+	// 12.34 USD -> 1851 JPY (JPY has no minor units)
+	rate, err := rates.Rate(ctx, "USD", "JPY")
+	if err != nil {
+		t.Fatalf("Rate failed: %v", err)
+	}
+	got, err := domain.NewMoney(1234, "USD").Convert("JPY", rate)
+	if err != nil || got.Amount != 1851 || got.Currency != "JPY" {
+		t.Errorf("Expected 1851 JPY, got %v (%v)", got, err)
+	}
+
+	// 1000 JPY -> 6.66 USD through the inverse rate, rounded down
+	rate, err = rates.Rate(ctx, "JPY", "USD")
+	if err != nil {
+		t.Fatalf("Inverse rate failed: %v", err)
+	}
+	got, _ = domain.NewMoney(1000, "JPY").Convert("USD", rate)
+	if got.Amount != 666 {
+		t.Errorf("Expected 666 USD cents, got %d", got.Amount)
+	}
+
+	// 1.00 USD -> 0.300 KWD (three minor digits)
+	rate, _ = rates.Rate(ctx, "USD", "KWD")
+	got, _ = domain.NewMoney(100, "USD").Convert("KWD", rate)
+	if got.Amount != 300 {
+		t.Errorf("Expected 300 fils, got %d", got.Amount)
+	}
+
+	if _, err := rates.Rate(ctx, "EUR", "GBP"); !errors.Is(err, domain.ErrRateUnavailable) {
+		t.Errorf("Expected ErrRateUnavailable, got %v", err)
+	}
+
+	// Fees round up to the next minor unit
+	if fee, err := domain.NewMoney(1001, "USD").BasisPoints(50); err != nil || fee.Amount != 6 {
+		t.Errorf("Expected fee of 6 cents, got %v (%v)", fee, err)
+	}
+}
+
+func TestBasisPoints(t *testing.T) {
+	const huge = math.MaxInt64 / 100
+
+	tests := []struct {
+		name   string
+		amount int64
+		bps    int64
+		want   int64
+	}{
+		{"no fee", 1001, 0, 0},
+		{"whole amount", 1001, 10_000, 1001},
+		{"large amount, no fee", huge, 0, 0},
+		{"large amount, whole amount", huge, 10_000, huge},
+		{"large amount, 0.50%", huge, 50, huge/200 + 1},
+		{"max amount", math.MaxInt64, 10_000, math.MaxInt64},
+	}
+	for _, tt := range tests {
+		fee, err := domain.NewMoney(tt.amount, "USD").BasisPoints(tt.bps)
+		if err != nil || fee.Amount != tt.want {
+			t.Errorf("%s: expected %d, got %v (%v)", tt.name, tt.want, fee, err)
+		}
+	}
+
+	if _, err := domain.NewMoney(math.MaxInt64, "USD").BasisPoints(10_001); !errors.Is(err, domain.ErrInvalidAmount) {
+		t.Errorf("Expected ErrInvalidAmount for a fee that overflows, got %v", err)
+	}
+}
+
+func setupFXService(t *testing.T) *service.WalletService {
+	_ = godotenv.Load("../.env")
+	pgDSN := os.Getenv("DATABASE_URL")
+	if pgDSN == "" {
+		pgDSN = "host=localhost user=postgres password=postgres dbname=wallet_db port=5432 sslmode=disable"
+	}
+	rates, err := repository.NewStaticRateProvider(map[string]string{"USD/EUR": "0.9"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := postgres.NewConnection(pgDSN)
+	if err != nil {
+		t.Logf("DB not available, using in-memory repositories: %v", err)
+		store := memory.NewStore()
+		return service.NewWalletService(memory.NewWalletRepository(store), memory.NewTransactionRepository(store), memory.NewLedgerRepository(store),
+			memory.NewCacheRepository(), memory.NewEventProducer(),
+			service.WithFX(memory.NewQuoteRepository(store), rates, service.FXConfig{}))
+	}
+	return service.NewWalletService(repository.NewWalletRepository(db), repository.NewTransactionRepository(db), repository.NewLedgerRepository(db),
+		noopCache{}, &recordingProducer{},
+		service.WithFX(repository.NewQuoteRepository(db), rates, service.FXConfig{}))
+}
+
+func TestQuoteBoundToSender(t *testing.T) {
+	svc := setupFXService(t)
+	ctx := context.Background()
+
+	alice, _ := svc.CreateWallet(ctx, uuid.New(), "USD")
+	mallory, _ := svc.CreateWallet(ctx, uuid.New(), "USD")
+	euro, _ := svc.CreateWallet(ctx, uuid.New(), "EUR")
+	for _, id := range []uuid.UUID{alice.ID, mallory.ID} {
+		if _, err := svc.Deposit(ctx, id, domain.NewMoney(10000, "USD")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Nobody can request a quote for someone else's wallet.
+	_, err := svc.CreateQuote(domain.WithCaller(ctx, mallory.UserID), alice.ID, domain.NewMoney(1000, "USD"), "EUR")
+	if !errors.Is(err, domain.ErrForbidden) {
+		t.Errorf("Expected ErrForbidden quoting for another user's wallet, got %v", err)
+	}
+
+	quote, err := svc.CreateQuote(domain.WithCaller(ctx, alice.UserID), alice.ID, domain.NewMoney(1000, "USD"), "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.SenderID != alice.ID {
+		t.Errorf("Expected the quote to be bound to %s, got %s", alice.ID, quote.SenderID)
+	}
+
+	// Another wallet cannot use it.
+	_, err = svc.TransferWithQuote(ctx, mallory.ID, euro.ID, quote.ID)
+	if !errors.Is(err, domain.ErrQuoteNotFound) {
+		t.Errorf("Expected ErrQuoteNotFound using another wallet's quote, got %v", err)
+	}
+
+	tx, err := svc.TransferWithQuote(ctx, alice.ID, euro.ID, quote.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.QuoteID == nil || *tx.QuoteID != quote.ID {
+		t.Errorf("Expected the transfer to use quote %s, got %+v", quote.ID, tx)
+	}
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"digital-wallet/internal/domain"
+	"digital-wallet/internal/repository"
+	"digital-wallet/internal/service"
+	"digital-wallet/pkg/postgres"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+)
+
+// BenchmarkHotWalletTransfers sends transfers from many wallets to a single
+// receiver in parallel, once with a plain receiver and once with a striped
+// one. Only Postgres is required.
+func BenchmarkHotWalletTransfers(b *testing.B) {
+	_ = godotenv.Load("../.env")
+	pgDSN := os.Getenv("DATABASE_URL")
+	if pgDSN == "" {
+		pgDSN = "host=localhost user=postgres password=postgres dbname=wallet_db port=5432 sslmode=disable"
+	}
+	db, err := postgres.NewConnection(pgDSN)
+	if err != nil {
+		b.Skipf("Skipping benchmark (DB not available): %v", err)
+	}
+	if err := postgres.Migrate(context.Background(), db); err != nil {
+		b.Fatal(err)
+	}
+
+	ctx := context.Background()
+	walletRepo := repository.NewWalletRepository(db)
+	svc := service.NewWalletService(walletRepo, repository.NewTransactionRepository(db), repository.NewLedgerRepository(db),
+		noopCache{}, &recordingProducer{}, service.WithWalletStriping())
+
+	const senders = 64
+
+	for _, stripes := range []int{0, 16} {
+		b.Run(fmt.Sprintf("stripes=%d", stripes), func(b *testing.B) {
+			receiver := &domain.Wallet{ID: uuid.New(), UserID: uuid.New(), Currency: domain.DefaultCurrency}
+			if err := walletRepo.Create(ctx, receiver); err != nil {
+				b.Fatalf("Failed to create receiver: %v", err)
+			}
+			if stripes > 0 {
+				if _, err := svc.SetWalletStripes(ctx, receiver.ID, stripes); err != nil {
+					b.Fatalf("Failed to stripe receiver: %v", err)
+				}
+			}
+
+			senderIDs := make([]uuid.UUID, senders)
+			for i := range senderIDs {
+				sender := &domain.Wallet{ID: uuid.New(), UserID: uuid.New(), Currency: domain.DefaultCurrency}
+				if err := walletRepo.Create(ctx, sender); err != nil {
+					b.Fatalf("Failed to create sender: %v", err)
+				}
+				if _, err := svc.Deposit(ctx, sender.ID, domain.NewMoney(int64(b.N)+1, domain.DefaultCurrency)); err != nil {
+					b.Fatalf("Failed to fund sender: %v", err)
+				}
+				senderIDs[i] = sender.ID
+			}
+
+			var next atomic.Int64
+			b.SetParallelism(4)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				senderID := senderIDs[next.Add(1)%senders]
+				for pb.Next() {
+					if _, err := svc.TransferMoney(ctx, senderID, receiver.ID, domain.NewMoney(1, domain.DefaultCurrency)); err != nil {
+						b.Errorf("Transfer failed: %v", err)
+						return
+					}
+				}
+			})
+			b.StopTimer()
+
+			check, err := svc.VerifyWalletBalance(ctx, receiver.ID)
+			if err != nil {
+				b.Fatalf("Failed to verify receiver: %v", err)
+			}
+			if !check.Consistent || check.Balance != int64(b.N) {
+				b.Errorf("Expected a consistent balance of %d, got %+v", b.N, check)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"digital-wallet/internal/domain"
+	"digital-wallet/internal/handler"
+	"digital-wallet/internal/repository/memory"
+	"digital-wallet/internal/service"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestWalletClosure(t *testing.T) {
+	store := memory.NewStore()
+	walletRepo := memory.NewWalletRepository(store)
+	events := memory.NewEventProducer()
+	svc := service.NewWalletService(walletRepo, memory.NewTransactionRepository(store), memory.NewLedgerRepository(store),
+		memory.NewCacheRepository(), events,
+		service.WithAdmin(memory.NewAdminAuditRepository(store)), service.WithWalletStriping())
+	ctx := context.Background()
+	usd := func(amount int64) domain.Money { return domain.NewMoney(amount, domain.DefaultCurrency) }
+
+	wallet, _ := svc.CreateWallet(ctx, uuid.New(), domain.DefaultCurrency)
+	payer, _ := svc.CreateWallet(ctx, uuid.New(), domain.DefaultCurrency)
+	safe, _ := svc.CreateWallet(ctx, uuid.New(), domain.DefaultCurrency)
+	euro, _ := svc.CreateWallet(ctx, uuid.New(), "EUR")
+	if _, err := svc.Deposit(ctx, wallet.ID, usd(300)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Deposit(ctx, payer.ID, usd(1000)); err != nil {
+		t.Fatal(err)
+	}
+	// Part of the balance sits on a stripe.
+	if _, err := svc.SetWalletStripes(ctx, wallet.ID, 4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.TransferMoney(ctx, payer.ID, wallet.ID, usd(200)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := svc.CloseWallet(ctx, wallet.ID, nil); !errors.Is(err, domain.ErrWalletNotEmpty) {
+		t.Fatalf("Expected ErrWalletNotEmpty without a sweep wallet, got %v", err)
+	}
+	if _, _, err := svc.CloseWallet(ctx, wallet.ID, &euro.ID); !errors.Is(err, domain.ErrCurrencyMismatch) {
+		t.Fatalf("Expected ErrCurrencyMismatch for a EUR sweep wallet, got %v", err)
+	}
+
+	closed, sweep, err := svc.CloseWallet(ctx, wallet.ID, &safe.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if closed.Status != domain.WalletStatusClosed || closed.Balance != 0 {
+		t.Errorf("Expected a closed, empty wallet, got %+v", closed)
+	}
+	if sweep == nil || sweep.Type != domain.TransactionTypeSweep || sweep.Amount != 500 || *sweep.ReceiverID != safe.ID {
+		t.Fatalf("Expected a sweep of 500 to the safe wallet, got %+v", sweep)
+	}
+	if got, _ := walletRepo.GetByID(ctx, safe.ID); got.Balance != 500 {
+		t.Errorf("Expected the safe wallet to hold 500, got %d", got.Balance)
+	}
+	check, err := svc.VerifyWalletBalance(ctx, wallet.ID)
+	if err != nil || !check.Consistent {
+		t.Errorf("Expected the closed wallet to match its ledger, got %+v, %v", check, err)
+	}
+
+	// Closed is terminal and takes no money in either direction.
+	for name, err := range map[string]error{
+		"deposit":  second(svc.Deposit(ctx, wallet.ID, usd(100))),
+		"withdraw": second(svc.Withdraw(ctx, wallet.ID, usd(100))),
+		"transfer": second(svc.TransferMoney(ctx, payer.ID, wallet.ID, usd(100))),
+	} {
+		if !errors.Is(err, domain.ErrWalletClosed) {
+			t.Errorf("%s: expected ErrWalletClosed, got %v", name, err)
+		}
+	}
+	staff := domain.AdminActor{ID: "test", Role: domain.AdminRoleOperator}
+	if _, err := svc.FreezeWallet(ctx, staff, wallet.ID, ""); !errors.Is(err, domain.ErrInvalidTransition) {
+		t.Errorf("Expected ErrInvalidTransition freezing a closed wallet, got %v", err)
+	}
+	if _, _, err := svc.CloseWallet(ctx, wallet.ID, nil); !errors.Is(err, domain.ErrStatusUnchanged) {
+		t.Errorf("Expected ErrStatusUnchanged closing twice, got %v", err)
+	}
+
+	published := events.WalletStatusEvents()
+	if len(published) != 1 {
+		t.Fatalf("Expected one status event, got %+v", published)
+	}
+	event := published[0]
+	if event.WalletID != wallet.ID || event.From != domain.WalletStatusActive || event.To != domain.WalletStatusClosed ||
+		event.SweepTransactionID == nil || *event.SweepTransactionID != sweep.ID {
+		t.Errorf("Unexpected status event %+v", event)
+	}
+}
+
+// second returns the error of a call that also returns a value.
+func second[T any](_ T, err error) error {
+	return err
+}
+
+func TestFreezePolicy(t *testing.T) {
+	for _, strategy := range []service.BalanceStrategy{service.BalanceStrategyPessimistic, service.BalanceStrategyConditional} {
+		t.Run(string(strategy), func(t *testing.T) {
+			svc, walletRepo := newMemoryService(service.WithFreezePolicy(service.FreezeAll), service.WithBalanceStrategy(strategy))
+			ctx := context.Background()
+			staff := domain.AdminActor{ID: "test", Role: domain.AdminRoleOperator}
+			usd := func(amount int64) domain.Money { return domain.NewMoney(amount, domain.DefaultCurrency) }
+
+			sender, _ := svc.CreateWallet(ctx, uuid.New(), domain.DefaultCurrency)
+			frozen, _ := svc.CreateWallet(ctx, uuid.New(), domain.DefaultCurrency)
+			if _, err := svc.Deposit(ctx, sender.ID, usd(500)); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := svc.FreezeWallet(ctx, staff, frozen.ID, ""); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := svc.Deposit(ctx, frozen.ID, usd(100)); !errors.Is(err, domain.ErrWalletFrozen) {
+				t.Errorf("Expected ErrWalletFrozen for a deposit, got %v", err)
+			}
+			if _, err := svc.TransferMoney(ctx, sender.ID, frozen.ID, usd(100)); !errors.Is(err, domain.ErrWalletFrozen) {
+				t.Errorf("Expected ErrWalletFrozen for a transfer, got %v", err)
+			}
+			if got, _ := walletRepo.GetByID(ctx, sender.ID); got.Balance != 500 {
+				t.Errorf("Expected the transfer to be rolled back, sender balance is %d", got.Balance)
+			}
+		})
+	}
+}
+
+func TestCloseWalletAPI(t *testing.T) {
+	secret := []byte("test-secret")
+	auth, err := handler.NewAuthenticator(handler.AuthConfig{HMACSecret: secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, _ := newMemoryService()
+	router := handler.NewRouter(handler.NewHandler(svc), auth.Middleware)
+
+	alice := uuid.New()
+	aliceToken := signToken(t, jwt.SigningMethodHS256, secret, "", alice)
+	operator := signStaffToken(t, secret, uuid.New(), "operator")
+
+	createWallet := func() domain.Wallet {
+		w := doAuthJSON(router, aliceToken, "POST", "/wallets", map[string]string{"user_id": alice.String()})
+		var wallet domain.Wallet
+		_ = json.Unmarshal(w.Body.Bytes(), &wallet)
+		return wallet
+	}
+	empty, compromised, safe := createWallet(), createWallet(), createWallet()
+	doAuthJSON(router, aliceToken, "POST", "/wallets/"+compromised.ID.String()+"/deposits", map[string]int64{"amount": 700})
+
+	// An empty wallet is closed by its owner without a sweep.
+	w := doAuthJSON(router, aliceToken, "POST", "/wallets/"+empty.ID.String()+"/close", nil)
+	var resp handler.CloseWalletResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Wallet.Status != domain.WalletStatusClosed || resp.Sweep != nil {
+		t.Fatalf("Expected the empty wallet to close, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	w = doAuthJSON(router, aliceToken, "POST", "/wallets/"+empty.ID.String()+"/deposits", map[string]int64{"amount": 100})
+	var problem handler.Problem
+	_ = json.Unmarshal(w.Body.Bytes(), &problem)
+	if w.Code != http.StatusConflict || problem.Code != "wallet_closed" {
+		t.Errorf("Expected 409 wallet_closed for a deposit, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	// A frozen wallet is closed by staff, who sweep its balance.
+	doAuthJSON(router, operator, "POST", "/admin/wallets/"+compromised.ID.String()+"/freeze", nil)
+	w = doAuthJSON(router, aliceToken, "POST", "/wallets/"+compromised.ID.String()+"/close",
+		map[string]string{"sweep_to": safe.ID.String()})
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected the owner to be refused closing a frozen wallet, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	w = doAuthJSON(router, operator, "POST", "/admin/wallets/"+compromised.ID.String()+"/close",
+		map[string]string{"sweep_to": safe.ID.String(), "note": "account takeover"})
+	resp = handler.CloseWalletResponse{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	if w.Code != http.StatusOK || resp.Sweep == nil || resp.Sweep.Amount != 700 {
+		t.Fatalf("Expected a sweep of 700, got %d. Body: %s", w.Code, w.Body.String())
+	}
+
+	entries, err := svc.AdminAuditLog(context.Background(), domain.AdminActor{ID: "test", Role: domain.AdminRoleViewer},
+		domain.AdminAuditFilter{WalletID: &compromised.ID, Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Action != domain.AdminActionClose || entries[0].TransactionID == nil ||
+		*entries[0].TransactionID != resp.Sweep.ID || entries[0].Note != "account takeover" {
+		t.Errorf("Expected the closure to be audited with its sweep, got %+v", entries)
+	}
+}
@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"digital-wallet/internal/handler"
+	"digital-wallet/internal/repository/memory"
+	"digital-wallet/internal/service"
+
+	"github.com/google/uuid"
+)
+
+// newMemoryService wires the service to in-memory repositories, for tests
+// that run without Postgres, Redis and RabbitMQ.
+func newMemoryService(opts ...service.Option) (*service.WalletService, domain.WalletRepository) {
+	store := memory.NewStore()
+	walletRepo := memory.NewWalletRepository(store)
+	opts = append([]service.Option{
+		service.WithIdempotency(memory.NewIdempotencyRepository(store), time.Hour),
+		service.WithPendingTransfers(memory.NewPendingTransferRepository(store), time.Hour),
+		service.WithAPIKeys(memory.NewAPIKeyRepository(store)),
+		service.WithAdmin(memory.NewAdminAuditRepository(store)),
+	}, opts...)
+	svc := service.NewWalletService(walletRepo, memory.NewTransactionRepository(store), memory.NewLedgerRepository(store),
+		memory.NewCacheRepository(), memory.NewEventProducer(), opts...)
+	return svc, walletRepo
+}
+
+func memoryRouter() http.Handler {
+	svc, _ := newMemoryService()
+	return handler.NewRouter(handler.NewHandler(svc))
+}
+
+func TestMemoryWithTxRollback(t *testing.T) {
+	store := memory.NewStore()
+	repo := memory.NewWalletRepository(store)
+	ctx := context.Background()
+
+	wallet := &domain.Wallet{UserID: uuid.New()}
+	if err := repo.Create(ctx, wallet); err != nil {
+		t.Fatalf("Failed to create wallet: %v", err)
+	}
+
+	errAbort := errors.New("abort")
+	err := repo.WithTx(ctx, func(ctx context.Context) error {
+		if err := repo.AdjustBalance(ctx, wallet.ID, 100); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("Expected the abort error, got %v", err)
+	}
+
+	got, err := repo.GetByID(ctx, wallet.ID)
+	if err != nil {
+		t.Fatalf("Failed to read wallet: %v", err)
+	}
+	if got.Balance != 0 || got.Version != 0 {
+		t.Errorf("Expected the rollback to restore balance 0 version 0, got %d version %d", got.Balance, got.Version)
+	}
+
+	// The check constraint rejects overdrafts.
+	if err := repo.AdjustBalance(ctx, wallet.ID, -1); err == nil {
+		t.Error("Expected a negative balance to be rejected")
+	}
+}
+
+func TestMemoryRowLock(t *testing.T) {
+	store := memory.NewStore()
+	repo := memory.NewWalletRepository(store)
+	ctx := context.Background()
+
+	wallet := &domain.Wallet{UserID: uuid.New()}
+	if err := repo.Create(ctx, wallet); err != nil {
+		t.Fatalf("Failed to create wallet: %v", err)
+	}
+
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- repo.WithTx(ctx, func(ctx context.Context) error {
+			if _, err := repo.GetByIDWithLock(ctx, wallet.ID); err != nil {
+				return err
+			}
+			close(locked)
+			<-release
+			return repo.AdjustBalance(ctx, wallet.ID, 10)
+		})
+	}()
+	<-locked
+
+	// A second locker waits for the first transaction to commit.
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := repo.GetByIDWithLock(waitCtx, wallet.ID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the lock to be held, got %v", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Locking transaction failed: %v", err)
+	}
+	got, err := repo.GetByIDWithLock(ctx, wallet.ID)
+	if err != nil {
+		t.Fatalf("Failed to lock wallet after commit: %v", err)
+	}
+	if got.Balance != 10 {
+		t.Errorf("Expected balance 10, got %d", got.Balance)
+	}
+}
+
+func TestMemoryConcurrentTransfers(t *testing.T) {
+	svc, repo := newMemoryService()
+	ctx := context.Background()
+
+	ids := createFundedWallets(t, svc, repo, 2, 1000)
+	one := domain.NewMoney(1, domain.DefaultCurrency)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			from, to := ids[i%2], ids[(i+1)%2]
+			if _, err := svc.TransferMoney(ctx, from, to, one); err != nil {
+				t.Errorf("Transfer %d failed: %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, id := range ids {
+		check, err := svc.VerifyWalletBalance(ctx, id)
+		if err != nil {
+			t.Fatalf("Failed to verify wallet: %v", err)
+		}
+		if check.Balance != 1000 || !check.Consistent {
+			t.Errorf("Expected balance 1000 matching the ledger, got %+v", check)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"digital-wallet/pkg/postgres"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+)
+
+// autoMigratedTransaction is the transactions model the first AutoMigrate
+// created the table from. Columns added to the model later were appended
+// after created_at.
+type autoMigratedTransaction struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	SenderID   *uuid.UUID `gorm:"type:uuid"`
+	ReceiverID *uuid.UUID `gorm:"type:uuid"`
+	Amount     int64      `gorm:"not null"`
+	Type       string     `gorm:"not null"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+}
+
+func (autoMigratedTransaction) TableName() string { return "transactions" }
+
+// TestMigrateAutoMigratedSchema runs the migrations up and down on a
+// database whose transactions table was built by AutoMigrate, in a schema of
+// its own.
+func TestMigrateAutoMigratedSchema(t *testing.T) {
+	_ = godotenv.Load("../.env")
+	pgDSN := os.Getenv("DATABASE_URL")
+	if pgDSN == "" {
+		pgDSN = "host=localhost user=postgres password=postgres dbname=wallet_db port=5432 sslmode=disable"
+	}
+	admin, err := postgres.NewConnection(pgDSN)
+	if err != nil {
+		t.Skipf("Skipping migration test (DB not available): %v", err)
+	}
+	schema := "migrate_test_" + uuid.NewString()[:8]
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Skipf("Skipping migration test (cannot create schema): %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	db, err := postgres.NewConnection(pgDSN + " search_path=" + schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// The table as AutoMigrate left it: created from the first model, then
+	// extended with the later columns.
+	if err := db.AutoMigrate(&autoMigratedTransaction{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&domain.Transaction{}); err != nil {
+		t.Fatal(err)
+	}
+	var lastColumn string
+	err = db.Raw(`SELECT column_name FROM information_schema.columns
+		WHERE table_schema = ? AND table_name = 'transactions' ORDER BY ordinal_position DESC LIMIT 1`, schema).Scan(&lastColumn).Error
+	if err != nil || lastColumn == "created_at" {
+		t.Fatalf("expected columns appended after created_at, last column is %q (%v)", lastColumn, err)
+	}
+
+	quoteID, originalID := uuid.New(), uuid.New()
+	want := domain.Transaction{
+		Amount:                500,
+		Currency:              "EUR",
+		Type:                  domain.TransactionTypeRefund,
+		QuoteID:               &quoteID,
+		RefundedAmount:        0,
+		OriginalTransactionID: &originalID,
+		CreatedAt:             time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if err := db.Create(&want).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := postgres.NewMigrator(sqlDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(stage string) {
+		t.Helper()
+		var got domain.Transaction
+		if err := db.First(&got, "id = ?", want.ID).Error; err != nil {
+			t.Fatalf("%s: %v", stage, err)
+		}
+		if got.Amount != want.Amount || got.Currency != want.Currency || got.Type != want.Type ||
+			got.QuoteID == nil || *got.QuoteID != quoteID ||
+			got.OriginalTransactionID == nil || *got.OriginalTransactionID != originalID ||
+			!got.CreatedAt.Equal(want.CreatedAt) {
+			t.Fatalf("%s: transaction changed to %+v", stage, got)
+		}
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	check("up")
+
+	migrations, err := postgres.Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Down to before the partitioning migration.
+	if _, err := migrator.Down(ctx, len(migrations)-1); err != nil {
+		t.Fatal(err)
+	}
+	check(fmt.Sprintf("down to %04d", migrations[0].Version))
+}
@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"digital-wallet/internal/domain"
+)
+
+func TestMoneyDecimal(t *testing.T) {
+	cases := []struct {
+		money domain.Money
+		want  string
+	}{
+		{domain.NewMoney(1050, "USD"), "10.50"},
+		{domain.NewMoney(5, "USD"), "0.05"},
+		{domain.NewMoney(-5, "USD"), "-0.05"},
+		{domain.NewMoney(1050, "JPY"), "1050"},
+		{domain.NewMoney(1050, "KWD"), "1.050"},
+		{domain.NewMoney(7, "KWD"), "0.007"},
+		{domain.NewMoney(0, "EUR"), "0.00"},
+	}
+	for _, c := range cases {
+		if got := c.money.Decimal(); got != c.want {
+			t.Errorf("%d %s: expected %q, got %q", c.money.Amount, c.money.Currency, c.want, got)
+		}
+	}
+}
+
+func TestParseCurrency(t *testing.T) {
+	c, err := domain.ParseCurrency("jpy")
+	if err != nil || c != "JPY" || c.Exponent() != 0 {
+		t.Errorf("Expected JPY with exponent 0, got %q (%v)", c, err)
+	}
+	if _, err := domain.ParseCurrency("XXX"); !errors.Is(err, domain.ErrUnsupportedCurrency) {
+		t.Errorf("Expected ErrUnsupportedCurrency, got %v", err)
+	}
+}
+
+func TestValidatePostingPerCurrency(t *testing.T) {
+	usd := domain.NewMoney(100, "USD")
+	eur := domain.NewMoney(90, "EUR")
+
+	balanced := []domain.LedgerEntry{
+		domain.Debit("a", usd), domain.Credit("b", usd),
+		domain.Debit("c", eur), domain.Credit("d", eur),
+	}
+	if err := domain.ValidatePosting(balanced); err != nil {
+		t.Errorf("Expected balanced posting, got %v", err)
+	}
+
+	mixed := []domain.LedgerEntry{domain.Debit("a", usd), domain.Credit("b", domain.NewMoney(100, "EUR"))}
+	if err := domain.ValidatePosting(mixed); !errors.Is(err, domain.ErrUnbalancedPosting) {
+		t.Errorf("Expected ErrUnbalancedPosting for cross-currency legs, got %v", err)
+	}
+}
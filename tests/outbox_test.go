@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+
+	"digital-wallet/internal/domain"
+	"digital-wallet/internal/repository"
+	"digital-wallet/internal/service"
+	"digital-wallet/internal/worker"
+	"digital-wallet/pkg/postgres"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+)
+
+type noopCache struct{}
+
+func (noopCache) GetWallet(context.Context, uuid.UUID) (*domain.Wallet, error) { return nil, nil }
+func (noopCache) SetWallet(context.Context, *domain.Wallet) error              { return nil }
+func (noopCache) InvalidateWallet(context.Context, uuid.UUID, int64) error     { return nil }
+
+// recordingProducer keeps published events in memory and fails while failing is set.
+type recordingProducer struct {
+	mu      sync.Mutex
+	failing bool
+	events  []domain.TransferEvent
+}
+
+func (p *recordingProducer) PublishRefundEvent(_ context.Context, event domain.RefundEvent) error {
+	return nil
+}
+
+func (p *recordingProducer) PublishWalletStatusEvent(_ context.Context, event domain.WalletStatusEvent) error {
+	return nil
+}
+
+func (p *recordingProducer) PublishTransferEvent(_ context.Context, event domain.TransferEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failing {
+		return errors.New("broker unavailable")
+	}
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestOutboxRelay(t *testing.T) {
+	_ = godotenv.Load("../.env")
+	pgDSN := os.Getenv("DATABASE_URL")
+	if pgDSN == "" {
+		pgDSN = "host=localhost user=postgres password=postgres dbname=wallet_db port=5432 sslmode=disable"
+	}
+	db, err := postgres.NewConnection(pgDSN)
+	if err != nil {
+		t.Skipf("Skipping outbox test (DB not available): %v", err)
+	}
+	if err := postgres.Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	walletRepo := repository.NewWalletRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	producer := &recordingProducer{}
+	svc := service.NewWalletService(walletRepo, repository.NewTransactionRepository(db), repository.NewLedgerRepository(db), noopCache{}, producer,
+		service.WithOutbox(outboxRepo),
+	)
+	relay := worker.NewOutboxRelay(outboxRepo, producer)
+
+	walletA := &domain.Wallet{UserID: uuid.New(), Currency: domain.DefaultCurrency}
+	walletB := &domain.Wallet{UserID: uuid.New(), Currency: domain.DefaultCurrency}
+	if err := walletRepo.Create(ctx, walletA); err != nil {
+		t.Fatalf("Failed to create wallet A: %v", err)
+	}
+	if err := walletRepo.Create(ctx, walletB); err != nil {
+		t.Fatalf("Failed to create wallet B: %v", err)
+	}
+	if _, err := svc.Deposit(ctx, walletA.ID, domain.NewMoney(1000, domain.DefaultCurrency)); err != nil {
+		t.Fatalf("Failed to fund wallet A: %v", err)
+	}
+
+	// Events are not published before the relay runs.
+	var txIDs []uuid.UUID
+	for i := 0; i < 3; i++ {
+		tx, err := svc.TransferMoney(ctx, walletA.ID, walletB.ID, domain.NewMoney(10, domain.DefaultCurrency))
+		if err != nil {
+			t.Fatalf("Transfer failed: %v", err)
+		}
+		txIDs = append(txIDs, tx.ID)
+	}
+	if len(producer.events) != 0 {
+		t.Fatalf("Expected no events before relaying, got %d", len(producer.events))
+	}
+
+	if _, err := relay.Dispatch(ctx); err != nil {
+		t.Fatalf("Dispatch failed: %v", err)
+	}
+	if _, err := relay.Dispatch(ctx); err != nil {
+		t.Fatalf("Second dispatch failed: %v", err)
+	}
+
+	// Each transaction is published exactly once, in commit order.
+	var got []uuid.UUID
+	for _, e := range producer.events {
+		for _, id := range txIDs {
+			if e.TransactionID == id {
+				got = append(got, id)
+			}
+		}
+	}
+	if len(got) != len(txIDs) {
+		t.Fatalf("Expected %d events, got %d", len(txIDs), len(got))
+	}
+	for i := range txIDs {
+		if got[i] != txIDs[i] {
+			t.Errorf("Event %d out of order: expected %s, got %s", i, txIDs[i], got[i])
+		}
+	}
+
+	// A failed publish keeps the event pending for a later retry.
+	producer.failing = true
+	tx, err := svc.TransferMoney(ctx, walletA.ID, walletB.ID, domain.NewMoney(10, domain.DefaultCurrency))
+	if err != nil {
+		t.Fatalf("Transfer failed: %v", err)
+	}
+	if _, err := relay.Dispatch(ctx); err != nil {
+		t.Fatalf("Dispatch failed: %v", err)
+	}
+
+	var pending domain.OutboxEvent
+	if err := db.Where("payload->>'transaction_id' = ?", tx.ID.String()).First(&pending).Error; err != nil {
+		t.Fatalf("Failed to load outbox event: %v", err)
+	}
+	if pending.DispatchedAt != nil || pending.Attempts != 1 || pending.LastError == "" {
+		t.Errorf("Expected one failed attempt on a pending event, got %+v", pending)
+	}
+}
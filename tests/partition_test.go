@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"digital-wallet/internal/repository"
+	"digital-wallet/pkg/postgres"
+
+	"github.com/joho/godotenv"
+)
+
+func TestPartitionArchival(t *testing.T) {
+	_ = godotenv.Load("../.env")
+	pgDSN := os.Getenv("DATABASE_URL")
+	if pgDSN == "" {
+		pgDSN = "host=localhost user=postgres password=postgres dbname=wallet_db port=5432 sslmode=disable"
+	}
+	db, err := postgres.NewConnection(pgDSN)
+	if err != nil {
+		t.Skipf("Skipping partition test (DB not available): %v", err)
+	}
+	ctx := context.Background()
+	if err := postgres.Migrate(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Partitions for 2001 are only ever created by this test.
+	t.Cleanup(func() {
+		for month := 1; month <= 12; month++ {
+			db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS transactions_p2001_%02d", month))
+		}
+	})
+
+	// Long before any partition, so the row lands in the default partition.
+	old := &domain.Transaction{Amount: 100, Type: domain.TransactionTypeDeposit, CreatedAt: time.Date(2001, 1, 15, 0, 0, 0, 0, time.UTC)}
+	transRepo := repository.NewTransactionRepository(db)
+	if err := transRepo.Create(ctx, old); err != nil {
+		t.Fatal(err)
+	}
+
+	archiveDir := t.TempDir()
+	manager := postgres.NewPartitionManager(sqlDB, postgres.PartitionConfig{MonthsAhead: 1, ArchiveDir: archiveDir})
+	created, _, err := manager.Maintain(ctx, old.CreatedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 2 || created[0] != "transactions_p2001_01" {
+		t.Fatalf("created %v, want transactions_p2001_01 and transactions_p2001_02", created)
+	}
+	if _, err := transRepo.GetByID(ctx, old.ID); err != nil {
+		t.Fatalf("transaction lost moving out of the default partition: %v", err)
+	}
+
+	// With one month of retention in April, January and February expire.
+	manager = postgres.NewPartitionManager(sqlDB, postgres.PartitionConfig{MonthsAhead: 1, RetentionMonths: 1, ArchiveDir: archiveDir})
+	_, detached, err := manager.Maintain(ctx, time.Date(2001, 4, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(detached) != 2 {
+		t.Fatalf("detached %v, want January and February 2001", detached)
+	}
+	if _, err := transRepo.GetByID(ctx, old.ID); err != domain.ErrTransactionNotFound {
+		t.Fatalf("detached transaction still served, err = %v", err)
+	}
+
+	files, err := manager.Archive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(archiveDir, "transactions_p2001_01.csv.gz")
+	var found bool
+	for _, f := range files {
+		found = found || f == path
+	}
+	if !found {
+		t.Fatalf("archived %v, want %s", files, path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported bool
+	scanner := bufio.NewScanner(zr)
+	for scanner.Scan() {
+		exported = exported || strings.HasPrefix(scanner.Text(), old.ID.String())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !exported {
+		t.Errorf("transaction %s missing from %s", old.ID, path)
+	}
+
+	var tables int64
+	db.Raw("SELECT count(*) FROM pg_class WHERE relname = 'transactions_p2001_01'").Scan(&tables)
+	if tables != 0 {
+		t.Error("archived partition was not dropped")
+	}
+}
+
+// TestPartitionLockTimeout checks that detaching a partition gives up when a
+// query holds the transactions table instead of queueing every query behind
+// its lock, and succeeds on a later run.
+func TestPartitionLockTimeout(t *testing.T) {
+	_ = godotenv.Load("../.env")
+	pgDSN := os.Getenv("DATABASE_URL")
+	if pgDSN == "" {
+		pgDSN = "host=localhost user=postgres password=postgres dbname=wallet_db port=5432 sslmode=disable"
+	}
+	db, err := postgres.NewConnection(pgDSN)
+	if err != nil {
+		t.Skipf("Skipping partition test (DB not available): %v", err)
+	}
+	ctx := context.Background()
+	if err := postgres.Migrate(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Partitions for 2002 are only ever created by this test.
+	t.Cleanup(func() {
+		for month := 1; month <= 12; month++ {
+			db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS transactions_p2002_%02d", month))
+		}
+	})
+
+	cfg := postgres.PartitionConfig{MonthsAhead: 1, ArchiveDir: t.TempDir(), LockTimeout: 100 * time.Millisecond}
+	if _, _, err := postgres.NewPartitionManager(sqlDB, cfg).Maintain(ctx, time.Date(2002, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	// Create April and May now, so that the runs below only detach.
+	april := time.Date(2002, 4, 1, 0, 0, 0, 0, time.UTC)
+	if _, _, err := postgres.NewPartitionManager(sqlDB, cfg).Maintain(ctx, april); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Rollback()
+	if _, err := reader.ExecContext(ctx, "LOCK TABLE ONLY transactions IN ACCESS SHARE MODE"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.RetentionMonths = 1
+	manager := postgres.NewPartitionManager(sqlDB, cfg)
+	start := time.Now()
+	_, detached, err := manager.Maintain(ctx, april)
+	if err == nil || len(detached) != 0 {
+		t.Fatalf("detached %v (err = %v) while the table was in use, want a lock timeout", detached, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("gave up after %v, want about %v", elapsed, cfg.LockTimeout)
+	}
+
+	if err := reader.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	_, detached, err = manager.Maintain(ctx, april)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(detached) != 2 {
+		t.Errorf("detached %v, want January and February 2002", detached)
+	}
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"testing"
+
+	"digital-wallet/internal/domain"
+	"digital-wallet/internal/repository"
+	"digital-wallet/internal/repository/pgxrepo"
+	"digital-wallet/internal/service"
+	"digital-wallet/pkg/postgres"
+
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+)
+
+type repositoryBackend struct {
+	svc  *service.WalletService
+	repo domain.WalletRepository
+}
+
+// setupBackendServices returns a service on the GORM repositories and one on
+// the pgx repositories, both on the same database.
+func setupBackendServices(tb testing.TB) map[string]repositoryBackend {
+	_ = godotenv.Load("../.env")
+	pgDSN := os.Getenv("DATABASE_URL")
+	if pgDSN == "" {
+		pgDSN = "host=localhost user=postgres password=postgres dbname=wallet_db port=5432 sslmode=disable"
+	}
+	db, err := postgres.NewConnection(pgDSN)
+	if err != nil {
+		tb.Skipf("Skipping pgx backend test (DB not available): %v", err)
+	}
+	if err := postgres.Migrate(context.Background(), db); err != nil {
+		tb.Fatal(err)
+	}
+	pool, err := postgres.NewPool(context.Background(), pgDSN, pgxrepo.PrepareStatements)
+	if err != nil {
+		tb.Skipf("Skipping pgx backend test (DB not available): %v", err)
+	}
+	tb.Cleanup(pool.Close)
+
+	gormWallets := repository.NewWalletRepository(db)
+	pgxWallets := pgxrepo.NewWalletRepository(pool)
+	return map[string]repositoryBackend{
+		"gorm": {
+			svc: service.NewWalletService(gormWallets, repository.NewTransactionRepository(db), repository.NewLedgerRepository(db),
+				noopCache{}, &recordingProducer{}, service.WithOutbox(repository.NewOutboxRepository(db))),
+			repo: gormWallets,
+		},
+		"pgx": {
+			svc: service.NewWalletService(pgxWallets, pgxrepo.NewTransactionRepository(pool), pgxrepo.NewLedgerRepository(pool),
+				noopCache{}, &recordingProducer{}, service.WithOutbox(pgxrepo.NewOutboxRepository(pool))),
+			repo: pgxWallets,
+		},
+	}
+}
+
+// TestPgxBackend checks that the pgx repositories post transfers the GORM
+// repositories read back identically.
+func TestPgxBackend(t *testing.T) {
+	backends := setupBackendServices(t)
+	pgxBackend, gormBackend := backends["pgx"], backends["gorm"]
+	ctx := context.Background()
+
+	ids := createFundedWallets(t, pgxBackend.svc, pgxBackend.repo, 2, 100)
+	transaction, err := pgxBackend.svc.TransferMoney(ctx, ids[0], ids[1], domain.NewMoney(30, domain.DefaultCurrency))
+	if err != nil {
+		t.Fatalf("Transfer failed: %v", err)
+	}
+
+	if _, err := pgxBackend.svc.TransferMoney(ctx, ids[0], ids[1], domain.NewMoney(71, domain.DefaultCurrency)); err == nil {
+		t.Error("Expected an overdraft to fail")
+	}
+	if _, err := pgxBackend.svc.TransferMoney(ctx, ids[0], uuid.New(), domain.NewMoney(1, domain.DefaultCurrency)); err == nil {
+		t.Error("Expected a transfer to a missing wallet to fail")
+	}
+
+	for i, want := range []int64{70, 130} {
+		check, err := gormBackend.svc.VerifyWalletBalance(ctx, ids[i])
+		if err != nil {
+			t.Fatalf("Failed to verify wallet: %v", err)
+		}
+		if check.Balance != want || !check.Consistent {
+			t.Errorf("Expected wallet %d balance %d matching the ledger, got %+v", i, want, check)
+		}
+	}
+
+	entries, err := gormBackend.svc.GetTransactionEntries(ctx, transaction.ID)
+	if err != nil {
+		t.Fatalf("Failed to read entries: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected 2 ledger entries, got %d", len(entries))
+	}
+}
+
+// BenchmarkRepositoryBackends transfers between random pairs of a small pool
+// of wallets on the GORM and the pgx repositories.
+func BenchmarkRepositoryBackends(b *testing.B) {
+	backends := setupBackendServices(b)
+	for _, name := range []string{"gorm", "pgx"} {
+		backend := backends[name]
+		b.Run(name, func(b *testing.B) {
+			ctx := context.Background()
+			ids := createFundedWallets(b, backend.svc, backend.repo, 32, int64(b.N)+1)
+			one := domain.NewMoney(1, domain.DefaultCurrency)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					i := rand.Intn(len(ids))
+					j := (i + 1 + rand.Intn(len(ids)-1)) % len(ids)
+					if _, err := backend.svc.TransferMoney(ctx, ids[i], ids[j], one); err != nil {
+						b.Errorf("Transfer failed: %v", err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"digital-wallet/internal/domain"
+	"digital-wallet/internal/handler"
+	"digital-wallet/internal/repository/memory"
+
+	"github.com/google/uuid"
+)
+
+// signedRequest builds a request carrying key, signed with secret at
+// timestamp. An empty key or secret leaves it out.
+func signedRequest(key string, secret []byte, method, path string, body []byte, timestamp time.Time, nonce string) *http.Request {
+	req, _ := http.NewRequest(method, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(handler.APIKeyHeader, key)
+	}
+	if len(secret) > 0 {
+		req.Header.Set(handler.SignatureTimestampHeader, strconv.FormatInt(timestamp.Unix(), 10))
+		req.Header.Set(handler.SignatureNonceHeader, nonce)
+		req.Header.Set(handler.SignatureHeader, handler.SignRequest(secret, method, path, timestamp.Unix(), nonce, body))
+	}
+	return req
+}
+
+func TestRequestSigning(t *testing.T) {
+	svc, _ := newMemoryService()
+	verifier := handler.NewSignatureVerifier(handler.SignatureConfig{MaxSkew: time.Minute}, memory.NewNonceStore())
+	router := handler.NewRouter(handler.NewHandler(svc),
+		handler.NewAPIKeyAuthenticator(svc, memory.NewRateLimiter(), nil).Middleware, verifier.Middleware)
+
+	scopes := []domain.APIScope{domain.ScopeWalletsRead, domain.ScopeTransfersWrite, domain.ScopeAllWallets}
+	keyA := &domain.APIKey{Name: "billing", Scopes: scopes}
+	plainA, err := svc.IssueAPIKey(context.Background(), keyA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyB := &domain.APIKey{Name: "payouts", Scopes: scopes}
+	plainB, err := svc.IssueAPIKey(context.Background(), keyB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretA, secretB := []byte(keyA.SigningSecret), []byte(keyB.SigningSecret)
+	if len(secretA) == 0 || bytes.Equal(secretA, secretB) {
+		t.Fatalf("Expected a signing secret per key, got %q and %q", secretA, secretB)
+	}
+
+	send := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	body, _ := json.Marshal(map[string]string{"user_id": uuid.NewString()})
+	now := time.Now()
+
+	w := send(signedRequest(plainA, nil, "POST", "/wallets", body, now, ""))
+	var problem handler.Problem
+	_ = json.Unmarshal(w.Body.Bytes(), &problem)
+	if w.Code != http.StatusUnauthorized || problem.Code != handler.CodeInvalidSignature {
+		t.Errorf("Expected 401 invalid_signature for an unsigned request, got %d %q", w.Code, problem.Code)
+	}
+	if w := send(signedRequest("", secretA, "POST", "/wallets", body, now, uuid.NewString())); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a signed request without a key, got %d", w.Code)
+	}
+
+	// A secret only verifies the requests of its own key.
+	if w := send(signedRequest(plainB, secretA, "POST", "/wallets", body, now, uuid.NewString())); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for key B signed with key A's secret, got %d", w.Code)
+	}
+
+	nonce := uuid.NewString()
+	w = send(signedRequest(plainA, secretA, "POST", "/wallets", body, now, nonce))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 for a signed request, got %d. Body: %s", w.Code, w.Body.String())
+	}
+	var wallet domain.Wallet
+	_ = json.Unmarshal(w.Body.Bytes(), &wallet)
+
+	if w := send(signedRequest(plainA, secretA, "POST", "/wallets", body, now, nonce)); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a replayed request, got %d", w.Code)
+	}
+
+	tampered := signedRequest(plainA, secretA, "POST", "/wallets", body, now, uuid.NewString())
+	tampered.Body = httptest.NewRequest("POST", "/", bytes.NewReader([]byte(`{"user_id":"`+uuid.NewString()+`"}`))).Body
+	if w := send(tampered); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a tampered body, got %d", w.Code)
+	}
+
+	stale := now.Add(-2 * time.Minute)
+	if w := send(signedRequest(plainA, secretA, "POST", "/wallets", body, stale, uuid.NewString())); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a stale timestamp, got %d", w.Code)
+	}
+
+	withdrawal, _ := json.Marshal(map[string]int64{"amount": 100})
+	if w := send(signedRequest(plainA, secretA, "POST", "/wallets/"+wallet.ID.String()+"/withdrawals", withdrawal, now, nonce+"-other")); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected the signed withdrawal to reach the service (422), got %d", w.Code)
+	}
+
+	// Reads are not signed.
+	if w := send(signedRequest(plainA, nil, "GET", "/wallets/"+wallet.ID.String(), nil, now, "")); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for an unsigned read, got %d", w.Code)
+	}
+
+	// A rotated key gets a new secret; the old key keeps its own during the overlap.
+	rotated, plainRotated, err := svc.RotateAPIKey(context.Background(), keyA.ID, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.SigningSecret == "" || rotated.SigningSecret == keyA.SigningSecret {
+		t.Fatalf("Expected a new signing secret for the rotated key, got %q", rotated.SigningSecret)
+	}
+	if w := send(signedRequest(plainRotated, secretA, "POST", "/wallets", body, now, uuid.NewString())); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for the rotated key signed with the old secret, got %d", w.Code)
+	}
+	if w := send(signedRequest(plainRotated, []byte(rotated.SigningSecret), "POST", "/wallets", body, now, uuid.NewString())); w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201 for the rotated key with its secret, got %d", w.Code)
+	}
+	if w := send(signedRequest(plainA, secretA, "POST", "/wallets", body, now, uuid.NewString())); w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201 for the old key during the overlap, got %d", w.Code)
+	}
+}